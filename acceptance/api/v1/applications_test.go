@@ -2,6 +2,10 @@ package v1_test
 
 import (
 	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -11,10 +15,12 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/acceptance/helpers/proc"
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers"
 	v1 "github.com/epinio/epinio/internal/api/v1"
@@ -70,7 +76,7 @@ var _ = Describe("Apps API Application Endpoints", func() {
 		return request, nil
 	}
 
-	appStatus := func(org, app string) string {
+	appShow := func(org, app string) models.App {
 		response, err := env.Curl("GET",
 			fmt.Sprintf("%s/api/v1/orgs/%s/applications/%s", serverURL, org, app),
 			strings.NewReader(""))
@@ -88,11 +94,15 @@ var _ = Describe("Apps API Application Endpoints", func() {
 		ExpectWithOffset(1, responseApp.Name).To(Equal(app))
 		ExpectWithOffset(1, responseApp.Organization).To(Equal(org))
 
-		return responseApp.Status
+		return responseApp
+	}
+
+	appStatus := func(org, app string) string {
+		return appShow(org, app).Status
 	}
 
 	updateAppInstances := func(org string, app string, instances int32) (int, []byte) {
-		data, err := json.Marshal(models.UpdateAppRequest{Instances: instances})
+		data, err := json.Marshal(models.UpdateAppRequest{Instances: &instances})
 		ExpectWithOffset(1, err).ToNot(HaveOccurred())
 
 		response, err := env.Curl("PATCH",
@@ -138,7 +148,6 @@ var _ = Describe("Apps API Application Endpoints", func() {
 				Revision: uploadResponse.Git.Revision,
 				URL:      uploadResponse.Git.URL,
 			},
-			Route: appName + ".omg.howdoi.website",
 		}
 		b, err := json.Marshal(request)
 		Expect(err).NotTo(HaveOccurred())
@@ -346,21 +355,67 @@ var _ = Describe("Apps API Application Endpoints", func() {
 	Context("Uploading", func() {
 
 		var (
+			appName string
 			url     string
 			path    string
 			request *http.Request
 		)
 
+		BeforeEach(func() {
+			appName = catalog.NewAppName()
+			_, err := createApplication(appName, org)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
 		JustBeforeEach(func() {
-			url = serverURL + "/" + v1.Routes.Path("AppUpload", org, "testapp")
+			url = serverURL + "/" + v1.Routes.Path("AppUpload", org, appName)
 			var err error
 			request, err = uploadRequest(url, path)
 			Expect(err).ToNot(HaveOccurred())
 		})
 
-		When("uploading a broken tarball", func() {
+		When("uploading to an unknown app", func() {
 			BeforeEach(func() {
-				path = "../../../fixtures/untar.tgz"
+				path = "../../../fixtures/sample-app.tar"
+			})
+
+			JustBeforeEach(func() {
+				url = serverURL + "/" + v1.Routes.Path("AppUpload", org, "bogus")
+				var err error
+				request, err = uploadRequest(url, path)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("returns a 404", func() {
+				resp, err := env.Client().Do(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp).ToNot(BeNil())
+				defer resp.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusNotFound), string(bodyBytes))
+			})
+		})
+
+		When("uploading a broken archive", func() {
+			BeforeEach(func() {
+				f, err := ioutil.TempFile("", "epinio-broken")
+				Expect(err).ToNot(HaveOccurred())
+				defer f.Close()
+
+				// gzip magic, followed by garbage
+				_, err = f.Write([]byte("\x1f\x8bbroken"))
+				Expect(err).ToNot(HaveOccurred())
+				path = f.Name()
+			})
+
+			AfterEach(func() {
+				os.Remove(path)
 			})
 
 			It("returns an error response", func() {
@@ -382,6 +437,52 @@ var _ = Describe("Apps API Application Endpoints", func() {
 			})
 		})
 
+		When("uploading an unsupported format", func() {
+			BeforeEach(func() {
+				path = "../../../assets/sample-app/buildpack.yml"
+			})
+
+			It("returns an error response", func() {
+				resp, err := env.Client().Do(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp).ToNot(BeNil())
+				defer resp.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusUnsupportedMediaType), string(bodyBytes))
+
+				r := &v1.ErrorResponse{}
+				err = json.Unmarshal(bodyBytes, &r)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(r.Errors).To(HaveLen(1))
+				Expect(r.Errors[0].Title).To(ContainSubstring("Unsupported archive format"))
+			})
+		})
+
+		When("uploading a gzipped tarball", func() {
+			BeforeEach(func() {
+				path = "../../../fixtures/untar.tgz"
+			})
+
+			It("returns the app response", func() {
+				resp, err := env.Client().Do(request)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp).ToNot(BeNil())
+				defer resp.Body.Close()
+
+				bodyBytes, err := ioutil.ReadAll(resp.Body)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(resp.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+				r := &models.UploadResponse{}
+				err = json.Unmarshal(bodyBytes, &r)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(r.Git.Revision).ToNot(BeEmpty())
+			})
+		})
+
 		When("uploading a new dir", func() {
 			BeforeEach(func() {
 				path = "../../../fixtures/sample-app.tar"
@@ -408,6 +509,129 @@ var _ = Describe("Apps API Application Endpoints", func() {
 
 	})
 
+	Context("Uploading in chunks", func() {
+		var (
+			appName string
+			tarball []byte
+			digest  string
+		)
+
+		chunkRequest := func(offset int, chunk []byte) (*http.Response, error) {
+			url := serverURL + "/" + v1.Routes.Path("AppUploadChunk", org, appName, digest)
+			request, err := http.NewRequest("PATCH", url, bytes.NewReader(chunk))
+			if err != nil {
+				return nil, err
+			}
+			request.SetBasicAuth(env.EpinioUser, env.EpinioPassword)
+			request.Header.Set(models.UploadOffsetHeader, strconv.Itoa(offset))
+			request.Header.Set(models.UploadLengthHeader, strconv.Itoa(len(tarball)))
+			return env.Client().Do(request)
+		}
+
+		uploadStatus := func() models.UploadStatus {
+			url := serverURL + "/" + v1.Routes.Path("AppUploadStatus", org, appName, digest)
+			response, err := env.Curl("GET", url, strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+			status := models.UploadStatus{}
+			Expect(json.Unmarshal(bodyBytes, &status)).To(Succeed())
+			return status
+		}
+
+		BeforeEach(func() {
+			appName = catalog.NewAppName()
+			_, err := createApplication(appName, org)
+			Expect(err).ToNot(HaveOccurred())
+
+			tarball, err = ioutil.ReadFile("../../../fixtures/sample-app.tar")
+			Expect(err).ToNot(HaveOccurred())
+			sum := sha256.Sum256(tarball)
+			digest = hex.EncodeToString(sum[:])
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("resumes at the offset, and skips stored sources", func() {
+			half := len(tarball) / 2
+
+			response, err := chunkRequest(0, tarball[:half])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+			Expect(uploadStatus().Offset).To(Equal(int64(half)))
+
+			By("rejecting a chunk at the wrong offset")
+			response, err = chunkRequest(0, tarball)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusConflict))
+
+			response, err = chunkRequest(half, tarball[half:])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			url := serverURL + "/" + v1.Routes.Path("AppUploadFinish", org, appName, digest)
+			response, err = env.Curl("POST", url, strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+			upload := models.UploadResponse{}
+			Expect(json.Unmarshal(bodyBytes, &upload)).To(Succeed())
+			Expect(upload.Git.Revision).ToNot(BeEmpty())
+
+			By("knowing the stored sources")
+			status := uploadStatus()
+			Expect(status.Stored).ToNot(BeNil())
+			Expect(status.Stored.Git.Revision).To(Equal(upload.Git.Revision))
+		})
+
+		It("replaces the unfinished upload of the app with a new one", func() {
+			half := len(tarball) / 2
+
+			response, err := chunkRequest(0, tarball[:half])
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			first := digest
+			sum := sha256.Sum256([]byte("other sources"))
+			digest = hex.EncodeToString(sum[:])
+			response, err = chunkRequest(0, []byte("other"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+			digest = first
+			Expect(uploadStatus().Offset).To(Equal(int64(0)))
+		})
+
+		It("rejects uploads over the maximum size", func() {
+			url := serverURL + "/" + v1.Routes.Path("AppUploadChunk", org, appName, digest)
+			request, err := http.NewRequest("PATCH", url, bytes.NewReader(tarball))
+			Expect(err).ToNot(HaveOccurred())
+			request.SetBasicAuth(env.EpinioUser, env.EpinioPassword)
+			request.Header.Set(models.UploadOffsetHeader, "0")
+			request.Header.Set(models.UploadLengthHeader, "1000000000000")
+
+			response, err := env.Client().Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusRequestEntityTooLarge))
+		})
+
+		It("rejects bad digests", func() {
+			digest = "abc"
+			response, err := chunkRequest(0, tarball)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest))
+		})
+	})
+
 	Context("Deploying", func() {
 		var (
 			url     string
@@ -458,7 +682,7 @@ var _ = Describe("Apps API Application Endpoints", func() {
 						Stage: models.StageRef{
 							ID: stageResponse.Stage.ID,
 						},
-						Route: appName + ".omg.howdoi.website",
+						Routes: []string{appName + ".omg.howdoi.website"},
 						Git: &models.GitRef{
 							Revision: respObj.Git.Revision,
 							URL:      respObj.Git.URL,
@@ -496,7 +720,7 @@ var _ = Describe("Apps API Application Endpoints", func() {
 						Org:  org,
 					},
 					Instances: &one,
-					Route:     appName + ".omg.howdoi.website",
+					Routes:    []string{appName + ".omg.howdoi.website"},
 					ImageURL:  "splatform/sample-app",
 				}
 
@@ -716,6 +940,180 @@ var _ = Describe("Apps API Application Endpoints", func() {
 		})
 	})
 
+	Context("Auto-deploy", func() {
+		var appName string
+
+		autoDeployRequest := func(method string, body string) (int, []byte) {
+			url := serverURL + "/" + v1.Routes.Path("AppAutoDeploy", org, appName)
+			response, err := env.Curl(method, url, strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return response.StatusCode, bodyBytes
+		}
+
+		webhookResponse := func(event, signature string, payload []byte) (*http.Response, []byte) {
+			url := serverURL + "/" + v1.Routes.Path("AppWebhook", org, appName)
+			request, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+			Expect(err).ToNot(HaveOccurred())
+			request.Header.Set("X-Gitea-Event", event)
+			request.Header.Set("X-Gitea-Signature", signature)
+
+			// No basic auth, gitea signs the payload instead
+			response, err := env.Client().Do(request)
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return response, bodyBytes
+		}
+
+		webhookRequest := func(event, signature string, payload []byte) int {
+			response, _ := webhookResponse(event, signature, payload)
+			return response.StatusCode
+		}
+
+		sign := func(secret string, payload []byte) string {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(payload)
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+
+		webhookSecret := func() string {
+			out, err := proc.Run(fmt.Sprintf(
+				"kubectl get secret -n %s %s.auto-deploy -o=jsonpath='{.data.secret}'",
+				org, appName), "", false)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			secret, err := base64.StdEncoding.DecodeString(out)
+			Expect(err).ToNot(HaveOccurred(), out)
+			return string(secret)
+		}
+
+		BeforeEach(func() {
+			appName = catalog.NewAppName()
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		When("the app has no sources", func() {
+			It("refuses to enable auto-deploy", func() {
+				_, err := createApplication(appName, org)
+				Expect(err).ToNot(HaveOccurred())
+
+				status, body := autoDeployRequest("POST", `{"branch":"main"}`)
+				Expect(status).To(Equal(http.StatusBadRequest), string(body))
+			})
+		})
+
+		When("the app was pushed from sources", func() {
+			BeforeEach(func() {
+				env.MakeApp(appName, 1, true)
+			})
+
+			It("enables and disables auto-deploy", func() {
+				status, body := autoDeployRequest("POST", `{"branch":"main"}`)
+				Expect(status).To(Equal(http.StatusOK), string(body))
+
+				app := appShow(org, appName)
+				Expect(app.AutoDeploy).ToNot(BeNil())
+				Expect(app.AutoDeploy.Branch).To(Equal("main"))
+
+				status, body = autoDeployRequest("DELETE", "")
+				Expect(status).To(Equal(http.StatusOK), string(body))
+
+				app = appShow(org, appName)
+				Expect(app.AutoDeploy).To(BeNil())
+
+				out, err := proc.Run(fmt.Sprintf("kubectl get secret -n %s %s.auto-deploy", org, appName), "", false)
+				Expect(err).To(HaveOccurred(), out)
+
+				status, body = autoDeployRequest("DELETE", "")
+				Expect(status).To(Equal(http.StatusBadRequest), string(body))
+			})
+
+			It("rejects bad branch names", func() {
+				status, body := autoDeployRequest("POST", `{"branch":"../main"}`)
+				Expect(status).To(Equal(http.StatusBadRequest), string(body))
+			})
+
+			It("does not accept webhooks when auto-deploy is not enabled", func() {
+				Expect(webhookRequest("push", sign("", []byte("{}")), []byte("{}"))).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("does not tell unknown apps apart", func() {
+				pushed := appName
+				appName = catalog.NewAppName()
+				status := webhookRequest("push", sign("", []byte("{}")), []byte("{}"))
+				appName = pushed
+
+				Expect(status).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("rejects webhooks with a bad signature", func() {
+				status, body := autoDeployRequest("POST", "")
+				Expect(status).To(Equal(http.StatusOK), string(body))
+
+				payload := []byte(`{"ref":"refs/heads/main","after":"0123456789abcdef"}`)
+				Expect(webhookRequest("push", sign("wrong", payload), payload)).To(Equal(http.StatusUnauthorized))
+			})
+
+			It("ignores pushes to other branches", func() {
+				status, body := autoDeployRequest("POST", "")
+				Expect(status).To(Equal(http.StatusOK), string(body))
+
+				payload := []byte(`{"ref":"refs/heads/feature","after":"0123456789abcdef"}`)
+				Expect(webhookRequest("push", sign(webhookSecret(), payload), payload)).To(Equal(http.StatusNoContent))
+			})
+		})
+
+		When("the app was pushed from sources with a Dockerfile", func() {
+			var sourceDir string
+
+			BeforeEach(func() {
+				var err error
+				sourceDir, err = ioutil.TempDir("", "epinio-dockerfile-app")
+				Expect(err).ToNot(HaveOccurred())
+
+				dockerfile := "FROM nginx:alpine\nRUN sed -i 's/listen  *80;/listen 8080;/' /etc/nginx/conf.d/default.conf\nCOPY index.html /usr/share/nginx/html/\n"
+				err = ioutil.WriteFile(filepath.Join(sourceDir, "Dockerfile"), []byte(dockerfile), 0600)
+				Expect(err).ToNot(HaveOccurred())
+				err = ioutil.WriteFile(filepath.Join(sourceDir, "index.html"), []byte("built from a Dockerfile\n"), 0644)
+				Expect(err).ToNot(HaveOccurred())
+
+				out, err := env.Epinio("apps push "+appName, sourceDir)
+				Expect(err).ToNot(HaveOccurred(), out)
+			})
+
+			AfterEach(func() {
+				os.RemoveAll(sourceDir)
+			})
+
+			It("stages pushes with the Dockerfile", func() {
+				status, body := autoDeployRequest("POST", "")
+				Expect(status).To(Equal(http.StatusOK), string(body))
+
+				payload := []byte(`{"ref":"refs/heads/main","after":"0123456789abcdef"}`)
+				response, body := webhookResponse("push", sign(webhookSecret(), payload), payload)
+				Expect(response.StatusCode).To(Equal(http.StatusAccepted), string(body))
+				Expect(response.Header.Get("Content-Type")).To(Equal("application/json"))
+
+				staged := models.StageResponse{}
+				Expect(json.Unmarshal(body, &staged)).To(Succeed())
+
+				out, err := helpers.Kubectl(fmt.Sprintf("-n %s get pipelinerun %s -o jsonpath='{.spec.pipelineRef.name}'",
+					deployments.TektonStagingNamespace, staged.Stage.ID))
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(Equal("dockerfile-staging-pipeline"))
+			})
+		})
+	})
+
 	Context("Creating", func() {
 		var (
 			appName string
@@ -10,6 +10,7 @@ import (
 	"github.com/epinio/epinio/acceptance/helpers/catalog"
 	"github.com/epinio/epinio/helpers"
 	apiv1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -199,4 +200,84 @@ var _ = Describe("Orgs API Application Endpoints", func() {
 			})
 		})
 	})
+
+	Context("Git credentials", func() {
+		var credentialsURL string
+
+		BeforeEach(func() {
+			credentialsURL = fmt.Sprintf("%s/api/v1/orgs/%s/gitcredentials", serverURL, org)
+		})
+
+		create := func(body string) (*http.Response, []byte) {
+			response, err := env.Curl("POST", credentialsURL, strings.NewReader(body))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response).ToNot(BeNil())
+			defer response.Body.Close()
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			return response, bodyBytes
+		}
+
+		It("creates, lists and deletes git credentials", func() {
+			response, bodyBytes := create(`{"name":"github","url":"https://github.com","username":"epinio","password":"s3cret"}`)
+			Expect(response.StatusCode).To(Equal(http.StatusCreated), string(bodyBytes))
+
+			out, err := helpers.Kubectl(fmt.Sprintf("get secret -n tekton-staging git-%s-github -o jsonpath={.type}", org))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("kubernetes.io/basic-auth"))
+
+			response, err = env.Curl("GET", credentialsURL, strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			bodyBytes, err = ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+			Expect(string(bodyBytes)).ToNot(ContainSubstring("s3cret"))
+
+			var credentials models.GitCredentialsList
+			err = json.Unmarshal(bodyBytes, &credentials)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(credentials).To(ConsistOf(models.GitCredentials{
+				Name:     "github",
+				URL:      "https://github.com",
+				Username: "epinio",
+			}))
+
+			response, err = env.Curl("DELETE", credentialsURL+"/github", strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			bodyBytes, err = ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+
+			_, err = helpers.Kubectl(fmt.Sprintf("get secret -n tekton-staging git-%s-github", org))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("fails for known git credentials", func() {
+			body := `{"name":"github","url":"https://github.com","username":"epinio","password":"s3cret"}`
+			response, bodyBytes := create(body)
+			Expect(response.StatusCode).To(Equal(http.StatusCreated), string(bodyBytes))
+
+			response, bodyBytes = create(body)
+			Expect(response.StatusCode).To(Equal(http.StatusConflict), string(bodyBytes))
+		})
+
+		It("fails for bad git credentials", func() {
+			response, bodyBytes := create(`{"name":"Bad_Name","url":"https://github.com","username":"epinio","password":"s3cret"}`)
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+
+			response, bodyBytes = create(`{"name":"github","url":"github.com","username":"epinio","password":"s3cret"}`)
+			Expect(response.StatusCode).To(Equal(http.StatusBadRequest), string(bodyBytes))
+		})
+
+		It("fails to delete unknown git credentials", func() {
+			response, err := env.Curl("DELETE", credentialsURL+"/unknown", strings.NewReader(""))
+			Expect(err).ToNot(HaveOccurred())
+			defer response.Body.Close()
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusNotFound), string(bodyBytes))
+		})
+	})
 })
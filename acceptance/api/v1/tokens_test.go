@@ -0,0 +1,90 @@
+package v1_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/acceptance/helpers/catalog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Tokens API", func() {
+	var name string
+
+	curlWithToken := func(token string) int {
+		request, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/orgs", serverURL), strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		request.Header.Set("Authorization", "Bearer "+token)
+		response, err := env.Client().Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		return response.StatusCode
+	}
+
+	createToken := func(body string) models.TokenResponse {
+		response, err := env.Curl("POST", fmt.Sprintf("%s/api/v1/tokens", serverURL), strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusCreated), string(bodyBytes))
+
+		var token models.TokenResponse
+		err = json.Unmarshal(bodyBytes, &token)
+		Expect(err).ToNot(HaveOccurred())
+		return token
+	}
+
+	BeforeEach(func() {
+		name = catalog.NewTokenName()
+	})
+
+	It("authenticates requests with a bearer token until it is revoked", func() {
+		token := createToken(fmt.Sprintf(`{"name":"%s","expiresin":"1h"}`, name))
+		Expect(token.Token).To(HavePrefix("epn_"))
+		Expect(token.Expires).ToNot(BeNil())
+
+		Expect(curlWithToken(token.Token)).To(Equal(http.StatusOK))
+
+		response, err := env.Curl("GET", fmt.Sprintf("%s/api/v1/tokens", serverURL), strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		var tokens models.TokenResponseList
+		err = json.Unmarshal(bodyBytes, &tokens)
+		Expect(err).ToNot(HaveOccurred())
+		names := []string{}
+		for _, t := range tokens {
+			names = append(names, t.Name)
+		}
+		Expect(names).To(ContainElement(name))
+		Expect(string(bodyBytes)).ToNot(ContainSubstring(token.Token))
+
+		response, err = env.Curl("DELETE", fmt.Sprintf("%s/api/v1/tokens/%s", serverURL, name), strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusOK))
+
+		Expect(curlWithToken(token.Token)).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects unknown tokens", func() {
+		Expect(curlWithToken("epn_bogus")).To(Equal(http.StatusUnauthorized))
+	})
+
+	It("rejects a second token of the same name", func() {
+		createToken(fmt.Sprintf(`{"name":"%s"}`, name))
+		defer env.Curl("DELETE", fmt.Sprintf("%s/api/v1/tokens/%s", serverURL, name), strings.NewReader(""))
+
+		response, err := env.Curl("POST", fmt.Sprintf("%s/api/v1/tokens", serverURL),
+			strings.NewReader(fmt.Sprintf(`{"name":"%s"}`, name)))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(http.StatusConflict))
+	})
+})
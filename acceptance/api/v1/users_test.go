@@ -0,0 +1,134 @@
+package v1_test
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/acceptance/helpers/catalog"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Users API", func() {
+	var org, otherOrg, user string
+	password := "s3cr3t"
+
+	curlAs := func(method, uri, body string) *http.Response {
+		request, err := http.NewRequest(method, uri, strings.NewReader(body))
+		Expect(err).ToNot(HaveOccurred())
+		request.SetBasicAuth(user, password)
+		response, err := env.Client().Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		return response
+	}
+
+	expectStatus := func(response *http.Response, status int) {
+		defer response.Body.Close()
+		bodyBytes, err := ioutil.ReadAll(response.Body)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(response.StatusCode).To(Equal(status), string(bodyBytes))
+	}
+
+	BeforeEach(func() {
+		org = catalog.NewOrgName()
+		env.SetupAndTargetOrg(org)
+		otherOrg = catalog.NewOrgName()
+		env.SetupAndTargetOrg(otherOrg)
+
+		user = catalog.NewUserName()
+		response, err := env.Curl("POST", fmt.Sprintf("%s/api/v1/users", serverURL),
+			strings.NewReader(fmt.Sprintf(`{"username":"%s","password":"%s"}`, user, password)))
+		Expect(err).ToNot(HaveOccurred())
+		expectStatus(response, http.StatusCreated)
+	})
+
+	AfterEach(func() {
+		response, err := env.Curl("DELETE", fmt.Sprintf("%s/api/v1/users/%s", serverURL, user),
+			strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		expectStatus(response, http.StatusOK)
+	})
+
+	It("rejects bad credentials", func() {
+		request, err := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/orgs", serverURL), strings.NewReader(""))
+		Expect(err).ToNot(HaveOccurred())
+		request.SetBasicAuth(user, "wrong")
+		response, err := env.Client().Do(request)
+		Expect(err).ToNot(HaveOccurred())
+		expectStatus(response, http.StatusUnauthorized)
+	})
+
+	It("denies access to orgs without a role", func() {
+		expectStatus(curlAs("GET", fmt.Sprintf("%s/api/v1/orgs/%s/applications", serverURL, org), ""),
+			http.StatusForbidden)
+	})
+
+	It("denies the admin routes to non-admins", func() {
+		expectStatus(curlAs("GET", fmt.Sprintf("%s/api/v1/users", serverURL), ""), http.StatusForbidden)
+		expectStatus(curlAs("POST", fmt.Sprintf("%s/api/v1/orgs", serverURL), `{"name":"nope"}`),
+			http.StatusForbidden)
+	})
+
+	When("the user is a viewer of an org", func() {
+		BeforeEach(func() {
+			response, err := env.Curl("POST", fmt.Sprintf("%s/api/v1/users/%s/orgs/%s", serverURL, user, org),
+				strings.NewReader(`{"role":"org-viewer"}`))
+			Expect(err).ToNot(HaveOccurred())
+			expectStatus(response, http.StatusOK)
+		})
+
+		It("allows reading, but not writing", func() {
+			expectStatus(curlAs("GET", fmt.Sprintf("%s/api/v1/orgs/%s/applications", serverURL, org), ""),
+				http.StatusOK)
+			expectStatus(curlAs("POST", fmt.Sprintf("%s/api/v1/orgs/%s/applications", serverURL, org),
+				`{"name":"nope"}`), http.StatusForbidden)
+		})
+
+		It("lists only the org of the user", func() {
+			response := curlAs("GET", fmt.Sprintf("%s/api/v1/orgs", serverURL), "")
+			defer response.Body.Close()
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+			Expect(string(bodyBytes)).To(Equal(fmt.Sprintf(`["%s"]`, org)))
+		})
+
+		It("shows only the org of the user in the dashboard", func() {
+			response := curlAs("GET", serverURL+"/", "")
+			defer response.Body.Close()
+			bodyBytes, err := ioutil.ReadAll(response.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(response.StatusCode).To(Equal(http.StatusOK), string(bodyBytes))
+			Expect(string(bodyBytes)).To(ContainSubstring(org))
+			Expect(string(bodyBytes)).ToNot(ContainSubstring(otherOrg))
+
+			expectStatus(curlAs("GET", fmt.Sprintf("%s/orgs/target/%s", serverURL, otherOrg), ""),
+				http.StatusForbidden)
+		})
+	})
+
+	When("the user is a developer of an org", func() {
+		BeforeEach(func() {
+			response, err := env.Curl("POST", fmt.Sprintf("%s/api/v1/users/%s/orgs/%s", serverURL, user, org),
+				strings.NewReader(`{"role":"org-developer"}`))
+			Expect(err).ToNot(HaveOccurred())
+			expectStatus(response, http.StatusOK)
+		})
+
+		It("allows writing to that org only", func() {
+			app := catalog.NewAppName()
+			expectStatus(curlAs("POST", fmt.Sprintf("%s/api/v1/orgs/%s/applications", serverURL, org),
+				fmt.Sprintf(`{"name":"%s"}`, app)), http.StatusOK)
+			expectStatus(curlAs("POST", fmt.Sprintf("%s/api/v1/orgs/%s/applications", serverURL, otherOrg),
+				fmt.Sprintf(`{"name":"%s"}`, app)), http.StatusForbidden)
+		})
+
+		It("does not allow changing the settings of the org", func() {
+			expectStatus(curlAs("PATCH", fmt.Sprintf("%s/api/v1/orgs/%s", serverURL, org),
+				`{"buildpacks":{"builder_image":"paketobuildpacks/builder:base"}}`), http.StatusForbidden)
+		})
+	})
+})
@@ -113,6 +113,46 @@ var _ = Describe("apps env", func() {
 				Expect(deployedEnv(org, appName)).To(MatchRegexp("MYVAR"))
 			})
 		})
+
+		When("setting scoped environment variables", func() {
+			BeforeEach(func() {
+				out, err := env.Epinio(fmt.Sprintf("apps env set %s BUILDVAR buildvalue --scope build", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				out, err = env.Epinio(fmt.Sprintf("apps env set %s RUNVAR runvalue --scope runtime", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+			})
+
+			It("shows the scopes in the environment listing", func() {
+				out, err := env.Epinio(fmt.Sprintf("apps env list %s", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(MatchRegexp(`BUILDVAR.*\|.*buildvalue.*\|.*build`))
+				Expect(out).To(MatchRegexp(`RUNVAR.*\|.*runvalue.*\|.*runtime`))
+			})
+
+			It("keeps the scope when changing the value", func() {
+				out, err := env.Epinio(fmt.Sprintf("apps env set %s BUILDVAR other", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+
+				out, err = env.Epinio(fmt.Sprintf("apps env show %s BUILDVAR", appName), "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				Expect(out).To(MatchRegexp(`Scope.*\|.*build`))
+			})
+
+			It("injects only the runtime variables into the pushed workload", func() {
+				appDir := "../assets/sample-app"
+				out, err := env.Epinio(fmt.Sprintf("apps push %s", appName), appDir)
+				Expect(err).ToNot(HaveOccurred(), out)
+
+				Expect(deployedEnv(org, appName)).To(MatchRegexp("RUNVAR"))
+				Expect(deployedEnv(org, appName)).ToNot(MatchRegexp("BUILDVAR"))
+			})
+
+			It("rejects an unknown scope", func() {
+				out, err := env.Epinio(fmt.Sprintf("apps env set %s MYVAR myvalue --scope test", appName), "")
+				Expect(err).To(HaveOccurred(), out)
+				Expect(out).To(ContainSubstring("unknown scope 'test'"))
+			})
+		})
 	})
 
 	Describe("deployed app", func() {
@@ -3,6 +3,7 @@ package acceptance_test
 import (
 	"bufio"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
@@ -15,6 +16,7 @@ import (
 	"github.com/epinio/epinio/acceptance/helpers/proc"
 	"github.com/epinio/epinio/helpers"
 	v1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/mholt/archiver/v3"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -77,6 +79,62 @@ var _ = Describe("Apps", func() {
 			env.DeleteApp(appName)
 		})
 
+		It("pushes the head of a branch", func() {
+			wordpress := "https://github.com/epinio/example-wordpress"
+			pushLog, err := env.Epinio(fmt.Sprintf("apps push %s --git-url %s --branch main",
+				appName, wordpress), "")
+			Expect(err).ToNot(HaveOccurred(), pushLog)
+			Expect(pushLog).To(ContainSubstring(wordpress + " @ main"))
+			Expect(pushLog).ToNot(ContainSubstring("Uploading application code"))
+
+			Eventually(func() string {
+				out, err := env.Epinio("app list", "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(MatchRegexp(fmt.Sprintf(`%s.*\|.*1\/1.*\|.*`, appName)))
+
+			By("deleting the app")
+			env.DeleteApp(appName)
+		})
+
+		It("pushes with git credentials", func() {
+			out, err := env.Epinio("git-credentials create github https://github.com --username epinio --password s3cret", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("git-credentials list", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`github.*\|.*https://github.com.*\|.*epinio`))
+			Expect(out).ToNot(ContainSubstring("s3cret"))
+
+			wordpress := "https://github.com/epinio/example-wordpress"
+			pushLog, err := env.Epinio(fmt.Sprintf("apps push %s --git-url %s --branch main --git-credentials github",
+				appName, wordpress), "")
+			Expect(err).ToNot(HaveOccurred(), pushLog)
+
+			Eventually(func() string {
+				out, err := env.Epinio("app list", "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(MatchRegexp(fmt.Sprintf(`%s.*\|.*1\/1.*\|.*`, appName)))
+
+			By("deleting the app")
+			env.DeleteApp(appName)
+
+			out, err = env.Epinio("git-credentials delete github", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
+		It("fails for unknown git credentials", func() {
+			wordpress := "https://github.com/epinio/example-wordpress"
+			pushLog, err := env.Epinio(fmt.Sprintf("apps push %s --git-url %s --git-credentials unknown",
+				appName, wordpress), "")
+			Expect(err).To(HaveOccurred(), pushLog)
+			Expect(pushLog).To(ContainSubstring("Git credentials 'unknown' do not exist"))
+
+			By("deleting the app")
+			env.DeleteApp(appName)
+		})
+
 		Describe("update", func() {
 			It("respects the desired number of instances", func() {
 				wordpress := "https://github.com/epinio/example-wordpress"
@@ -114,6 +172,217 @@ var _ = Describe("Apps", func() {
 		})
 	})
 
+	When("pushing an app with a manifest", func() {
+		var manifestDir string
+
+		BeforeEach(func() {
+			var err error
+			manifestDir, err = ioutil.TempDir("", "epinio-manifest")
+			Expect(err).ToNot(HaveOccurred())
+
+			manifest := fmt.Sprintf(`name: %s
+instances: 2
+env:
+  MANIFEST_VAR: from-manifest
+staging:
+  docker_image_url: %s
+`, appName, dockerImageURL)
+			err = ioutil.WriteFile(path.Join(manifestDir, "epinio.yml"), []byte(manifest), 0600)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			os.RemoveAll(manifestDir)
+		})
+
+		It("takes the application configuration from the manifest", func() {
+			out, err := env.Epinio("apps push", manifestDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio("app list", "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(MatchRegexp(fmt.Sprintf(`%s.*\|.*2\/2.*\|.*`, appName)))
+
+			out, err = env.Epinio("apps env list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`MANIFEST_VAR.*\|.*from-manifest`))
+		})
+
+		It("lets the command line override the manifest", func() {
+			out, err := env.Epinio("apps push --instances 1", manifestDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio("app list", "")
+				Expect(err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(MatchRegexp(fmt.Sprintf(`%s.*\|.*1\/1.*\|.*`, appName)))
+		})
+	})
+
+	When("staging the app fails", func() {
+		var sourceDir string
+
+		BeforeEach(func() {
+			var err error
+			sourceDir, err = ioutil.TempDir("", "epinio-failing-app")
+			Expect(err).ToNot(HaveOccurred())
+
+			// No buildpack detects an app in plain text
+			err = ioutil.WriteFile(path.Join(sourceDir, "README.txt"), []byte("not an app\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			os.RemoveAll(sourceDir)
+		})
+
+		It("fails the push with the failed step", func() {
+			out, err := env.Epinio("apps push "+appName, sourceDir)
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("staging failed in step 'create' of task 'stage'"))
+			Expect(out).To(ContainSubstring("Last lines of the step log"))
+			Expect(out).ToNot(ContainSubstring("timed out"))
+		})
+	})
+
+	When("pushing an app repeatedly", func() {
+		It("keeps the build cache of the app until the app is deleted", func() {
+			appDir := "../assets/sample-app"
+			out, err := env.Epinio(fmt.Sprintf("apps push %s", appName), appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			cache := fmt.Sprintf("cache.%s.%s", org, appName)
+			out, err = helpers.Kubectl(fmt.Sprintf("get pvc --namespace tekton-staging %s", cache))
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("apps push %s --clear-cache", appName), appDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("App is online"))
+
+			env.DeleteApp(appName)
+			Eventually(func() string {
+				out, _ := helpers.Kubectl(fmt.Sprintf("get pvc --namespace tekton-staging %s", cache))
+				return out
+			}, "1m").Should(ContainSubstring("not found"))
+		})
+	})
+
+	When("pushing an app with a Dockerfile", func() {
+		var sourceDir string
+
+		BeforeEach(func() {
+			var err error
+			sourceDir, err = ioutil.TempDir("", "epinio-dockerfile-app")
+			Expect(err).ToNot(HaveOccurred())
+
+			dockerfile := "FROM nginx:alpine\nRUN sed -i 's/listen  *80;/listen 8080;/' /etc/nginx/conf.d/default.conf\nCOPY index.html /usr/share/nginx/html/\n"
+			err = ioutil.WriteFile(path.Join(sourceDir, "Dockerfile"), []byte(dockerfile), 0600)
+			Expect(err).ToNot(HaveOccurred())
+			err = ioutil.WriteFile(path.Join(sourceDir, "index.html"), []byte("built from a Dockerfile\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			os.RemoveAll(sourceDir)
+		})
+
+		It("builds the image from the Dockerfile", func() {
+			out, err := env.Epinio("apps push "+appName, sourceDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("App is online"))
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Builder.*\|.*auto`))
+
+			out, err = env.Epinio(fmt.Sprintf("app logs --staging %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("step-create"))
+			Expect(out).ToNot(ContainSubstring("paketo"))
+		})
+
+		It("rejects an unknown builder", func() {
+			out, err := env.Epinio(fmt.Sprintf("app create %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s --builder make", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("unknown builder 'make'"))
+		})
+	})
+
+	When("pushing an app from an archive", func() {
+		var archiveDir string
+
+		BeforeEach(func() {
+			var err error
+			archiveDir, err = ioutil.TempDir("", "epinio-archive-app")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = archiver.Archive([]string{"../assets/sample-app/htdocs", "../assets/sample-app/buildpack.yml"},
+				path.Join(archiveDir, "sample-app.zip"))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			os.RemoveAll(archiveDir)
+		})
+
+		It("unpacks and stages it", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s %s", appName, path.Join(archiveDir, "sample-app.zip")), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Uploading application archive"))
+			Expect(out).To(ContainSubstring("App is online"))
+		})
+
+		It("rejects an unsupported format", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s ../assets/sample-app/buildpack.yml", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Unsupported archive format"))
+		})
+	})
+
+	When("pushing an app with an ignore file", func() {
+		var sourceDir string
+
+		BeforeEach(func() {
+			var err error
+			sourceDir, err = ioutil.TempDir("", "epinio-ignore-app")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = ioutil.WriteFile(path.Join(sourceDir, "index.php"), []byte("<?php phpinfo(); ?>\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+			err = ioutil.WriteFile(path.Join(sourceDir, "secret.env"), []byte("TOKEN=secret\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+			err = ioutil.WriteFile(path.Join(sourceDir, ".epinioignore"), []byte("*.env\n"), 0644)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(sourceDir)
+		})
+
+		It("lists the files to upload, without the ignored ones", func() {
+			out, err := env.Epinio(fmt.Sprintf("apps push %s --show-files", appName), sourceDir)
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("index.php"))
+			Expect(out).ToNot(ContainSubstring("secret.env"))
+			Expect(out).To(MatchRegexp(`Ignore File.*\.epinioignore`))
+			Expect(out).To(MatchRegexp(`Total.*1 files`))
+
+			out, err = env.Epinio("app list", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring(appName))
+		})
+	})
+
 	When("pushing an app multiple times", func() {
 		var (
 			timeout  = 30 * time.Second
@@ -356,11 +625,465 @@ var _ = Describe("Apps", func() {
 			}, "1m").Should(MatchRegexp(`Status\s*\|\s*3\/3\s*\|`))
 		})
 
+		It("applies the runtime settings and keeps them for the next push", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app update %s --readiness tcp,period=5 --memory-request 64Mi --memory-limit 256Mi", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			container := func() string {
+				out, err := helpers.Kubectl(fmt.Sprintf("get deployment --namespace %s %s -o=jsonpath='{.spec.template.spec.containers[0]}'", org, appName))
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}
+			Expect(container()).To(ContainSubstring(`"readinessProbe":{`))
+			Expect(container()).To(ContainSubstring(`"limits":{"memory":"256Mi"}`))
+
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+			Expect(container()).To(ContainSubstring(`"limits":{"memory":"256Mi"}`))
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Readiness Probe\s*\|\s*tcp, period=5`))
+
+			By("removing the memory limit")
+			out, err = env.Epinio(fmt.Sprintf("app update %s --memory-limit none", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(container()).ToNot(ContainSubstring(`"limits"`))
+			Expect(container()).To(ContainSubstring(`"requests":{"memory":"64Mi"}`))
+		})
+
+		It("rejects bad runtime settings", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app update %s --cpu-request 2 --cpu-limit 1", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("cpu request exceeds the cpu limit"))
+		})
+
 		AfterEach(func() {
 			env.DeleteApp(appName)
 		})
 	})
 
+	Describe("autoscale", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("scales the app between the bounds and leaves the instances to the autoscaler", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app update %s --cpu-request 100m", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("app autoscale %s --min 2 --max 4 --cpu-percent 60", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get hpa --namespace %s %s -o=jsonpath='{.spec.minReplicas} {.spec.maxReplicas} {.spec.targetCPUUtilizationPercentage}'", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(Equal("2 4 60"))
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "2m").Should(MatchRegexp(`Status\s*\|\s*2\/2\s*\|`))
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Autoscale\s*\|\s*2 - 4 instances, at 60% cpu`))
+
+			By("refusing a fixed number of instances")
+			out, err = env.Epinio(fmt.Sprintf("app update %s -i 1", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("application is autoscaled"))
+
+			By("ignoring the instances of a push")
+			env.MakeDockerImageApp(appName, 2, dockerImageURL)
+
+			By("disabling the autoscaling")
+			out, err = env.Epinio(fmt.Sprintf("app autoscale %s --disable", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Autoscale\s*\|\s*off`))
+		})
+
+		It("rejects an app without cpu request", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app autoscale %s --min 1 --max 2 --cpu-percent 60", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("the application has no cpu request"))
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get hpa --namespace %s %s", org, appName))
+			Expect(err).To(HaveOccurred(), out)
+		})
+
+		It("rejects bad bounds", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app autoscale %s --min 3 --max 2", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("maximum instances should not be below the minimum"))
+		})
+	})
+
+	Describe("routes", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("adds and removes routes of the app", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+			route := "custom-" + appName + ".example.com"
+
+			out, err := env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app route list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("https://" + route))
+			Expect(out).To(MatchRegexp(`https://` + appName + `\..*\.omg\.howdoi\.website`))
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get ingress --namespace %s %s -o=jsonpath='{.spec.rules[*].host}'", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(route))
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get certificate --namespace %s %s", org, route))
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			By("removing the custom route")
+			out, err = env.Epinio(fmt.Sprintf("app route remove %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get ingress --namespace %s %s -o=jsonpath='{.spec.rules[*].host}'", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring(route))
+
+			By("refusing to remove the last route")
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			defaultRoute := regexp.MustCompile(`Routes\s*\|\s*(\S+)`).FindStringSubmatch(out)[1]
+
+			out, err = env.Epinio(fmt.Sprintf("app route remove %s %s", appName, defaultRoute), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("cannot remove the last route of an application"))
+		})
+
+		It("rejects a route in use by another app", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+			route := "taken-" + appName + ".example.com"
+
+			out, err := env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			otherApp := catalog.NewAppName()
+			env.MakeDockerImageApp(otherApp, 1, dockerImageURL)
+			defer env.DeleteApp(otherApp)
+
+			out, err = env.Epinio(fmt.Sprintf("app route add %s %s", otherApp, route), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("is in use by application '" + appName + "'"))
+		})
+
+		It("rejects a route saved for another app which is not deployed yet", func() {
+			out, err := env.Epinio("app create "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			route := "saved-" + appName + ".example.com"
+
+			out, err = env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			otherApp := catalog.NewAppName()
+			env.MakeDockerImageApp(otherApp, 1, dockerImageURL)
+			defer env.DeleteApp(otherApp)
+
+			out, err = env.Epinio(fmt.Sprintf("app route add %s %s", otherApp, route), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("is in use by application '" + appName + "'"))
+		})
+
+		It("rejects the routes of the system", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := helpers.Kubectl("get ingress --namespace epinio epinio -o=jsonpath='{.spec.rules[0].host}'")
+			Expect(err).ToNot(HaveOccurred(), out)
+			route := strings.TrimSpace(out)
+
+			out, err = env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("route '" + route + "' is reserved"))
+		})
+	})
+
+	Describe("certificates", func() {
+		var certDir string
+
+		BeforeEach(func() {
+			var err error
+			certDir, err = ioutil.TempDir("", "epinio-certificate")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+			os.RemoveAll(certDir)
+		})
+
+		It("serves a route with the uploaded certificate", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+			route := "byo-" + appName + ".example.com"
+
+			out, err := proc.Run(fmt.Sprintf(`openssl req -x509 -newkey rsa:2048 -nodes -days 30 -subj "/CN=%[1]s" -addext "subjectAltName=DNS:%[1]s" -keyout key.pem -out cert.pem`, route), certDir, false)
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			By("rejecting the certificate for another domain")
+			out, err = env.Epinio(fmt.Sprintf("certificate upload other.example.com --cert %[1]s/cert.pem --key %[1]s/key.pem --ca %[1]s/cert.pem", certDir), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("certificate is not valid for 'other.example.com'"))
+
+			out, err = env.Epinio(fmt.Sprintf("certificate upload %[1]s --cert %[2]s/cert.pem --key %[2]s/key.pem --ca %[2]s/cert.pem", route, certDir), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			defer func() {
+				out, err := env.Epinio("certificate delete "+route, "")
+				Expect(err).ToNot(HaveOccurred(), out)
+			}()
+
+			out, err = env.Epinio("certificate list", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(route))
+
+			out, err = env.Epinio(fmt.Sprintf("app route add %s %s", appName, route), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get ingress --namespace %s %s -o=jsonpath='{.spec.tls[*].secretName}'", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("epinio-tls-" + route))
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get certificate --namespace %s %s", org, route))
+			Expect(err).To(HaveOccurred(), out)
+		})
+	})
+
+	Describe("staging policy", func() {
+		BeforeEach(func() {
+			out, err := env.Epinio(fmt.Sprintf("app create %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("queues staging by default", func() {
+			out, err := env.Epinio(fmt.Sprintf("app show %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Staging Policy.*\|.*queue`))
+
+			out, err = env.Epinio(fmt.Sprintf("app stage list %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("The application is not staging"))
+		})
+
+		It("changes the staging policy", func() {
+			out, err := env.Epinio(fmt.Sprintf("app update %s --staging-policy reject", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("app show %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Staging Policy.*\|.*reject`))
+		})
+
+		It("rejects an unknown staging policy", func() {
+			out, err := env.Epinio(fmt.Sprintf("app update %s --staging-policy later", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("unknown staging policy 'later'"))
+		})
+
+		It("fails to cancel the staging of an idle app", func() {
+			out, err := env.Epinio(fmt.Sprintf("app stage cancel %s", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("the application is not staging"))
+		})
+
+		It("fails to cancel an unknown staging", func() {
+			out, err := env.Epinio(fmt.Sprintf("app stage cancel %s --stage-id missing-stage", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("missing-stage"))
+		})
+	})
+
+	Describe("buildpacks", func() {
+		BeforeEach(func() {
+			out, err := env.Epinio(fmt.Sprintf("app create %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("pins the builder image and the buildpacks", func() {
+			out, err := env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Builder Image.*\|.*paketobuildpacks/builder:full`))
+			Expect(out).To(MatchRegexp(`Buildpacks.*\|.*all of the builder`))
+
+			out, err = env.Epinio(fmt.Sprintf("app update %s --builder-image paketobuildpacks/builder:base --buildpack paketo-buildpacks/php", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Builder Image.*\|.*paketobuildpacks/builder:base`))
+			Expect(out).To(MatchRegexp(`Buildpacks.*\|.*paketo-buildpacks/php`))
+		})
+
+		It("rejects a bad buildpack", func() {
+			out, err := env.Epinio(fmt.Sprintf("app update %s --buildpack ''", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad buildpack"))
+		})
+	})
+
+	Describe("events", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("shows the events of the app workload", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app events %s", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Deployment/" + appName))
+			Expect(out).To(ContainSubstring("ScalingReplicaSet"))
+			Expect(out).To(MatchRegexp(`Pod/%s-\S+: `, appName))
+		})
+
+		It("fails for an unknown app", func() {
+			out, err := env.Epinio("app events missing-app", "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Application 'missing-app' does not exist"))
+		})
+	})
+
+	Describe("exec", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("runs a command in an instance of the app", func() {
+			env.MakeDockerImageApp(appName, 2, dockerImageURL)
+
+			out, err := env.Epinio("app show "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			second := regexp.MustCompile(`\|\s*1\s*\|\s*(` + appName + `-\S+)`).FindStringSubmatch(out)
+			Expect(second).ToNot(BeNil(), out)
+
+			out, err = env.Epinio(fmt.Sprintf("app exec %s --instance 1 -- printenv HOSTNAME", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring(second[1]))
+
+			By("passing the exit status of the command")
+			out, err = env.Epinio(fmt.Sprintf("app exec %s -- sh -c 'exit 3'", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+
+			By("rejecting an unknown instance")
+			out, err = env.Epinio(fmt.Sprintf("app exec %s --instance 2 -- true", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("instance 2 not found"))
+		})
+	})
+
+	Describe("port-forward", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("forwards a local port to the app", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			p, err := proc.Get(nodeTmpDir+"/epinio app port-forward "+appName+" 18080:8080", "")
+			Expect(err).NotTo(HaveOccurred())
+			defer func() {
+				if p.Process != nil {
+					p.Process.Kill()
+				}
+			}()
+			go p.Run()
+
+			Eventually(func() int {
+				resp, err := http.Get("http://127.0.0.1:18080/")
+				if err != nil {
+					return 0
+				}
+				resp.Body.Close()
+				return resp.StatusCode
+			}, "1m").Should(Equal(http.StatusOK))
+		})
+
+		It("rejects a bad port", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("app port-forward %s 8080:http", appName), "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("bad remote port"))
+		})
+	})
+
+	Describe("releases and rollback", func() {
+		AfterEach(func() {
+			env.DeleteApp(appName)
+		})
+
+		It("records a release per deployment and rolls back to an earlier one", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio(fmt.Sprintf("apps env set %s MYVAR myvalue", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err = env.Epinio("app releases "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`\|\s*1\s*\|.*` + dockerImageURL))
+			Expect(out).To(MatchRegexp(`\|\s*2\s*\|.*` + dockerImageURL))
+
+			By("keeping the environments of the releases in secrets")
+			out, err = helpers.Kubectl(fmt.Sprintf("get secret --namespace %s %s.release.2 -o jsonpath={.data.environment}", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(BeEmpty())
+
+			out, err = helpers.Kubectl(fmt.Sprintf("get applications.app.k8s.io --namespace %s %s -o yaml", org, appName))
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("myvalue"))
+
+			out, err = env.Epinio("app rollback "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Rolled back to release 1"))
+
+			out, err = env.Epinio("app releases "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`\|\s*3\s*\|.*rollback to 1`))
+
+			By("restoring the environment of the release")
+			out, err = env.Epinio("app env list "+appName, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).ToNot(ContainSubstring("MYVAR"))
+		})
+
+		It("fails to roll back an app without an earlier release", func() {
+			env.MakeDockerImageApp(appName, 1, dockerImageURL)
+
+			out, err := env.Epinio("app rollback "+appName, "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("no earlier release"))
+		})
+	})
+
 	Describe("list and show", func() {
 		var serviceCustomName string
 		BeforeEach(func() {
@@ -397,6 +1120,20 @@ var _ = Describe("Apps", func() {
 				Expect(err).ToNot(HaveOccurred(), out)
 				return out
 			}, "1m").Should(MatchRegexp(`Status .*\|.* 1\/1`))
+
+			Expect(out).To(MatchRegexp("Instances:"))
+			Expect(out).To(MatchRegexp(`\| ` + appName + `-\S+ \s*\| true\s*\| Running\s*\| 0 `))
+		})
+
+		It("flags crash looping instances", func() {
+			out, err := env.Epinio(fmt.Sprintf("app update %s --liveness tcp,delay=1,period=1,failures=1 --port 9999", appName), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			Eventually(func() string {
+				out, err := env.Epinio("app show "+appName, "")
+				ExpectWithOffset(1, err).ToNot(HaveOccurred(), out)
+				return out
+			}, "5m").Should(MatchRegexp(`Instance ` + appName + `-\S+ is crash looping`))
 		})
 
 		Describe("no instances", func() {
@@ -14,6 +14,14 @@ func NewAppName() string {
 	return "apps-" + strconv.Itoa(int(time.Now().Nanosecond()))
 }
 
+func NewUserName() string {
+	return "user-" + strconv.Itoa(int(time.Now().Nanosecond()))
+}
+
+func NewTokenName() string {
+	return "token-" + strconv.Itoa(int(time.Now().Nanosecond()))
+}
+
 func NewServiceName() string {
 	return "service-" + strconv.Itoa(int(time.Now().Nanosecond()))
 }
@@ -37,6 +37,36 @@ var _ = Describe("Orgs", func() {
 		})
 	})
 
+	Describe("org update", func() {
+		It("sets the buildpacks defaults of the org", func() {
+			org := catalog.NewOrgName()
+			env.SetupAndTargetOrg(org)
+
+			out, err := env.Epinio("org show "+org, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Builder Image.*\|.*default`))
+			Expect(out).To(MatchRegexp(`Buildpacks.*\|.*all of the builder`))
+
+			out, err = env.Epinio(fmt.Sprintf("org update %s --builder-image paketobuildpacks/builder:base --buildpack paketo-buildpacks/nodejs", org), "")
+			Expect(err).ToNot(HaveOccurred(), out)
+
+			out, err = env.Epinio("org show "+org, "")
+			Expect(err).ToNot(HaveOccurred(), out)
+			Expect(out).To(MatchRegexp(`Builder Image.*\|.*paketobuildpacks/builder:base`))
+			Expect(out).To(MatchRegexp(`Buildpacks.*\|.*paketo-buildpacks/nodejs`))
+
+			By("switching org back to default")
+			out, err = env.Epinio("target workspace", "")
+			Expect(err).ToNot(HaveOccurred(), out)
+		})
+
+		It("fails for an unknown org", func() {
+			out, err := env.Epinio("org update missing-org --builder-image paketobuildpacks/builder:base", "")
+			Expect(err).To(HaveOccurred(), out)
+			Expect(out).To(ContainSubstring("Organization 'missing-org' does not exist"))
+		})
+	})
+
 	Describe("org delete", func() {
 		It("deletes an org", func() {
 			org := catalog.NewOrgName()
@@ -22,6 +22,7 @@ import (
 	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -33,11 +34,13 @@ type Epinio struct {
 var _ kubernetes.Deployment = &Epinio{}
 
 const (
-	EpinioDeploymentID  = "epinio"
-	epinioServerYaml    = "epinio/server.yaml"
-	epinioRolesYAML     = "epinio/roles.yaml"
-	epinioBasicAuthYaml = "epinio/basicauth.yaml"
-	applicationCRDYaml  = "epinio/app-crd.yaml"
+	EpinioDeploymentID = "epinio"
+	// EpinioServerURL is the URL of the API server within the cluster,
+	// e.g. for the webhooks of gitea
+	EpinioServerURL    = "http://epinio-server." + EpinioDeploymentID + ".svc.cluster.local"
+	epinioServerYaml   = "epinio/server.yaml"
+	epinioRolesYAML    = "epinio/roles.yaml"
+	applicationCRDYaml = "epinio/app-crd.yaml"
 )
 
 func (k *Epinio) ID() string {
@@ -80,16 +83,6 @@ func (k Epinio) Delete(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI
 		return errors.Wrap(err, fmt.Sprintf("Deleting %s failed:\n%s", epinioRolesYAML, out))
 	}
 
-	// (yyy) Note: We ignore deletion errors due to a mising
-	// Middleware CRD. That indicates that a traefik v1 controller
-	// was used, and the object was not applied. See also (xxx).
-
-	if out, err := helpers.KubectlDeleteEmbeddedYaml(epinioBasicAuthYaml, true); err != nil {
-		if !strings.Contains(out, `no matches for kind "Middleware"`) {
-			return errors.Wrap(err, fmt.Sprintf("Deleting %s failed:\n%s", epinioServerYaml, out))
-		}
-	}
-
 	message := "Deleting Epinio namespace " + EpinioDeploymentID
 	_, err = helpers.WaitForCommandCompletion(ui, message,
 		func() (string, error) {
@@ -134,10 +127,20 @@ func (k Epinio) apply(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI,
 
 	issuer := options.GetStringNG("tls-issuer")
 	nodePort := options.GetBoolNG("use-internal-registry-node-port")
-	if out, err := k.applyEpinioConfigYaml(ctx, c, ui, authAPI, issuer, nodePort); err != nil {
+	oidc := oidcConfig{
+		issuer:   options.GetStringNG("oidc-issuer"),
+		clientID: options.GetStringNG("oidc-client-id"),
+	}
+	cacheSize := options.GetStringNG("build-cache-size")
+	uploadMaxSize := options.GetStringNG("upload-max-size")
+	if out, err := k.applyEpinioConfigYaml(ctx, c, ui, authAPI, issuer, nodePort, cacheSize, uploadMaxSize, oidc); err != nil {
 		return errors.Wrap(err, out)
 	}
 
+	if err := k.applyAdminUser(ctx, c, authAPI); err != nil {
+		return errors.Wrap(err, "failed to create the API admin user")
+	}
+
 	domain, err := options.GetString("system_domain", TektonDeploymentID)
 	if err != nil {
 		return errors.Wrap(err, "Couldn't get system_domain option")
@@ -240,26 +243,15 @@ func (k Epinio) Upgrade(ctx context.Context, c *kubernetes.Cluster, ui *termui.U
 	return k.apply(ctx, c, ui, options, true)
 }
 
-// Replaces ##current_epinio_version## with version.Version and applies the embedded yaml
-func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI, auth auth.PasswordAuth, issuer string, nodePort bool) (string, error) {
-	// (xxx) Apply traefik v2 middleware. This will fail for a
-	// traefik v1 controller.  Ignore error if it was due due to a
-	// missing Middleware CRD. That indicates presence of the
-	// traefik v1 controller. See also (yyy).
-
-	yamlPathOnDisk, err := helpers.ExtractFile(epinioBasicAuthYaml)
-	if err != nil {
-		return "", errors.New("Failed to extract embedded file: " + epinioBasicAuthYaml + " - " + err.Error())
-	}
-	defer os.Remove(yamlPathOnDisk)
-
-	out, err := helpers.Kubectl(fmt.Sprintf("apply -n %s --filename %s", EpinioDeploymentID, yamlPathOnDisk))
-	if err != nil && !strings.Contains(out, `no matches for kind "Middleware"`) {
-		return "", err
-	}
-
-	yamlPathOnDisk, err = helpers.ExtractFile(epinioServerYaml)
+// oidcConfig is the OpenID Connect issuer the API server accepts ID tokens from
+type oidcConfig struct {
+	issuer   string
+	clientID string
+}
 
+// Replaces ##current_epinio_version## with version.Version and applies the embedded yaml
+func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster, ui *termui.UI, auth auth.PasswordAuth, issuer string, nodePort bool, cacheSize, uploadMaxSize string, oidc oidcConfig) (string, error) {
+	yamlPathOnDisk, err := helpers.ExtractFile(epinioServerYaml)
 	if err != nil {
 		return "", errors.New("Failed to extract embedded file: " + epinioServerYaml + " - " + err.Error())
 	}
@@ -270,20 +262,12 @@ func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster
 		return "", err
 	}
 
-	htpasswd, err := auth.Htpassword()
-	if err != nil {
-		return "", err
-	}
-	encodedCredentials := base64.StdEncoding.EncodeToString([]byte(htpasswd))
 	encodedUser := base64.StdEncoding.EncodeToString([]byte(auth.Username))
 	encodedPass := base64.StdEncoding.EncodeToString([]byte(auth.Password))
 
 	re := regexp.MustCompile(`##current_epinio_version##`)
 	renderedFileContents := re.ReplaceAll(fileContents, []byte(version.Version))
 
-	re = regexp.MustCompile(`##api_credentials##`)
-	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(encodedCredentials))
-
 	re = regexp.MustCompile(`##api_user##`)
 	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(encodedUser))
 
@@ -296,6 +280,18 @@ func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster
 	re = regexp.MustCompile(`##use_internal_registry_node_port##`)
 	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(strconv.FormatBool(nodePort)))
 
+	re = regexp.MustCompile(`##build_cache_size##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(cacheSize))
+
+	re = regexp.MustCompile(`##upload_max_size##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(uploadMaxSize))
+
+	re = regexp.MustCompile(`##oidc_issuer##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(oidc.issuer))
+
+	re = regexp.MustCompile(`##oidc_client_id##`)
+	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(oidc.clientID))
+
 	re = regexp.MustCompile(`##trace_level##`)
 	renderedFileContents = re.ReplaceAll(renderedFileContents, []byte(strconv.Itoa(viper.GetInt("trace-level"))))
 
@@ -323,6 +319,37 @@ func (k Epinio) applyEpinioConfigYaml(ctx context.Context, c *kubernetes.Cluster
 	return helpers.Kubectl(fmt.Sprintf("apply -n %s --filename %s", TektonStagingNamespace, yamlPathOnDisk))
 }
 
+// applyAdminUser stores the API user given at installation as the initial
+// admin user. On upgrade the existing user gets the possibly new password.
+func (k Epinio) applyAdminUser(ctx context.Context, c *kubernetes.Cluster, authAPI auth.PasswordAuth) error {
+	hash, err := auth.HashBcrypt(authAPI.Password)
+	if err != nil {
+		return err
+	}
+
+	secret, err := auth.NewUserSecret(authAPI.Username, hash, true)
+	if err != nil {
+		return err
+	}
+
+	secrets := c.Kubectl.CoreV1().Secrets(auth.UserNamespace)
+
+	existing, err := secrets.Get(ctx, secret.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	// Keep the org roles of the existing user
+	secret.Data["orgs"] = existing.Data["orgs"]
+	secret.ResourceVersion = existing.ResourceVersion
+	_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	return err
+}
+
 func (k *Epinio) createIngress(ctx context.Context, c *kubernetes.Cluster, subdomain string) error {
 	pathTypePrefix := networkingv1.PathTypeImplementationSpecific
 	_, err := c.Kubectl.NetworkingV1().Ingresses(EpinioDeploymentID).Create(
@@ -333,15 +360,9 @@ func (k *Epinio) createIngress(ctx context.Context, c *kubernetes.Cluster, subdo
 				Namespace: EpinioDeploymentID,
 				Annotations: map[string]string{
 					"kubernetes.io/ingress.class": "traefik",
-					// Traefik v1 annotations for ingress with basic auth.
-					// See `assets/embedded-files/epinio/server.yaml` for
-					// the definition of the secret.
-					"ingress.kubernetes.io/auth-type":   "basic",
-					"ingress.kubernetes.io/auth-secret": "epinio-api-auth-secret",
-					// Traefik v2 annotation for ingress with basic auth.
-					// The name of the middleware is `(namespace)-(object)@kubernetescrd`.
-					"traefik.ingress.kubernetes.io/router.middlewares": EpinioDeploymentID + "-epinio-api-auth@kubernetescrd",
 					// Traefik v1/v2 tls annotations.
+					// Authentication is done by the API server itself,
+					// see `internal/api/v1/authentication.go`.
 					"traefik.ingress.kubernetes.io/router.entrypoints": "websecure",
 					"traefik.ingress.kubernetes.io/router.tls":         "true",
 				},
@@ -51,6 +51,7 @@ const (
 	tektonPipelineReleaseYamlPath = "tekton/pipeline-v0.23.0.yaml"
 	tektonAdminRoleYamlPath       = "tekton/admin-role.yaml"
 	tektonStagingYamlPath         = "tekton/buildpacks-task.yaml"
+	tektonKanikoStagingYamlPath   = "tekton/kaniko-task.yaml"
 	tektonPipelineYamlPath        = "tekton/stage-pipeline.yaml"
 )
 
@@ -336,10 +337,46 @@ func getRegistryCAHash(ctx context.Context, c *kubernetes.Cluster) (string, erro
 	return hash, nil
 }
 
+// stagingTasks are the tekton tasks building the app images, for the
+// buildpacks and the dockerfile staging pipelines. certsDir is the directory
+// of the certificates trusted by the builder of the task.
+var stagingTasks = []struct {
+	yamlPath string
+	certsDir string
+}{
+	{tektonStagingYamlPath, "/etc/ssl/certs"},
+	{tektonKanikoStagingYamlPath, "/kaniko/ssl/certs"},
+}
+
 func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, domain string) error {
-	yamlPathOnDisk, err := helpers.ExtractFile(tektonStagingYamlPath)
+	// TODO this workaround is only needed for untrusted certs.
+	//  Once we can reach Tekton via linkerd, blocked by
+	//  https://github.com/tektoncd/catalog/issues/757, we can remove the
+	//  workaround.
+
+	// Add volume and volume mount of registry-certs for local deployment
+	// since tekton should trust the registry-certs.
+	caHash, err := getRegistryCAHash(ctx, c)
+	if err != nil {
+		return errors.Wrapf(err, "Failed to get registry CA from %s namespace", TektonStagingNamespace)
+	}
+
+	for _, task := range stagingTasks {
+		if err := applyTektonTask(ctx, c, task.yamlPath, caHash, task.certsDir); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyTektonTask creates the task of the embedded yaml file. With a caHash
+// the step "create" of the task trusts the registry CA, by mounting it into
+// the certsDir.
+func applyTektonTask(ctx context.Context, c *kubernetes.Cluster, yamlPath, caHash, certsDir string) error {
+	yamlPathOnDisk, err := helpers.ExtractFile(yamlPath)
 	if err != nil {
-		return errors.New("Failed to extract embedded file: " + tektonStagingYamlPath + " - " + err.Error())
+		return errors.New("Failed to extract embedded file: " + yamlPath + " - " + err.Error())
 	}
 	defer os.Remove(yamlPathOnDisk)
 
@@ -357,18 +394,6 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, domain strin
 		return errors.Wrapf(err, "failed to unmarshal task %s", string(fileContents))
 	}
 
-	// TODO this workaround is only needed for untrusted certs.
-	//  Once we can reach Tekton via linkerd, blocked by
-	//  https://github.com/tektoncd/catalog/issues/757, we can remove the
-	//  workaround.
-
-	// Add volume and volume mount of registry-certs for local deployment
-	// since tekton should trust the registry-certs.
-	caHash, err := getRegistryCAHash(ctx, c)
-	if err != nil {
-		return errors.Wrapf(err, "Failed to get registry CA from %s namespace", TektonStagingNamespace)
-	}
-
 	if caHash != "" {
 		volume := corev1.Volume{
 			Name: "registry-certs",
@@ -382,7 +407,7 @@ func applyTektonStaging(ctx context.Context, c *kubernetes.Cluster, domain strin
 
 		volumeMount := corev1.VolumeMount{
 			Name:      "registry-certs",
-			MountPath: fmt.Sprintf("%s/%s", "/etc/ssl/certs", caHash),
+			MountPath: fmt.Sprintf("%s/%s", certsDir, caHash),
 			SubPath:   "ca.crt",
 			ReadOnly:  true,
 		}
@@ -0,0 +1,69 @@
+package helpers
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/mholt/archiver/v3"
+	"github.com/pkg/errors"
+)
+
+// The archive formats known to ArchiveFormat. Java archives, i.e. jar and war
+// files, are zip archives.
+const (
+	ArchiveTar   = "tar"
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+// ArchiveFormat returns the format of the archive file, detected from its
+// content. It returns the empty string for unknown formats.
+func ArchiveFormat(file string) (string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	// The tar magic is found at offset 257
+	header := make([]byte, 512)
+	n, err := io.ReadFull(f, header)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	header = header[:n]
+
+	switch {
+	case bytes.HasPrefix(header, []byte{0x1f, 0x8b}):
+		return ArchiveTarGz, nil
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")), bytes.HasPrefix(header, []byte("PK\x05\x06")):
+		return ArchiveZip, nil
+	case len(header) >= 262 && bytes.Equal(header[257:262], []byte("ustar")):
+		return ArchiveTar, nil
+	case len(header) == 512 && bytes.Equal(header, make([]byte, 512)):
+		// The end marker of an empty tar archive
+		return ArchiveTar, nil
+	}
+
+	return "", nil
+}
+
+// Unarchive unpacks the archive file of the given format, see ArchiveFormat,
+// into the directory
+func Unarchive(file, dir, format string) error {
+	var unarchiver archiver.Unarchiver
+
+	switch format {
+	case ArchiveTar:
+		unarchiver = archiver.NewTar()
+	case ArchiveTarGz:
+		unarchiver = archiver.NewTarGz()
+	case ArchiveZip:
+		unarchiver = archiver.NewZip()
+	default:
+		return errors.Errorf("unknown archive format '%s'", format)
+	}
+
+	return unarchiver.Unarchive(file, dir)
+}
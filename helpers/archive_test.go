@@ -0,0 +1,59 @@
+package helpers_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/epinio/epinio/helpers"
+	"github.com/mholt/archiver/v3"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Archive", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "epinio-test")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = ioutil.WriteFile(path.Join(dir, "index.php"), []byte("<?php phpinfo(); ?>\n"), 0644)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	for name, format := range map[string]string{
+		"app.tar":    ArchiveTar,
+		"app.tar.gz": ArchiveTarGz,
+		"app.zip":    ArchiveZip,
+	} {
+		name, format := name, format
+
+		It("detects the format of "+name+" from the content, and unpacks it", func() {
+			// The name hides the format from the detection
+			archive := path.Join(dir, "blob")
+			Expect(archiver.Archive([]string{path.Join(dir, "index.php")}, path.Join(dir, name))).To(Succeed())
+			Expect(os.Rename(path.Join(dir, name), archive)).To(Succeed())
+
+			Expect(ArchiveFormat(archive)).To(Equal(format))
+
+			target := path.Join(dir, "app")
+			Expect(Unarchive(archive, target, format)).To(Succeed())
+			Expect(path.Join(target, "index.php")).To(BeARegularFile())
+		})
+	}
+
+	It("knows the gzipped tar fixture", func() {
+		Expect(ArchiveFormat(FixturePath("untar.tgz"))).To(Equal(ArchiveTarGz))
+	})
+
+	It("does not know other formats", func() {
+		Expect(ArchiveFormat(path.Join(dir, "index.php"))).To(BeEmpty())
+		Expect(Unarchive(path.Join(dir, "index.php"), dir, "")).To(MatchError(ContainSubstring("unknown archive format")))
+	})
+})
@@ -5,6 +5,9 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -27,13 +30,16 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/httpstream"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/kubernetes/scheme"
 	typedbatchv1 "k8s.io/client-go/kubernetes/typed/batch/v1"
 	restclient "k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/portforward"
 	"k8s.io/client-go/tools/remotecommand"
+	"k8s.io/client-go/transport/spdy"
 
 	// https://github.com/kubernetes/client-go/issues/345
 	_ "k8s.io/client-go/plugin/pkg/client/auth/oidc"
@@ -679,6 +685,126 @@ func (c *Cluster) execPod(namespace, podName, containerName string,
 	return nil
 }
 
+// ExecStream runs the command in the container of the pod, connected to the
+// given streams. A nil stdin runs the command without input. With tty set
+// the command runs in a terminal, whose size follows the sizes queue, if any,
+// and stderr is merged into stdout. A command exiting with a non-zero status
+// returns an error implementing k8s.io/client-go/util/exec.ExitError.
+func (c *Cluster) ExecStream(namespace, podName, containerName string, command []string, tty bool,
+	stdin io.Reader, stdout, stderr io.Writer, sizes remotecommand.TerminalSizeQueue) error {
+	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
+		Namespace(namespace).SubResource("exec")
+	option := &v1.PodExecOptions{
+		Container: containerName,
+		Command:   command,
+		Stdin:     stdin != nil,
+		Stdout:    true,
+		Stderr:    !tty,
+		TTY:       tty,
+	}
+	req.VersionedParams(
+		option,
+		scheme.ParameterCodec,
+	)
+	exec, err := remotecommand.NewSPDYExecutor(c.RestConfig, "POST", req.URL())
+	if err != nil {
+		return err
+	}
+
+	options := remotecommand.StreamOptions{
+		Stdin:             stdin,
+		Stdout:            stdout,
+		Tty:               tty,
+		TerminalSizeQueue: sizes,
+	}
+	if !tty {
+		options.Stderr = stderr
+	}
+
+	return exec.Stream(options)
+}
+
+// PortForward opens a connection to the port of the pod, tunnelled through
+// the API server. Closing the connection releases the tunnel. An error
+// reported by the cluster, e.g. for a port nobody listens on, is returned by
+// Read, after the end of the data.
+func (c *Cluster) PortForward(namespace, podName string, port int) (io.ReadWriteCloser, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(c.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	req := c.Kubectl.CoreV1().RESTClient().Post().Resource("pods").Name(podName).
+		Namespace(namespace).SubResource("portforward")
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, "POST", req.URL())
+
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to connect to the pod")
+	}
+
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(port))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		streamConn.Close()
+		return nil, errors.Wrap(err, "failed to create the error stream")
+	}
+	// Only the cluster writes to the error stream
+	errorStream.Close()
+
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		streamConn.Close()
+		return nil, errors.Wrap(err, "failed to create the data stream")
+	}
+
+	conn := &podConnection{
+		streamConn: streamConn,
+		data:       dataStream,
+		errorDone:  make(chan struct{}),
+	}
+	go func() {
+		defer close(conn.errorDone)
+		message, err := ioutil.ReadAll(errorStream)
+		if err == nil && len(message) > 0 {
+			conn.err = errors.Errorf("port %d of pod %s: %s", port, podName, string(message))
+		}
+	}()
+
+	return conn, nil
+}
+
+// podConnection is a connection to a port of a pod, see PortForward
+type podConnection struct {
+	streamConn httpstream.Connection
+	data       httpstream.Stream
+	errorDone  chan struct{}
+	err        error
+}
+
+func (p *podConnection) Read(data []byte) (int, error) {
+	n, err := p.data.Read(data)
+	if err == io.EOF {
+		<-p.errorDone
+		if p.err != nil {
+			return n, p.err
+		}
+	}
+	return n, err
+}
+
+func (p *podConnection) Write(data []byte) (int, error) {
+	return p.data.Write(data)
+}
+
+func (p *podConnection) Close() error {
+	return p.streamConn.Close()
+}
+
 // LabelNamespace adds a label to the namespace
 func (c *Cluster) LabelNamespace(ctx context.Context, namespace, labelKey, labelValue string) error {
 	patchContents := fmt.Sprintf(`{ "metadata": { "labels": { "%s": "%s" } } }`, labelKey, labelValue)
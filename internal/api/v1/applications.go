@@ -153,6 +153,46 @@ func (hc ApplicationsController) Show(w http.ResponseWriter, r *http.Request) AP
 		app.Status = `Inactive, without workload. Launch via "epinio app push"`
 	}
 
+	settings, err := application.Settings(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+	app.Settings = &settings
+
+	app.Autoscale, err = application.Autoscaler(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+
+	hook, err := application.AutoDeploy(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+	if hook != nil {
+		app.AutoDeploy = &models.AutoDeploy{Branch: hook.Branch}
+	}
+
+	app.Instances, err = application.NewWorkload(cluster, app.AppRef()).Instances(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app.StagingPolicy, err = application.StagingPolicy(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app.Builder, err = application.Builder(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+
+	buildpacks, err := application.StagingBuildpacks(ctx, cluster, app.AppRef())
+	if err != nil {
+		return InternalError(err)
+	}
+	app.Buildpacks = &buildpacks
+
 	js, err := json.Marshal(app)
 	if err != nil {
 		return InternalError(err)
@@ -196,43 +236,108 @@ func (hc ApplicationsController) Update(w http.ResponseWriter, r *http.Request)
 		return AppIsNotKnown(appName)
 	}
 
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var updateRequest models.UpdateAppRequest
+	err = json.Unmarshal(bodyBytes, &updateRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if updateRequest.Instances != nil && *updateRequest.Instances < 0 {
+		return NewBadRequest("instances param should be integer equal or greater than zero")
+	}
+	if updateRequest.StagingPolicy != "" {
+		if err := application.ValidateStagingPolicy(updateRequest.StagingPolicy); err != nil {
+			return BadRequest(err)
+		}
+	}
+	if updateRequest.Builder != "" {
+		if err := application.ValidateBuilder(updateRequest.Builder); err != nil {
+			return BadRequest(err)
+		}
+	}
+	if updateRequest.Buildpacks != nil {
+		if err := application.ValidateBuildpacks(*updateRequest.Buildpacks); err != nil {
+			return BadRequest(err)
+		}
+	}
+
 	// Application exists. It may not have a workload however.
 
+	appRef := models.NewAppRef(appName, org)
 	app, err := application.Lookup(ctx, cluster, org, appName)
 	if err != nil {
 		return InternalError(err)
 	}
 
-	if app == nil {
+	if app == nil && updateRequest.Instances != nil {
 		// App without workload cannot be scaled at the moment.
 		// TODO: Extend to stash the request in the app or attached resource
 		return NewAPIError("Unable to scale application without workload", "", http.StatusBadRequest)
 	}
 
-	defer r.Body.Close()
-	bodyBytes, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		return InternalError(err)
+	if updateRequest.Instances != nil {
+		autoscale, err := application.Autoscaler(ctx, cluster, appRef)
+		if err != nil {
+			return InternalError(err)
+		}
+		if autoscale != nil {
+			return NewBadRequest("application is autoscaled, change the bounds of the autoscaling instead")
+		}
 	}
 
-	var updateRequest models.UpdateAppRequest
-	err = json.Unmarshal(bodyBytes, &updateRequest)
-	if err != nil {
-		return BadRequest(err)
+	// Settings are kept on the application resource, and are applied
+	// by the next deployment of an app without workload.
+	if updateRequest.Settings != nil {
+		settings, apiErr := updateSettings(ctx, cluster, appRef, updateRequest.Settings)
+		if apiErr != nil {
+			return apiErr
+		}
+
+		if app != nil {
+			if err := applyWorkloadSettings(ctx, cluster, appRef, settings); err != nil {
+				return InternalError(err)
+			}
+		}
 	}
 
-	if updateRequest.Instances < 0 {
-		return NewBadRequest("instances param should be integer equal or greater than zero")
+	if updateRequest.StagingPolicy != "" {
+		err = application.StagingPolicySet(ctx, cluster, appRef, updateRequest.StagingPolicy)
+		if err != nil {
+			return InternalError(err)
+		}
 	}
 
-	workload := application.NewWorkload(cluster, app.AppRef())
-	err = workload.Scale(r.Context(), updateRequest.Instances)
-	if err != nil {
-		return InternalError(err)
+	if updateRequest.Builder != "" {
+		err = application.BuilderSet(ctx, cluster, appRef, updateRequest.Builder)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	if updateRequest.Buildpacks != nil {
+		_, err = application.BuildpacksUpdate(ctx, cluster, appRef, *updateRequest.Buildpacks)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	if updateRequest.Instances != nil {
+		workload := application.NewWorkload(cluster, appRef)
+		err = workload.Scale(r.Context(), *updateRequest.Instances)
+		if err != nil {
+			return InternalError(err)
+		}
 	}
 
 	return nil
 }
+
 func (hc ApplicationsController) Logs(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	params := httprouter.ParamsFromContext(ctx)
@@ -0,0 +1,237 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/domain"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// RouteIndex returns the routes of the app
+func (hc ApplicationsController) RouteIndex(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	routes, err := application.Routes(ctx, cluster, models.NewAppRef(appName, org))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, routes)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// RouteAdd adds a route to the app. The ingress of a running app serves the
+// route right away, with a certificate of its own.
+func (hc ApplicationsController) RouteAdd(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var req models.RouteRequest
+	if err := json.Unmarshal(bodyBytes, &req); err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	routes, err := application.Routes(ctx, cluster, app)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err)
+	}
+
+	mainDomain, err := domain.MainDomain(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	route, apiErr := checkRoute(ctx, cluster, app, req.Route, mainDomain)
+	if apiErr != nil {
+		return apiErr
+	}
+	if contains(routes, route) {
+		return NewAPIError(fmt.Sprintf("application already has route '%s'", route), "", http.StatusConflict)
+	}
+
+	routes = append(routes, route)
+	if apiErr := setRoutes(ctx, cluster, app, routes); apiErr != nil {
+		return apiErr
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// RouteRemove removes a route from the app, with its certificate. The last
+// route of an app cannot be removed.
+func (hc ApplicationsController) RouteRemove(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	route, err := url.PathUnescape(params.ByName("route"))
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	routes, err := application.Routes(ctx, cluster, app)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err)
+	}
+
+	remaining := []string{}
+	for _, r := range routes {
+		if r != route {
+			remaining = append(remaining, r)
+		}
+	}
+	if len(remaining) == len(routes) {
+		return NewAPIError(fmt.Sprintf("application has no route '%s'", route), "", http.StatusNotFound)
+	}
+	if len(remaining) == 0 {
+		return NewBadRequest("cannot remove the last route of an application")
+	}
+
+	if apiErr := setRoutes(ctx, cluster, app, remaining); apiErr != nil {
+		return apiErr
+	}
+
+	if err := auth.DeleteCertificate(ctx, cluster, org, route); err != nil {
+		return InternalError(err)
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// setRoutes saves the routes of the app, and applies them to the ingress of
+// a running app
+func setRoutes(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, routes []string) APIErrors {
+	if err := application.RoutesSet(ctx, cluster, app, routes); err != nil {
+		return InternalError(err, "failed to save the application routes")
+	}
+
+	if err := applyRoutes(ctx, cluster, app, routes); err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// applyRoutes updates the ingress of a running app for the routes. An app
+// which is not running is left alone, its next deployment creates the
+// ingress.
+func applyRoutes(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, routes []string) error {
+	_, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+
+	applicationCR, err := application.Get(ctx, cluster, app)
+	if err != nil {
+		return err
+	}
+
+	settings, err := application.Settings(ctx, cluster, app)
+	if err != nil {
+		return err
+	}
+
+	return deployIngress(ctx, cluster, deployParam{
+		AppRef:   app,
+		Routes:   routes,
+		Settings: settings,
+		Owner: metav1.OwnerReference{
+			APIVersion: applicationCR.GetAPIVersion(),
+			Kind:       applicationCR.GetKind(),
+			Name:       applicationCR.GetName(),
+			UID:        applicationCR.GetUID(),
+		},
+	})
+}
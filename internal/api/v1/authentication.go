@@ -0,0 +1,211 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/oidc"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// Authenticate is the middleware checking the credentials of every request
+// against the API users stored in the cluster. Authenticated requests get the
+// user injected into their context, see auth.CurrentUser.
+func Authenticate(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		log := tracelog.Logger(ctx)
+
+		user, apiErr := authenticatedUser(ctx, r)
+		if apiErr != nil {
+			log.V(1).Info("authentication failed", "reason", apiErr.Errors()[0].Title)
+			w.Header().Set("WWW-Authenticate", `Basic realm="epinio"`)
+			jsonErrorResponse(w, apiErr)
+			return
+		}
+
+		log.V(1).Info("authenticated", "user", user.Username)
+
+		ctx = context.WithValue(ctx, auth.CtxUserKey{}, user)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// authenticatedUser returns the user identified by the credentials of the
+// request. These are either an API token, as bearer token, or the user's name
+// and password, as basic auth.
+func authenticatedUser(ctx context.Context, r *http.Request) (*auth.User, APIErrors) {
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	if token, ok := bearerToken(r); ok {
+		if strings.HasPrefix(token, auth.TokenPrefix) {
+			user, err := auth.AuthenticateToken(ctx, cluster, token)
+			if err != nil {
+				if err == auth.ErrTokenNotKnown {
+					return nil, UserNotAuthenticated()
+				}
+				return nil, InternalError(err)
+			}
+			return user, nil
+		}
+
+		return oidcUser(ctx, cluster, token)
+	}
+
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return nil, UserNotAuthenticated()
+	}
+
+	user, err := auth.Authenticate(ctx, cluster, username, password)
+	if err != nil {
+		if err == auth.ErrUserNotKnown {
+			return nil, UserNotAuthenticated()
+		}
+		return nil, InternalError(err)
+	}
+
+	return user, nil
+}
+
+// oidcUser returns the user identified by an ID token of the OIDC issuer the
+// server is configured with. The identity is mapped to the Epinio user of the
+// same name, which holds the user's roles.
+func oidcUser(ctx context.Context, cluster *kubernetes.Cluster, token string) (*auth.User, APIErrors) {
+	log := tracelog.Logger(ctx)
+
+	verifier := oidcVerifier()
+	if verifier == nil {
+		return nil, UserNotAuthenticated()
+	}
+
+	claims, err := verifier.Verify(ctx, token)
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidToken) {
+			log.V(1).Info("bad oidc token", "error", err.Error())
+			return nil, UserNotAuthenticated()
+		}
+		return nil, InternalError(err)
+	}
+
+	user, err := auth.LookupUser(ctx, cluster, claims.Username())
+	if err != nil {
+		if err == auth.ErrUserNotKnown {
+			log.V(1).Info("oidc identity without epinio user", "user", claims.Username())
+			return nil, UserNotAuthenticated()
+		}
+		return nil, InternalError(err)
+	}
+
+	return user, nil
+}
+
+var (
+	verifierMutex sync.Mutex
+	verifierMemo  *oidc.Verifier
+	verifierKey   string
+)
+
+// oidcVerifier returns the verifier for the configured OIDC issuer and
+// client, or nil if there is none. The verifier is cached, as it holds the
+// keys of the issuer.
+func oidcVerifier() *oidc.Verifier {
+	issuer := viper.GetString("oidc-issuer")
+	clientID := viper.GetString("oidc-client-id")
+	if issuer == "" {
+		return nil
+	}
+
+	verifierMutex.Lock()
+	defer verifierMutex.Unlock()
+
+	if verifierMemo == nil || verifierKey != issuer+" "+clientID {
+		verifierMemo = oidc.NewVerifier(issuer, clientID, http.DefaultClient)
+		verifierKey = issuer + " " + clientID
+	}
+
+	return verifierMemo
+}
+
+// AuthConfig returns the configuration clients need to log in. It is a
+// public route, see publicRoutes.
+func AuthConfig(w http.ResponseWriter, r *http.Request) APIErrors {
+	err := jsonResponse(w, models.AuthConfigResponse{
+		OIDCIssuer:   viper.GetString("oidc-issuer"),
+		OIDCClientID: viper.GetString("oidc-client-id"),
+	})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// bearerToken returns the token of a bearer `Authorization` header, if any
+func bearerToken(r *http.Request) (string, bool) {
+	const prefix = "Bearer "
+
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+
+	return header[len(prefix):], true
+}
+
+// Authorize wraps the handler of a route outside of the API, i.e. of the
+// dashboard, with the check of the current user's roles, see authorize. The
+// route is neither an admin route nor does it change the org.
+func Authorize(h http.HandlerFunc) http.HandlerFunc {
+	return authorize(h, false, false)
+}
+
+// authorize wraps the handler of a route with the check of the current user's
+// roles. Admin routes are restricted to admins. Routes with an `:org`
+// parameter are restricted to the users with a suitable role in that org.
+// Routes which change the org are checked as such, whatever their method.
+// Everything else is open to all authenticated users.
+func authorize(h http.HandlerFunc, adminOnly, changesOrg bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		user := auth.CurrentUser(ctx)
+		if user == nil {
+			jsonErrorResponse(w, UserNotAuthenticated())
+			return
+		}
+
+		if adminOnly {
+			if !user.Admin {
+				jsonErrorResponse(w, UserNotAuthorized(user.Username))
+				return
+			}
+			h(w, r)
+			return
+		}
+
+		method := r.Method
+		if changesOrg {
+			method = http.MethodPost
+		}
+
+		org := httprouter.ParamsFromContext(ctx).ByName("org")
+		if org != "" && !user.Allowed(org, method) {
+			jsonErrorResponse(w, UserNotAuthorized(user.Username))
+			return
+		}
+
+		h(w, r)
+	}
+}
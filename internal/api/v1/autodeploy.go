@@ -0,0 +1,343 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/randstr"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/cli/clients/gitea"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// webhookMaxSize limits the size of the webhook payloads read
+const webhookMaxSize = 10 * 1024 * 1024
+
+// autoDeployUser is recorded as the user of the releases deployed by the
+// webhook
+const autoDeployUser = "auto-deploy"
+
+// giteaPushPayload holds the parts of the payload of a gitea push event used
+// for auto-deploy
+type giteaPushPayload struct {
+	Ref   string `json:"ref"`
+	After string `json:"after"`
+}
+
+// AutoDeploy turns on the auto-deploy mode of the app. A webhook in the
+// gitea repo of the app reports pushes to the branch to the Webhook
+// endpoint, which stages and deploys them.
+func (hc ApplicationsController) AutoDeploy(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var request models.AutoDeploy
+	if len(bodyBytes) > 0 {
+		if err := json.Unmarshal(bodyBytes, &request); err != nil {
+			return BadRequest(err)
+		}
+	}
+	if request.Branch == "" {
+		request.Branch = models.DefaultAutoDeployBranch
+	}
+	if err := application.ValidateBranch(request.Branch); err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := autoDeployApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	client, err := gitea.New(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := client.RepoExists(app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return NewBadRequest("application has no gitea repository, push it from its sources first")
+	}
+
+	// Replace the webhook of an earlier auto-deploy
+	current, err := application.AutoDeploy(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if current != nil {
+		if err := client.DeleteHook(app, current.HookID); err != nil {
+			return InternalError(err)
+		}
+	}
+
+	secret, err := randstr.Hex16()
+	if err != nil {
+		return InternalError(err, "failed to generate the webhook secret")
+	}
+
+	id, err := client.CreateHook(app, webhookURL(app), secret, request.Branch)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = application.AutoDeploySet(ctx, cluster, app, &application.AutoDeployHook{
+		Branch: request.Branch,
+		HookID: id,
+		Secret: secret,
+	})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	log.Info("enabled auto-deploy", "org", org, "app", appName, "branch", request.Branch)
+
+	err = jsonResponse(w, request)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// AutoDeployDelete turns off the auto-deploy mode of the app, removing the
+// webhook from its gitea repo
+func (hc ApplicationsController) AutoDeployDelete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	app, apierr := autoDeployApp(ctx, cluster, org, appName)
+	if apierr != nil {
+		return apierr
+	}
+
+	current, err := application.AutoDeploy(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if current == nil {
+		return NewBadRequest("auto-deploy is not enabled for the application")
+	}
+
+	client, err := gitea.New(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if err := client.DeleteHook(app, current.HookID); err != nil {
+		return InternalError(err)
+	}
+
+	if err := application.AutoDeploySet(ctx, cluster, app, nil); err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Webhook receives the push events of the gitea webhook of an app in
+// auto-deploy mode. The request is not authenticated, it has to be signed
+// with the secret of the webhook instead. All requests which are not signed
+// so, including those for unknown apps, and apps without auto-deploy, are
+// rejected alike. A push to the branch of the app stages the pushed
+// revision, and deploys it when staging succeeded.
+func (hc ApplicationsController) Webhook(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+	params := httprouter.ParamsFromContext(ctx)
+	app := models.NewAppRef(params.ByName("app"), params.ByName("org"))
+
+	defer r.Body.Close()
+	payload, err := ioutil.ReadAll(io.LimitReader(r.Body, webhookMaxSize))
+	if err != nil {
+		return InternalError(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	hook, err := application.AutoDeploy(ctx, cluster, app)
+	if err != nil && !apierrors.IsNotFound(err) {
+		return InternalError(err)
+	}
+	if hook == nil {
+		hook = &application.AutoDeployHook{}
+	}
+
+	if !application.VerifyWebhookSignature(hook.Secret, payload, r.Header.Get("X-Gitea-Signature")) {
+		return NewAPIError("webhook signature does not match", "", http.StatusUnauthorized)
+	}
+
+	if event := r.Header.Get("X-Gitea-Event"); event != "push" {
+		log.Info("ignoring webhook event", "org", app.Org, "app", app.Name, "event", event)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	var push giteaPushPayload
+	if err := json.Unmarshal(payload, &push); err != nil {
+		return BadRequest(err)
+	}
+
+	// Pushes to other branches, and the deletion of the branch, are
+	// ignored
+	if push.Ref != "refs/heads/"+hook.Branch || strings.Trim(push.After, "0") == "" {
+		log.Info("ignoring push", "org", app.Org, "app", app.Name, "ref", push.Ref)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	// Sources pushed by an upload are staged by the client
+	uploaded, err := application.UploadRevision(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if push.After == uploaded {
+		log.Info("ignoring push of uploaded sources", "org", app.Org, "app", app.Name, "revision", push.After)
+		w.WriteHeader(http.StatusNoContent)
+		return nil
+	}
+
+	log.Info("auto-deploying push", "org", app.Org, "app", app.Name, "revision", push.After)
+
+	gitRef := &models.GitRef{
+		URL:      fmt.Sprintf("%s/%s/%s", deployments.GiteaURL, app.Org, app.Name),
+		Revision: push.After,
+	}
+
+	// Stage with the builder detected at the last upload, as an app
+	// detecting its builder would be staged by a push of the same sources
+	builder, err := application.UploadedBuilder(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	staged, apierr := stage(ctx, cluster, models.StageRequest{App: app, Git: gitRef, Builder: builder})
+	if apierr != nil {
+		return apierr
+	}
+
+	// Deploy in the background, once staging is done. The request
+	// context ends with the response.
+	deployCtx := context.WithValue(context.Background(), tracelog.CtxLoggerKey{}, log)
+	deployCtx = context.WithValue(deployCtx, auth.CtxUserKey{}, &auth.User{Username: autoDeployUser})
+	go deployStaged(deployCtx, cluster, models.DeployRequest{
+		App:      app,
+		Git:      gitRef,
+		ImageURL: staged.ImageURL,
+		Stage:    staged.Stage,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	err = jsonResponse(w, staged)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// deployStaged waits for the staging of the deploy request to finish, and
+// deploys its image if it succeeded
+func deployStaged(ctx context.Context, cluster *kubernetes.Cluster, req models.DeployRequest) {
+	log := tracelog.Logger(ctx).WithValues("org", req.App.Org, "app", req.App.Name, "stage", req.Stage.ID)
+
+	var status *models.StageStatus
+	err := wait.PollImmediate(duration.PollInterval(), duration.ToAppBuilt(), func() (bool, error) {
+		var err error
+		status, err = application.StageStatus(ctx, cluster, req.App.Org, req.Stage.ID)
+		if err != nil || status == nil {
+			return false, err
+		}
+		switch status.Status {
+		case models.StageSucceeded, models.StageFailed, models.StageCancelled:
+			return true, nil
+		}
+		return false, nil
+	})
+	if err != nil {
+		log.Error(err, "auto-deploy failed to wait for staging")
+		return
+	}
+
+	if status.Status != models.StageSucceeded {
+		log.Info("auto-deploy skipped, staging did not succeed", "status", status.Status, "message", status.Message)
+		return
+	}
+
+	if _, apierr := deployApp(ctx, cluster, req); apierr != nil {
+		log.Error(apierr.Errors()[0], "auto-deploy failed")
+		return
+	}
+
+	log.Info("auto-deployed app")
+}
+
+// autoDeployApp returns the app after checking that it and its org exist
+func autoDeployApp(ctx context.Context, cluster *kubernetes.Cluster, org, appName string) (models.AppRef, APIErrors) {
+	app := models.NewAppRef(appName, org)
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return app, InternalError(err)
+	}
+	if !exists {
+		return app, OrgIsNotKnown(org)
+	}
+
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return app, InternalError(err)
+	}
+	if !exists {
+		return app, AppIsNotKnown(appName)
+	}
+
+	return app, nil
+}
+
+// webhookURL returns the URL of the Webhook endpoint of the app, as seen from
+// within the cluster
+func webhookURL(app models.AppRef) string {
+	return fmt.Sprintf("%s%s/webhooks/gitea/%s/%s", deployments.EpinioServerURL, v, app.Org, app.Name)
+}
@@ -0,0 +1,106 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Autoscale creates or changes the horizontal autoscaling of the app
+func (hc ApplicationsController) Autoscale(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var autoscale models.Autoscale
+	if err := json.Unmarshal(bodyBytes, &autoscale); err != nil {
+		return BadRequest(err)
+	}
+	if err := application.ValidateAutoscale(autoscale); err != nil {
+		return NewBadRequest(err.Error())
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	if err := application.Autoscale(ctx, cluster, app, autoscale); err != nil {
+		if err == application.ErrNoCPURequest {
+			return NewBadRequest("cannot autoscale, the application has no cpu request, set one with `epinio app update --cpu-request`")
+		}
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// AutoscaleDelete removes the horizontal autoscaling of the app
+func (hc ApplicationsController) AutoscaleDelete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	if err := application.AutoscaleDelete(ctx, cluster, app); err != nil {
+		if apierrors.IsNotFound(err) {
+			return NewBadRequest("application is not autoscaled")
+		}
+		return InternalError(err)
+	}
+
+	return nil
+}
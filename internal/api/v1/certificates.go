@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+)
+
+// CertificatesController manages the user provided TLS certificates of an
+// org. The routes of a domain with such a certificate use it, instead of a
+// certificate from cert-manager.
+type CertificatesController struct {
+}
+
+// Index lists the certificates of the org
+func (cc CertificatesController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	certs, err := auth.ListTLSCertificates(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	responseData := models.CertificateResponseList{}
+	for _, cert := range certs {
+		responseData = append(responseData, certificateResponse(cert))
+	}
+
+	err = jsonResponse(w, responseData)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Create stores a certificate for a domain of the org, replacing the one the
+// org had for it. The running apps of the org with routes in the domain are
+// switched over to the certificate.
+func (cc CertificatesController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var createRequest models.CertificateCreateRequest
+	err = json.Unmarshal(bodyBytes, &createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	domain, err := validateDomain(createRequest.Domain)
+	if err != nil {
+		return BadRequest(err)
+	}
+	if createRequest.Certificate == "" || createRequest.Key == "" {
+		return NewBadRequest("certificate and key are required")
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	// Validate upfront, to tell a bad certificate apart from trouble
+	// with storing it
+	_, err = auth.ValidateTLSCertificate(domain, createRequest.Certificate, createRequest.Key, createRequest.CA, time.Now())
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	cert, err := auth.StoreTLSCertificate(ctx, cluster, org, domain,
+		createRequest.Certificate, createRequest.Key, createRequest.CA)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if err := refreshDomainRoutes(ctx, cluster, org, domain); err != nil {
+		return InternalError(err, "certificate stored, but failed to update the application routes")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = jsonResponse(w, certificateResponse(*cert))
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Delete removes the certificate of the org for the domain. The running apps
+// of the org with routes in the domain go back to cert-manager certificates.
+func (cc CertificatesController) Delete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	domain, err := url.PathUnescape(params.ByName("domain"))
+	if err != nil {
+		return BadRequest(err)
+	}
+	domain = strings.ToLower(domain)
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	err = auth.DeleteTLSCertificate(ctx, cluster, org, domain)
+	if err != nil {
+		if err == auth.ErrTLSSecretNotKnown {
+			return CertificateIsNotKnown(domain)
+		}
+		return InternalError(err)
+	}
+
+	if err := refreshDomainRoutes(ctx, cluster, org, domain); err != nil {
+		return InternalError(err, "certificate deleted, but failed to update the application routes")
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// validateDomain checks the domain of a certificate. It is a route, see
+// application.ValidateRoute, possibly prefixed with a `*.` wildcard.
+func validateDomain(domain string) (string, error) {
+	wildcard := strings.HasPrefix(domain, "*.")
+	route, err := application.ValidateRoute(strings.TrimPrefix(domain, "*."))
+	if err != nil {
+		return "", err
+	}
+	if wildcard {
+		return "*." + route, nil
+	}
+	return route, nil
+}
+
+// refreshDomainRoutes applies the routes of the apps of the org which are in
+// the domain again, to pick up a change of the domain's certificate
+func refreshDomainRoutes(ctx context.Context, cluster *kubernetes.Cluster, org, domain string) error {
+	apps, err := application.ListAppRefs(ctx, cluster, org)
+	if err != nil {
+		return err
+	}
+
+	for _, app := range apps {
+		routes, err := application.Routes(ctx, cluster, app)
+		if err != nil {
+			return err
+		}
+
+		for _, route := range routes {
+			if auth.DomainCovers(domain, route) {
+				if err := applyRoutes(ctx, cluster, app, routes); err != nil {
+					return err
+				}
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+func certificateResponse(cert auth.TLSCertificate) models.CertificateResponse {
+	return models.CertificateResponse{
+		Domain:   cert.Domain,
+		Names:    cert.DNSNames,
+		Issuer:   cert.Issuer,
+		NotAfter: cert.NotAfter,
+	}
+}
@@ -8,15 +8,19 @@ import (
 	"net/http"
 
 	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
 	"github.com/epinio/epinio/internal/domain"
 	"github.com/julienschmidt/httprouter"
+	"github.com/spf13/viper"
 	appsv1 "k8s.io/api/apps/v1"
 	v1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/retry"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/tracelog"
@@ -30,19 +34,19 @@ const (
 type deployParam struct {
 	models.AppRef
 	Git         *models.GitRef
-	Route       string
+	Routes      []string
 	ImageURL    string
 	Instances   int32
 	Domain      string
 	Stage       models.StageRef
 	Owner       metav1.OwnerReference
 	Environment models.EnvVariableList
+	Settings    models.AppSettings
 }
 
 // Deploy will create the deployment, service and ingress for the app
 func (hc ApplicationsController) Deploy(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
-	log := tracelog.Logger(ctx)
 
 	p := httprouter.ParamsFromContext(ctx)
 	org := p.ByName("org")
@@ -75,13 +79,31 @@ func (hc ApplicationsController) Deploy(w http.ResponseWriter, r *http.Request)
 		return InternalError(err, "failed to get access to a kube client")
 	}
 
+	resp, apierr := deployApp(ctx, cluster, req)
+	if apierr != nil {
+		return apierr
+	}
+
+	err = jsonResponse(w, resp)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// deployApp deploys the image of the request, and records the release
+func deployApp(ctx context.Context, cluster *kubernetes.Cluster, req models.DeployRequest) (*models.DeployResponse, APIErrors) {
+	log := tracelog.Logger(ctx)
+	org := req.App.Org
+
 	// check application resource
 	applicationCR, err := application.Get(ctx, cluster, req.App)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return AppIsNotKnown("cannot deploy app, application resource is missing")
+			return nil, AppIsNotKnown("cannot deploy app, application resource is missing")
 		}
-		return InternalError(err, "failed to get the application resource")
+		return nil, InternalError(err, "failed to get the application resource")
 	}
 	owner := metav1.OwnerReference{
 		APIVersion: applicationCR.GetAPIVersion(),
@@ -91,99 +113,344 @@ func (hc ApplicationsController) Deploy(w http.ResponseWriter, r *http.Request)
 	}
 
 	// find out the number of instances
-	var instances int32
-	if req.Instances != nil {
-		instances = int32(*req.Instances)
-	} else {
-		instances, err = existingReplica(ctx, cluster.Kubectl, req.App)
-		if err != nil {
-			return InternalError(err)
-		}
+	instances, err := deployInstances(ctx, cluster, req.App, req.Instances)
+	if err != nil {
+		return nil, InternalError(err)
 	}
 
 	mainDomain, err := domain.MainDomain(ctx)
 	if err != nil {
-		return InternalError(err)
+		return nil, InternalError(err)
 	}
 
 	// determine runtime environment, if any
 	environment, err := application.Environment(ctx, cluster, req.App)
 	if err != nil {
-		return InternalError(err, "failed to access application runtime environment")
+		return nil, InternalError(err, "failed to access application runtime environment")
+	}
+
+	// determine runtime settings, updated by the request
+	settings, apiErr := updateSettings(ctx, cluster, req.App, req.Settings)
+	if apiErr != nil {
+		return nil, apiErr
+	}
+
+	// determine the routes, replaced by the request
+	routes, apiErr := updateRoutes(ctx, cluster, req.App, req.Routes, mainDomain)
+	if apiErr != nil {
+		return nil, apiErr
 	}
 
 	deployParams := deployParam{
 		AppRef:      req.App,
 		Git:         req.Git,
-		Route:       req.Route,
+		Routes:      routes,
 		Owner:       owner,
 		Environment: environment,
 		Instances:   instances,
 		Domain:      mainDomain,
 		ImageURL:    req.ImageURL,
+		Stage:       req.Stage,
+		Settings:    settings,
 	}
 
 	log.Info("deploying app", "org", org, "app", req.App)
-	deployment, err := newAppDeployment(req.Stage.ID, deployParams)
+	if err := deploy(ctx, cluster, deployParams); err != nil {
+		return nil, InternalError(err)
+	}
+
+	// Delete previous pipelineruns except for the current one
+	if req.Stage.ID != "" {
+		if err := application.Unstage(ctx, cluster, req.App, req.Stage.ID); err != nil {
+			return nil, InternalError(err)
+		}
+	}
+
+	// The app is deployed already, a missing release only shortens its
+	// history
+	if _, err := application.AddRelease(ctx, cluster, req.App, newRelease(ctx, deployParams)); err != nil {
+		log.Error(err, "failed to record the release", "org", org, "app", req.App.Name)
+	}
+
+	return &models.DeployResponse{Routes: routes}, nil
+}
+
+// deploy creates or updates the deployment, service and ingress of the app
+func deploy(ctx context.Context, cluster *kubernetes.Cluster, deployParams deployParam) error {
+	app := deployParams.AppRef
+	owner := deployParams.Owner
+
+	deployment, err := newAppDeployment(deployParams.Stage.ID, deployParams)
 	if err != nil {
-		return InternalError(err)
+		return err
 	}
 	deployment.SetOwnerReferences([]metav1.OwnerReference{owner})
-	if _, err := cluster.Kubectl.AppsV1().Deployments(req.App.Org).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
+	if _, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Create(ctx, deployment, metav1.CreateOptions{}); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			if _, err := cluster.Kubectl.AppsV1().Deployments(req.App.Org).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
-				return InternalError(err)
+			if _, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Update(ctx, deployment, metav1.UpdateOptions{}); err != nil {
+				return err
 			}
 		} else {
-			return InternalError(err)
+			return err
 		}
 	}
 
-	svc, err := newAppService(req.App)
+	svc, err := newAppService(app, deployParams.Settings.ContainerPort())
 	if err != nil {
-		return InternalError(err)
+		return err
 	}
 	svc.SetOwnerReferences([]metav1.OwnerReference{owner})
-	if _, err := cluster.Kubectl.CoreV1().Services(req.App.Org).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
+	if _, err := cluster.Kubectl.CoreV1().Services(app.Org).Create(ctx, svc, metav1.CreateOptions{}); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			service, err := cluster.Kubectl.CoreV1().Services(req.App.Org).Get(ctx, svc.Name, metav1.GetOptions{})
+			service, err := cluster.Kubectl.CoreV1().Services(app.Org).Get(ctx, svc.Name, metav1.GetOptions{})
 			if err != nil {
-				return InternalError(err)
+				return err
 			}
 
 			svc.ResourceVersion = service.ResourceVersion
 			svc.Spec.ClusterIP = service.Spec.ClusterIP
-			if _, err := cluster.Kubectl.CoreV1().Services(req.App.Org).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
-				return InternalError(err)
+			if _, err := cluster.Kubectl.CoreV1().Services(app.Org).Update(ctx, svc, metav1.UpdateOptions{}); err != nil {
+				return err
 			}
 		} else {
-			return InternalError(err)
+			return err
 		}
 	}
 
-	ing, err := newAppIngress(req.App, req.Route)
+	return deployIngress(ctx, cluster, deployParams)
+}
+
+// deployIngress creates or updates the ingress of the app, with a rule and a
+// certificate for each of its routes. A route covered by a certificate of the
+// user, see auth.TLSCertificateFor, uses that instead of one from cert-manager.
+func deployIngress(ctx context.Context, cluster *kubernetes.Cluster, deployParams deployParam) error {
+	app := deployParams.AppRef
+	owner := deployParams.Owner
+
+	userCerts, err := auth.ListTLSCertificates(ctx, cluster, app.Org)
 	if err != nil {
-		return InternalError(err)
+		return err
+	}
+
+	// Routes covered by a certificate of the user use it, all others get
+	// a certificate from cert-manager.
+	secrets := map[string]string{}
+	for _, route := range deployParams.Routes {
+		if userCert := auth.TLSCertificateFor(userCerts, route); userCert != nil {
+			secrets[route] = userCert.SecretName
+			if err := auth.DeleteCertificate(ctx, cluster, app.Org, route); err != nil {
+				return err
+			}
+			continue
+		}
+
+		cert := auth.CertParam{
+			Name:      route,
+			Namespace: app.Org,
+			Issuer:    viper.GetString("tls-issuer"),
+			DNSName:   route,
+		}
+		if err := auth.CreateCertificate(ctx, cluster, cert, &owner); err != nil {
+			return err
+		}
+		secrets[route] = fmt.Sprintf("%s-tls", route)
+	}
+
+	ing, err := newAppIngress(app, deployParams.Routes, secrets, deployParams.Settings.ContainerPort())
+	if err != nil {
+		return err
 	}
 	ing.SetOwnerReferences([]metav1.OwnerReference{owner})
-	if _, err := cluster.Kubectl.NetworkingV1().Ingresses(req.App.Org).Create(ctx, ing, metav1.CreateOptions{}); err != nil {
+	if _, err := cluster.Kubectl.NetworkingV1().Ingresses(app.Org).Create(ctx, ing, metav1.CreateOptions{}); err != nil {
 		if apierrors.IsAlreadyExists(err) {
-			if _, err := cluster.Kubectl.NetworkingV1().Ingresses(req.App.Org).Update(ctx, ing, metav1.UpdateOptions{}); err != nil {
-				return InternalError(err)
+			if _, err := cluster.Kubectl.NetworkingV1().Ingresses(app.Org).Update(ctx, ing, metav1.UpdateOptions{}); err != nil {
+				return err
 			}
 		} else {
-			return InternalError(err)
+			return err
 		}
 	}
 
-	// Delete previous pipelineruns except for the current one
-	if req.Stage.ID != "" {
-		if err := application.Unstage(ctx, cluster, req.App, req.Stage.ID); err != nil {
-			return InternalError(err)
+	return nil
+}
+
+// updateRoutes replaces the routes of the app with the given ones, if any,
+// and returns the result. An app without routes gets the default route.
+func updateRoutes(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, update []string, mainDomain string) ([]string, APIErrors) {
+	current, err := application.Routes(ctx, cluster, app)
+	if err != nil {
+		return nil, InternalError(err, "failed to get the application routes")
+	}
+
+	if len(update) == 0 {
+		if len(current) > 0 {
+			return current, nil
 		}
+		update = []string{application.DefaultRoute(app, mainDomain)}
 	}
 
-	return nil
+	routes := []string{}
+	for _, route := range update {
+		route, apiErr := checkRoute(ctx, cluster, app, route, mainDomain)
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		if !contains(routes, route) {
+			routes = append(routes, route)
+		}
+	}
+
+	if err := application.RoutesSet(ctx, cluster, app, routes); err != nil {
+		return nil, InternalError(err, "failed to save the application routes")
+	}
+
+	for _, route := range current {
+		if !contains(routes, route) {
+			if err := auth.DeleteCertificate(ctx, cluster, app.Org, route); err != nil {
+				return nil, InternalError(err)
+			}
+		}
+	}
+
+	return routes, nil
+}
+
+// checkRoute validates the route, and ensures that no other app, nor the
+// system, claims it. It returns the normalized route.
+func checkRoute(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, route, mainDomain string) (string, APIErrors) {
+	route, err := application.ValidateRoute(route)
+	if err != nil {
+		return "", NewBadRequest(err.Error())
+	}
+
+	owner, err := application.RouteOwner(ctx, cluster, route, mainDomain)
+	if err == application.ErrRouteReserved {
+		return "", NewAPIError(fmt.Sprintf("route '%s' is reserved", route), "", http.StatusConflict)
+	}
+	if err != nil {
+		return "", InternalError(err)
+	}
+	if owner != nil && *owner != app {
+		return "", NewAPIError(
+			fmt.Sprintf("route '%s' is in use by application '%s' of organization '%s'", route, owner.Name, owner.Org),
+			"", http.StatusConflict)
+	}
+
+	return route, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// updateSettings merges the update, if any, into the runtime settings of the
+// app, and returns the result
+func updateSettings(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, update *models.AppSettings) (models.AppSettings, APIErrors) {
+	current, err := application.Settings(ctx, cluster, app)
+	if err != nil {
+		return current, InternalError(err, "failed to get the application settings")
+	}
+	if update == nil {
+		return current, nil
+	}
+
+	merged := application.MergeSettings(current, *update)
+	if err := application.ValidateSettings(merged); err != nil {
+		return current, NewBadRequest(err.Error())
+	}
+
+	if merged.Resources.CPURequest == "" && current.Resources.CPURequest != "" {
+		autoscale, err := application.Autoscaler(ctx, cluster, app)
+		if err != nil {
+			return current, InternalError(err)
+		}
+		if autoscale != nil {
+			return current, NewBadRequest("cannot remove the cpu request, the application is autoscaled")
+		}
+	}
+
+	settings, err := application.SettingsUpdate(ctx, cluster, app, *update)
+	if err != nil {
+		return current, InternalError(err, "failed to save the application settings")
+	}
+
+	return settings, nil
+}
+
+// applyWorkloadSettings changes the deployment, service and ingress of a
+// running app to the settings
+func applyWorkloadSettings(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, settings models.AppSettings) error {
+	port := settings.ContainerPort()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		deployment, err := cluster.Kubectl.AppsV1().Deployments(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		if err := applySettings(&deployment.Spec.Template.Spec.Containers[0], settings); err != nil {
+			return err
+		}
+		_, err = cluster.Kubectl.AppsV1().Deployments(app.Org).Update(ctx, deployment, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		service, err := cluster.Kubectl.CoreV1().Services(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for i := range service.Spec.Ports {
+			service.Spec.Ports[i].Port = port
+			service.Spec.Ports[i].TargetPort = intstr.FromInt(int(port))
+		}
+		_, err = cluster.Kubectl.CoreV1().Services(app.Org).Update(ctx, service, metav1.UpdateOptions{})
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		ingress, err := cluster.Kubectl.NetworkingV1().Ingresses(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+		for _, rule := range ingress.Spec.Rules {
+			if rule.HTTP == nil {
+				continue
+			}
+			for i := range rule.HTTP.Paths {
+				if backend := rule.HTTP.Paths[i].Backend.Service; backend != nil {
+					backend.Port.Number = port
+				}
+			}
+		}
+		_, err = cluster.Kubectl.NetworkingV1().Ingresses(app.Org).Update(ctx, ingress, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// newRelease returns the release record of the deployment, made by the current user
+func newRelease(ctx context.Context, deployParams deployParam) models.Release {
+	release := models.Release{
+		StageID:     deployParams.Stage.ID,
+		Git:         deployParams.Git,
+		ImageURL:    deployParams.ImageURL,
+		Routes:      deployParams.Routes,
+		Environment: deployParams.Environment,
+	}
+	if user := auth.CurrentUser(ctx); user != nil {
+		release.User = user.Username
+	}
+	return release
 }
 
 // newAppDeployment will create a deployment for the app
@@ -230,12 +497,7 @@ func newAppDeployment(stageID string, deployParams deployParam) (*appsv1.Deploym
 						{
 							Name:  deployParams.Name,
 							Image: deployParams.ImageURL,
-							Ports: []v1.ContainerPort{
-								{
-									ContainerPort: 8080,
-								},
-							},
-							Env: deployParams.Environment.ToEnvVarArray(deployParams.AppRef),
+							Env:   deployParams.Environment.ToEnvVarArray(deployParams.AppRef),
 						},
 					},
 				},
@@ -243,11 +505,81 @@ func newAppDeployment(stageID string, deployParams deployParam) (*appsv1.Deploym
 		},
 	}
 
+	if err := applySettings(&deploymentData.Spec.Template.Spec.Containers[0], deployParams.Settings); err != nil {
+		return nil, err
+	}
+
 	return deploymentData, nil
 }
 
+// applySettings configures the port, the probes and the resources of the
+// app's container
+func applySettings(container *v1.Container, settings models.AppSettings) error {
+	port := settings.ContainerPort()
+	container.Ports = []v1.ContainerPort{
+		{
+			ContainerPort: port,
+		},
+	}
+	container.LivenessProbe = newProbe(settings.Liveness, port)
+	container.ReadinessProbe = newProbe(settings.Readiness, port)
+
+	resources := v1.ResourceRequirements{}
+	quantities := []struct {
+		list  *v1.ResourceList
+		name  v1.ResourceName
+		value string
+	}{
+		{&resources.Requests, v1.ResourceMemory, settings.Resources.MemoryRequest},
+		{&resources.Limits, v1.ResourceMemory, settings.Resources.MemoryLimit},
+		{&resources.Requests, v1.ResourceCPU, settings.Resources.CPURequest},
+		{&resources.Limits, v1.ResourceCPU, settings.Resources.CPULimit},
+	}
+	for _, q := range quantities {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return err
+		}
+		if *q.list == nil {
+			*q.list = v1.ResourceList{}
+		}
+		(*q.list)[q.name] = quantity
+	}
+	container.Resources = resources
+
+	return nil
+}
+
+func newProbe(probe *models.Probe, port int32) *v1.Probe {
+	if probe == nil {
+		return nil
+	}
+
+	result := &v1.Probe{
+		InitialDelaySeconds: probe.InitialDelaySeconds,
+		PeriodSeconds:       probe.PeriodSeconds,
+		TimeoutSeconds:      probe.TimeoutSeconds,
+		FailureThreshold:    probe.FailureThreshold,
+	}
+	if probe.Type == "http" {
+		result.HTTPGet = &v1.HTTPGetAction{
+			Path: probe.Path,
+			Port: intstr.FromInt(int(port)),
+		}
+	} else {
+		result.TCPSocket = &v1.TCPSocketAction{
+			Port: intstr.FromInt(int(port)),
+		}
+	}
+
+	return result
+}
+
 // newAppService will create a service for the app
-func newAppService(app models.AppRef) (*v1.Service, error) {
+func newAppService(app models.AppRef, port int32) (*v1.Service, error) {
 	serviceData := v1.Service{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      app.Name,
@@ -267,9 +599,9 @@ func newAppService(app models.AppRef) (*v1.Service, error) {
 		Spec: v1.ServiceSpec{
 			Ports: []v1.ServicePort{
 				{
-					Port:       8080,
+					Port:       port,
 					Protocol:   v1.ProtocolTCP,
-					TargetPort: intstr.IntOrString{IntVal: 8080},
+					TargetPort: intstr.FromInt(int(port)),
 				},
 			},
 			Selector: map[string]string{
@@ -283,7 +615,7 @@ func newAppService(app models.AppRef) (*v1.Service, error) {
 	return &serviceData, nil
 }
 
-func newAppIngress(appRef models.AppRef, route string) (*networkingv1.Ingress, error) {
+func newAppIngress(appRef models.AppRef, routes []string, secrets map[string]string, port int32) (*networkingv1.Ingress, error) {
 	pathTypeImplementationSpecific := networkingv1.PathTypeImplementationSpecific
 
 	ingressData := networkingv1.Ingress{
@@ -301,44 +633,72 @@ func newAppIngress(appRef models.AppRef, route string) (*networkingv1.Ingress, e
 				"app.kubernetes.io/part-of":    appRef.Org,
 			},
 		},
-		Spec: networkingv1.IngressSpec{
-			Rules: []networkingv1.IngressRule{
-				{
-					Host: route,
-					IngressRuleValue: networkingv1.IngressRuleValue{
-						HTTP: &networkingv1.HTTPIngressRuleValue{
-							Paths: []networkingv1.HTTPIngressPath{
-								{
-									Backend: networkingv1.IngressBackend{
-										Service: &networkingv1.IngressServiceBackend{
-											Name: appRef.Name,
-											Port: networkingv1.ServiceBackendPort{
-												Number: 8080,
-											},
-										},
+	}
+
+	for _, route := range routes {
+		ingressData.Spec.Rules = append(ingressData.Spec.Rules, networkingv1.IngressRule{
+			Host: route,
+			IngressRuleValue: networkingv1.IngressRuleValue{
+				HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{
+						{
+							Backend: networkingv1.IngressBackend{
+								Service: &networkingv1.IngressServiceBackend{
+									Name: appRef.Name,
+									Port: networkingv1.ServiceBackendPort{
+										Number: port,
 									},
-									Path:     "/",
-									PathType: &pathTypeImplementationSpecific,
 								},
 							},
+							Path:     "/",
+							PathType: &pathTypeImplementationSpecific,
 						},
 					},
 				},
 			},
-			TLS: []networkingv1.IngressTLS{
-				{
-					Hosts: []string{
-						route,
-					},
-					SecretName: fmt.Sprintf("%s-tls", route),
-				},
+		})
+		ingressData.Spec.TLS = append(ingressData.Spec.TLS, networkingv1.IngressTLS{
+			Hosts: []string{
+				route,
 			},
-		},
+			SecretName: secrets[route],
+		})
 	}
 
 	return &ingressData, nil
 }
 
+// deployInstances returns the number of instances to deploy the app with.
+// It is the requested number, or else the number of instances already
+// running. The instances of an autoscaled app are left to its autoscaler,
+// ignoring the requested number.
+func deployInstances(ctx context.Context, cluster *kubernetes.Cluster, app models.AppRef, requested *int32) (int32, error) {
+	autoscale, err := application.Autoscaler(ctx, cluster, app)
+	if err != nil {
+		return 0, err
+	}
+
+	if autoscale == nil && requested != nil {
+		return *requested, nil
+	}
+
+	instances, err := existingReplica(ctx, cluster.Kubectl, app)
+	if err != nil {
+		return 0, err
+	}
+
+	if autoscale != nil {
+		if instances < autoscale.Min {
+			instances = autoscale.Min
+		}
+		if instances > autoscale.Max {
+			instances = autoscale.Max
+		}
+	}
+
+	return instances, nil
+}
+
 func existingReplica(ctx context.Context, client *k8s.Clientset, app models.AppRef) (int32, error) {
 	// if a deployment exists, use that deployment's replica count
 	result, err := client.AppsV1().Deployments(app.Org).Get(ctx, app.Name, metav1.GetOptions{})
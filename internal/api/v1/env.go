@@ -139,8 +139,8 @@ func (hc ApplicationsController) EnvMatch(w http.ResponseWriter, r *http.Request
 	return nil
 }
 
-// EnvSet receives the org, application name, var name and value,
-// and add/modifies the variable in the  application's environment.
+// EnvSet receives the org, application name, var name, value and
+// scope, and add/modifies the variable in the  application's environment.
 func (hc ApplicationsController) EnvSet(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 	log := tracelog.Logger(ctx)
@@ -189,6 +189,12 @@ func (hc ApplicationsController) EnvSet(w http.ResponseWriter, r *http.Request)
 		return BadRequest(err)
 	}
 
+	for _, ev := range setRequest {
+		if err := application.ValidateEnvScope(ev.Scope); err != nil {
+			return BadRequest(err)
+		}
+	}
+
 	err = application.EnvironmentSet(ctx, cluster, app, setRequest)
 	if err != nil {
 		return InternalError(err)
@@ -164,3 +164,94 @@ func ServiceIsNotBound(service string) APIError {
 		"",
 		http.StatusBadRequest)
 }
+
+func UploadTooLarge(maxSize int64) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Upload exceeds the maximum size of %d bytes", maxSize),
+		"",
+		http.StatusRequestEntityTooLarge)
+}
+
+func UploadIsBusy(digest string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Upload '%s' is busy with another request, retry", digest),
+		"",
+		http.StatusConflict)
+}
+
+func ArchiveIsNotSupported() APIError {
+	return NewAPIError(
+		"Unsupported archive format, expected a tar, tar.gz, zip, jar or war archive",
+		"",
+		http.StatusUnsupportedMediaType)
+}
+
+func UserNotAuthenticated() APIError {
+	return NewAPIError(
+		"Authentication required, bad or missing credentials",
+		"",
+		http.StatusUnauthorized)
+}
+
+func UserNotAuthorized(user string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("User '%s' is not authorized for this request", user),
+		"",
+		http.StatusForbidden)
+}
+
+func UserIsNotKnown(user string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("User '%s' does not exist", user),
+		"",
+		http.StatusNotFound)
+}
+
+func UserAlreadyKnown(user string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("User '%s' already exists", user),
+		"",
+		http.StatusConflict)
+}
+
+func TokenIsNotKnown(token string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Token '%s' does not exist", token),
+		"",
+		http.StatusNotFound)
+}
+
+func TokenAlreadyKnown(token string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Token '%s' already exists", token),
+		"",
+		http.StatusConflict)
+}
+
+func CertificateIsNotKnown(domain string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Certificate for '%s' does not exist", domain),
+		"",
+		http.StatusNotFound)
+}
+
+func GitCredentialsAreNotKnown(name string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Git credentials '%s' do not exist", name),
+		"",
+		http.StatusNotFound)
+}
+
+func GitCredentialsAlreadyKnown(name string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Git credentials '%s' already exist", name),
+		"",
+		http.StatusConflict)
+}
+
+func StageIsNotKnown(stageID string) APIError {
+	return NewAPIError(
+		fmt.Sprintf("Staging run '%s' does not exist", stageID),
+		"",
+		http.StatusNotFound)
+}
@@ -0,0 +1,54 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+)
+
+// Events lists the kubernetes events about the app and its staging, oldest
+// first, see application.Events
+func (hc ApplicationsController) Events(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	events, err := application.Events(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, events)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
@@ -0,0 +1,241 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/remotecommand"
+	kexec "k8s.io/client-go/util/exec"
+)
+
+// DefaultExecCommand is run by an exec request without command
+const DefaultExecCommand = "/bin/sh"
+
+// Exec runs a command in a running instance of the app, streaming its input
+// and output over a websocket, see models.ExecStdin. The query parameters are
+// `instance`, the index of the instance, `command`, repeated for each
+// argument of the command, and `tty`, to run the command in a terminal.
+func (hc ApplicationsController) Exec(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	query := r.URL.Query()
+	command := query["command"]
+	if len(command) == 0 {
+		command = []string{DefaultExecCommand}
+	}
+	tty := query.Get("tty") == "true"
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	pod, apiErr := instancePod(ctx, cluster, org, appName, query.Get("instance"))
+	if apiErr != nil {
+		jsonErrorResponse(w, apiErr)
+		return
+	}
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader responded already
+		log.V(1).Error(err, "failed to upgrade to websockets")
+		return
+	}
+	defer conn.Close()
+
+	session := newExecSession(conn)
+
+	log.Info("exec into app", "org", org, "app", appName, "pod", pod.Name, "command", command)
+	err = cluster.ExecStream(org, pod.Name, pod.Spec.Containers[0].Name, command, tty,
+		session.stdin, session.writer(models.ExecStdout), session.writer(models.ExecStderr), session)
+
+	result := models.ExecResult{}
+	if err != nil {
+		if exitErr, ok := err.(kexec.ExitError); ok {
+			result.ExitCode = exitErr.ExitStatus()
+		} else {
+			result.ExitCode = 1
+			result.Error = err.Error()
+		}
+	}
+
+	if err := session.finish(result); err != nil {
+		log.V(1).Error(err, "failed to finish the exec stream")
+	}
+}
+
+// instancePod returns the pod of the running instance of the app with the
+// given index, the first by default
+func instancePod(ctx context.Context, cluster *kubernetes.Cluster, org, appName, index string) (*corev1.Pod, APIErrors) {
+	instance := 0
+	if index != "" {
+		var err error
+		instance, err = strconv.Atoi(index)
+		if err != nil || instance < 0 {
+			return nil, NewBadRequest("instance should be a number, starting at zero")
+		}
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+	if !exists {
+		return nil, OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+	if !exists {
+		return nil, AppIsNotKnown(appName)
+	}
+
+	pods, err := application.NewWorkload(cluster, app).RunningInstances(ctx)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+	if len(pods) == 0 {
+		return nil, NewBadRequest("application has no running instance")
+	}
+	if instance >= len(pods) {
+		return nil, NewAPIError(
+			fmt.Sprintf("instance %d not found, the application has %d running instances", instance, len(pods)),
+			"", http.StatusNotFound)
+	}
+
+	return &pods[instance], nil
+}
+
+// execSession connects the streams of an exec to the websocket of the
+// client. It is the terminal size queue of the exec as well.
+type execSession struct {
+	conn      *websocket.Conn
+	writeLock sync.Mutex
+	stdin     *io.PipeReader
+	sizes     chan remotecommand.TerminalSize
+}
+
+func newExecSession(conn *websocket.Conn) *execSession {
+	stdin, input := io.Pipe()
+	session := &execSession{
+		conn:  conn,
+		stdin: stdin,
+		sizes: make(chan remotecommand.TerminalSize, 1),
+	}
+	go session.receive(input)
+	return session
+}
+
+// receive forwards the messages of the client to the input and the terminal
+// size queue of the exec, until the connection is closed
+func (s *execSession) receive(input *io.PipeWriter) {
+	defer close(s.sizes)
+	defer input.Close()
+
+	for {
+		kind, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if kind != websocket.BinaryMessage || len(data) == 0 {
+			continue
+		}
+
+		switch data[0] {
+		case models.ExecStdin:
+			if len(data) == 1 {
+				input.Close()
+				continue
+			}
+			if _, err := input.Write(data[1:]); err != nil {
+				return
+			}
+		case models.ExecResize:
+			var size models.TerminalSize
+			if err := json.Unmarshal(data[1:], &size); err != nil {
+				continue
+			}
+			// Drop the size when the exec did not take the previous one
+			// yet, i.e. for commands without terminal
+			select {
+			case s.sizes <- remotecommand.TerminalSize{Width: size.Width, Height: size.Height}:
+			default:
+			}
+		}
+	}
+}
+
+// Next implements remotecommand.TerminalSizeQueue
+func (s *execSession) Next() *remotecommand.TerminalSize {
+	size, ok := <-s.sizes
+	if !ok {
+		return nil
+	}
+	return &size
+}
+
+// writer returns a writer sending to the client on the channel
+func (s *execSession) writer(channel byte) io.Writer {
+	return execWriter{session: s, channel: channel}
+}
+
+// finish sends the result of the exec to the client, and closes the stream
+func (s *execSession) finish(result models.ExecResult) error {
+	// Unblock the forwarding of input the command did not read
+	s.stdin.Close()
+
+	js, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	s.writeLock.Lock()
+	defer s.writeLock.Unlock()
+
+	if err := s.conn.WriteMessage(websocket.TextMessage, js); err != nil {
+		return err
+	}
+	return s.conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+}
+
+type execWriter struct {
+	session *execSession
+	channel byte
+}
+
+func (w execWriter) Write(data []byte) (int, error) {
+	w.session.writeLock.Lock()
+	defer w.session.writeLock.Unlock()
+
+	message := append([]byte{w.channel}, data...)
+	if err := w.session.conn.WriteMessage(websocket.BinaryMessage, message); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
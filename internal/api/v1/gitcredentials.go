@@ -0,0 +1,153 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+)
+
+// GitCredentialsController manages the credentials of an org for external
+// git servers. Apps of the org staged from a repository on such a server
+// are cloned with them.
+type GitCredentialsController struct {
+}
+
+// Index lists the git credentials of the org, without their passwords
+func (gc GitCredentialsController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	credentials, err := organizations.GitCredentials(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, credentials)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Create stores new git credentials for the org
+func (gc GitCredentialsController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var createRequest models.GitCredentialsCreateRequest
+	err = json.Unmarshal(bodyBytes, &createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if err := organizations.ValidateGitCredentials(createRequest); err != nil {
+		return BadRequest(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	exists, err = organizations.GitCredentialsExist(ctx, cluster, org, createRequest.Name)
+	if err != nil {
+		return InternalError(err)
+	}
+	if exists {
+		return GitCredentialsAlreadyKnown(createRequest.Name)
+	}
+
+	err = organizations.GitCredentialsCreate(ctx, cluster, org, createRequest)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = jsonResponse(w, models.GitCredentials{
+		Name:     createRequest.Name,
+		URL:      createRequest.URL,
+		Username: createRequest.Username,
+	})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Delete removes the named git credentials of the org
+func (gc GitCredentialsController) Delete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	name := params.ByName("credentials")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	exists, err = organizations.GitCredentialsExist(ctx, cluster, org, name)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return GitCredentialsAreNotKnown(name)
+	}
+
+	err = organizations.GitCredentialsDelete(ctx, cluster, org, name)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
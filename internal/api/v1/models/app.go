@@ -1,5 +1,7 @@
 package models
 
+import "time"
+
 const (
 	EpinioStageIDLabel = "epinio.suse.org/stage-id"
 )
@@ -7,13 +9,20 @@ const (
 // App has all the app properties, like the routes and stage ID.
 // It is used in the CLI and  API responses.
 type App struct {
-	Active        bool     `json:"active,omitempty"`
-	StageID       string   `json:"stage_id,omitempty"`
-	Name          string   `json:"name,omitempty"`
-	Organization  string   `json:"organization,omitempty"`
-	Status        string   `json:"status,omitempty"`
-	Route         string   `json:"routes,omitempty"`
-	BoundServices []string `json:"bound_services,omitempty"`
+	Active        bool         `json:"active,omitempty"`
+	StageID       string       `json:"stage_id,omitempty"`
+	Name          string       `json:"name,omitempty"`
+	Organization  string       `json:"organization,omitempty"`
+	Status        string       `json:"status,omitempty"`
+	Routes        []string     `json:"routes,omitempty"`
+	BoundServices []string     `json:"bound_services,omitempty"`
+	Settings      *AppSettings `json:"settings,omitempty"`
+	Autoscale     *Autoscale   `json:"autoscale,omitempty"`
+	AutoDeploy    *AutoDeploy  `json:"auto_deploy,omitempty"`
+	Instances     []Instance   `json:"instances,omitempty"`
+	StagingPolicy string       `json:"staging_policy,omitempty"`
+	Builder       string       `json:"builder,omitempty"`
+	Buildpacks    *Buildpacks  `json:"buildpacks,omitempty"`
 }
 
 // NewApp returns a new app for name and org
@@ -26,6 +35,40 @@ func (a *App) AppRef() AppRef {
 	return NewAppRef(a.Name, a.Organization)
 }
 
+// Instance is the status of a single instance, i.e. pod, of an app.
+// LastTermination is the reason the app container terminated the last time,
+// like OOMKilled or Error. CrashLoop flags an instance which keeps failing,
+// and is held back by kubernetes before the next restart. Index is the number
+// of a running instance, as taken by exec and port-forward.
+type Instance struct {
+	Index           *int      `json:"index,omitempty"`
+	Name            string    `json:"name"`
+	Phase           string    `json:"phase"`
+	Ready           bool      `json:"ready"`
+	Restarts        int32     `json:"restarts"`
+	LastTermination string    `json:"last_termination,omitempty"`
+	CrashLoop       bool      `json:"crash_loop,omitempty"`
+	Node            string    `json:"node,omitempty"`
+	Created         time.Time `json:"created"`
+	StageID         string    `json:"stage_id,omitempty"`
+}
+
+// Event is a kubernetes event about one of the resources of an app, or of
+// its staging. Object names the resource, as Kind/Name. Count is the number of
+// times the event occurred, the last time at Time.
+type Event struct {
+	UID     string    `json:"uid"`
+	Time    time.Time `json:"time"`
+	Type    string    `json:"type"`
+	Reason  string    `json:"reason"`
+	Object  string    `json:"object"`
+	Message string    `json:"message"`
+	Count   int32     `json:"count"`
+}
+
+// EventList is a list of app events, oldest first
+type EventList []Event
+
 type AppList []App
 
 // Implement the Sort interface for application slices
@@ -73,6 +116,102 @@ func NewStage(id string) StageRef {
 	return StageRef{id}
 }
 
+// The states of a staging run. A queued run waits for the staging runs of
+// the app before it to finish.
+const (
+	StageQueued    = "queued"
+	StagePending   = "pending"
+	StageRunning   = "running"
+	StageSucceeded = "succeeded"
+	StageFailed    = "failed"
+	StageCancelled = "cancelled"
+)
+
+// The staging policies of an app, deciding about a staging request while the
+// app is staged already. "queue" runs the request after the staging runs
+// before it, "supersede" cancels them, and "reject" refuses the request.
+const (
+	StagingQueue     = "queue"
+	StagingSupersede = "supersede"
+	StagingReject    = "reject"
+)
+
+// The builders staging an app. "buildpacks" builds the sources with the cloud
+// native buildpacks, "dockerfile" builds the image from the Dockerfile of the
+// sources. "auto" chooses "dockerfile" for uploaded sources with a Dockerfile,
+// and "buildpacks" else.
+const (
+	BuilderAuto       = "auto"
+	BuilderBuildpacks = "buildpacks"
+	BuilderDockerfile = "dockerfile"
+)
+
+// BuildpacksDefault resets a field of the buildpacks settings in an update
+const BuildpacksDefault = "default"
+
+// Buildpacks pins the builder image, and the ordered list of buildpacks to
+// detect the app with, for the staging by buildpacks. Buildpacks are given by
+// ID, or as ID@VERSION. Empty fields are taken from the defaults of the org,
+// and then from the builder. For an app the API reports the settings in
+// effect.
+type Buildpacks struct {
+	BuilderImage string   `json:"builder_image,omitempty"`
+	Buildpacks   []string `json:"buildpacks,omitempty"`
+}
+
+// Merge returns the buildpacks settings, overridden by the non-empty fields of
+// the update. A field set to BuildpacksDefault is reset.
+func (b Buildpacks) Merge(update Buildpacks) Buildpacks {
+	result := b
+
+	switch update.BuilderImage {
+	case "":
+	case BuildpacksDefault:
+		result.BuilderImage = ""
+	default:
+		result.BuilderImage = update.BuilderImage
+	}
+
+	switch {
+	case len(update.Buildpacks) == 0:
+	case len(update.Buildpacks) == 1 && update.Buildpacks[0] == BuildpacksDefault:
+		result.Buildpacks = nil
+	default:
+		result.Buildpacks = update.Buildpacks
+	}
+
+	return result
+}
+
+// Over returns the buildpacks settings, with empty fields taken from the
+// defaults
+func (b Buildpacks) Over(defaults Buildpacks) Buildpacks {
+	result := b
+	if result.BuilderImage == "" {
+		result.BuilderImage = defaults.BuilderImage
+	}
+	if len(result.Buildpacks) == 0 {
+		result.Buildpacks = defaults.Buildpacks
+	}
+	return result
+}
+
+// StageStatus is the state of a staging run, see StagePending and friends.
+// For a failed run Task and Step name the failed step of the pipeline, with
+// its exit code and the last lines of its log, as far as known. Message is the
+// reason tekton gave for the state. Position is the place of a queued run in
+// the queue of the app, starting at one.
+type StageStatus struct {
+	ID       string   `json:"id"`
+	Status   string   `json:"status"`
+	Position int      `json:"position,omitempty"`
+	Message  string   `json:"message,omitempty"`
+	Task     string   `json:"task,omitempty"`
+	Step     string   `json:"step,omitempty"`
+	ExitCode int32    `json:"exit_code,omitempty"`
+	Logs     []string `json:"logs,omitempty"`
+}
+
 // ImageRef references an upload
 type ImageRef struct {
 	ID string `json:"id,omitempty"`
@@ -83,8 +222,110 @@ func NewImage(id string) ImageRef {
 	return ImageRef{id}
 }
 
-// GitRef describes a git commit in a repo
+// GitRef describes a git commit in a repo. Without a revision, staging uses
+// the head of the branch. Credentials names the git credentials of the org
+// to clone the repo with, see GitCredentials.
 type GitRef struct {
-	Revision string `json:"revision"`
-	URL      string `json:"url"`
+	Revision    string `json:"revision"`
+	URL         string `json:"url"`
+	Branch      string `json:"branch,omitempty"`
+	Credentials string `json:"credentials,omitempty"`
+}
+
+// Release records a deployment of an app: what was deployed, when, and by
+// whom. The releases of an app are kept on its application resource. Their
+// environments are kept in secrets of their own, which the releases refer
+// to. Releases recorded before carry their environment inline.
+type Release struct {
+	ID                int             `json:"id"`
+	StageID           string          `json:"stage_id,omitempty"`
+	Git               *GitRef         `json:"git,omitempty"`
+	ImageURL          string          `json:"image"`
+	Routes            []string        `json:"routes,omitempty"`
+	Environment       EnvVariableList `json:"environment,omitempty"`
+	EnvironmentSecret string          `json:"environment_secret,omitempty"`
+	Created           time.Time       `json:"created"`
+	User              string          `json:"user,omitempty"`
+	RollbackOf        int             `json:"rollback_of,omitempty"` // the release this one restored, if any
+}
+
+// ReleaseList is a list of releases, oldest first
+type ReleaseList []Release
+
+// Find returns the release with the given ID, or nil
+func (rl ReleaseList) Find(id int) *Release {
+	for i := range rl {
+		if rl[i].ID == id {
+			return &rl[i]
+		}
+	}
+	return nil
+}
+
+// DefaultPort is the container port of apps which do not configure one
+const DefaultPort = int32(8080)
+
+// Probe configures a health check of an app's container. An "http" probe
+// requests the path, a "tcp" probe only opens a connection to the port. The
+// type "none" removes the probe in an update.
+type Probe struct {
+	Type                string `json:"type"`
+	Path                string `json:"path,omitempty"`
+	InitialDelaySeconds int32  `json:"initial_delay_seconds,omitempty"`
+	PeriodSeconds       int32  `json:"period_seconds,omitempty"`
+	TimeoutSeconds      int32  `json:"timeout_seconds,omitempty"`
+	FailureThreshold    int32  `json:"failure_threshold,omitempty"`
+}
+
+// ResourceNone removes a request or limit of the resources in an update
+const ResourceNone = "none"
+
+// Resources are the compute resource requests and limits of an app's
+// container, as kubernetes quantities, e.g. "512Mi" or "250m".
+type Resources struct {
+	MemoryRequest string `json:"memory_request,omitempty"`
+	MemoryLimit   string `json:"memory_limit,omitempty"`
+	CPURequest    string `json:"cpu_request,omitempty"`
+	CPULimit      string `json:"cpu_limit,omitempty"`
+}
+
+// AppSettings are the runtime settings of an app's workload. They are kept
+// on the application resource and applied by every deployment.
+type AppSettings struct {
+	Port      int32     `json:"port,omitempty"`
+	Liveness  *Probe    `json:"liveness,omitempty"`
+	Readiness *Probe    `json:"readiness,omitempty"`
+	Resources Resources `json:"resources,omitempty"`
+}
+
+// ContainerPort returns the port the app listens on
+func (s AppSettings) ContainerPort() int32 {
+	if s.Port == 0 {
+		return DefaultPort
+	}
+	return s.Port
+}
+
+// Autoscale describes the horizontal autoscaling of an app, i.e. the bounds
+// of its instances and the average cpu utilization aimed at. The current
+// values are reported by the API only.
+type Autoscale struct {
+	Min        int32 `json:"min"`
+	Max        int32 `json:"max"`
+	CPUPercent int32 `json:"cpu_percent"`
+
+	CurrentInstances  int32  `json:"current_instances,omitempty"`
+	DesiredInstances  int32  `json:"desired_instances,omitempty"`
+	CurrentCPUPercent *int32 `json:"current_cpu_percent,omitempty"`
+}
+
+// DefaultAutoDeployBranch is the branch of the gitea repo of an app which is
+// deployed automatically, if no other branch is configured. The repo is
+// created with it as its default branch.
+const DefaultAutoDeployBranch = "main"
+
+// AutoDeploy describes the auto-deploy mode of an app. Pushes to the branch
+// of the app's gitea repo stage and deploy the pushed revision.
+type AutoDeploy struct {
+	Branch string `json:"branch,omitempty"`
 }
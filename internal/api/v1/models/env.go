@@ -9,10 +9,26 @@ import (
 // This subsection of models provides structures related to the
 // environment variables of applications.
 
+// The scopes of an environment variable. A variable is passed to the
+// staging of the app, to the running app, or to both. Variables without a
+// scope are passed to both.
+const (
+	EnvScopeBoth    = "both"
+	EnvScopeBuild   = "build"
+	EnvScopeRuntime = "runtime"
+)
+
 // Show Response
 type EnvVariable struct {
 	Name  string `json:"name"`
 	Value string `json:"value"`
+	Scope string `json:"scope,omitempty"`
+}
+
+// InScope returns true if the variable is passed to the given scope, i.e.
+// EnvScopeBuild or EnvScopeRuntime
+func (ev EnvVariable) InScope(scope string) bool {
+	return ev.Scope == "" || ev.Scope == EnvScopeBoth || ev.Scope == scope
 }
 
 // Set Request, List Response
@@ -35,6 +51,8 @@ func (evl EnvVariableList) Less(i, j int) bool {
 	return evl[i].Name < evl[j].Name
 }
 
+// ToEnvVarArray returns the runtime variables of the list, as references
+// into the environment secret of the app
 func (evl EnvVariableList) ToEnvVarArray(appRef AppRef) []v1.EnvVar {
 	deploymentEnvironment := []v1.EnvVar{
 		{
@@ -44,6 +62,9 @@ func (evl EnvVariableList) ToEnvVarArray(appRef AppRef) []v1.EnvVar {
 	}
 
 	for _, ev := range evl {
+		if !ev.InScope(EnvScopeRuntime) {
+			continue
+		}
 		deploymentEnvironment = append(deploymentEnvironment, v1.EnvVar{
 			Name: ev.Name,
 			ValueFrom: &v1.EnvVarSource{
@@ -60,10 +81,14 @@ func (evl EnvVariableList) ToEnvVarArray(appRef AppRef) []v1.EnvVar {
 	return deploymentEnvironment
 }
 
+// StagingEnvArray returns the build variables of the list, as assignments
 func (evl EnvVariableList) StagingEnvArray() []string {
 	stagingVariables := []string{}
 
 	for _, ev := range evl {
+		if !ev.InScope(EnvScopeBuild) {
+			continue
+		}
 		stagingVariables = append(stagingVariables, fmt.Sprintf("%s=%s", ev.Name, ev.Value))
 	}
 
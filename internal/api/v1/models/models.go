@@ -2,6 +2,8 @@
 // response data used by the communication between cli and api server.
 package models
 
+import "time"
+
 type ServiceResponse struct {
 	Name      string   `json:"name"`
 	BoundApps []string `json:"boundapps"`
@@ -43,21 +45,159 @@ type ApplicationCreateRequest struct {
 }
 
 type UpdateAppRequest struct {
-	Instances int32 `json:"instances"`
+	Instances     *int32       `json:"instances,omitempty"`
+	Settings      *AppSettings `json:"settings,omitempty"`
+	StagingPolicy string       `json:"staging_policy,omitempty"`
+	Builder       string       `json:"builder,omitempty"`
+	Buildpacks    *Buildpacks  `json:"buildpacks,omitempty"`
+}
+
+// Org is an organization, with the buildpacks settings its apps default to
+type Org struct {
+	Name       string     `json:"name"`
+	Buildpacks Buildpacks `json:"buildpacks"`
+}
+
+// OrgUpdateRequest changes the buildpacks settings the apps of an org
+// default to, see Buildpacks.Merge
+type OrgUpdateRequest struct {
+	Buildpacks *Buildpacks `json:"buildpacks,omitempty"`
 }
 
 // TODO: CreateOrgRequest
 
+type UserCreateRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Admin    bool   `json:"admin,omitempty"`
+}
+
+type UserRoleRequest struct {
+	Role string `json:"role"`
+}
+
+type UserResponse struct {
+	Username string            `json:"username"`
+	Admin    bool              `json:"admin"`
+	Orgs     map[string]string `json:"orgs"`
+}
+
+type UserResponseList []UserResponse
+
+type TokenCreateRequest struct {
+	Name      string `json:"name"`
+	ExpiresIn string `json:"expiresin,omitempty"` // a duration, like `720h`. Empty for tokens which do not expire.
+}
+
+type TokenResponse struct {
+	Name    string     `json:"name"`
+	Created time.Time  `json:"created"`
+	Expires *time.Time `json:"expires,omitempty"`
+	Token   string     `json:"token,omitempty"` // only set on creation
+}
+
+type TokenResponseList []TokenResponse
+
+// The channels of an exec stream. Each binary message of the websocket
+// starts with the byte of its channel. The client sends ExecStdin and
+// ExecResize messages, the latter carrying a JSON TerminalSize. An empty
+// ExecStdin message closes the input of the command. The server
+// sends ExecStdout and ExecStderr messages, and ends the stream with a text
+// message carrying the JSON ExecResult.
+const (
+	ExecStdin  = byte(0)
+	ExecStdout = byte(1)
+	ExecStderr = byte(2)
+	ExecResize = byte(4)
+)
+
+type TerminalSize struct {
+	Width  uint16 `json:"width"`
+	Height uint16 `json:"height"`
+}
+
+type ExecResult struct {
+	ExitCode int    `json:"exitcode"`
+	Error    string `json:"error,omitempty"`
+}
+
+// CertificateCreateRequest carries a PEM encoded certificate chain and key
+// for the routes of a domain. CA holds the root certificate(s) to verify the
+// chain with, if the system roots do not suffice.
+type CertificateCreateRequest struct {
+	Domain      string `json:"domain"`
+	Certificate string `json:"certificate"`
+	Key         string `json:"key"`
+	CA          string `json:"ca,omitempty"`
+}
+
+type CertificateResponse struct {
+	Domain   string    `json:"domain"`
+	Names    []string  `json:"names"`
+	Issuer   string    `json:"issuer"`
+	NotAfter time.Time `json:"notafter"`
+}
+
+type CertificateResponseList []CertificateResponse
+
+// GitCredentialsCreateRequest carries the basic auth credentials of an
+// external git server, for staging from the repositories it hosts
+type GitCredentialsCreateRequest struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// GitCredentials describes the git credentials of an org. The password is
+// never returned.
+type GitCredentials struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Username string `json:"username"`
+}
+
+type GitCredentialsList []GitCredentials
+
+type AuthConfigResponse struct {
+	OIDCIssuer   string `json:"oidcissuer,omitempty"`
+	OIDCClientID string `json:"oidcclientid,omitempty"`
+}
+
 // UploadRequest is a multipart form
 
+// UploadResponse is the response to an upload of app sources. Builder is the
+// builder detected from the sources, see BuilderAuto.
 type UploadResponse struct {
-	Git *GitRef `json:"git,omitempty"`
+	Git     *GitRef `json:"git,omitempty"`
+	Builder string  `json:"builder,omitempty"`
 }
 
+// The headers of the chunks of an upload. Offset is the position of the chunk
+// in the upload, and Length the total size of the upload.
+const (
+	UploadOffsetHeader = "Upload-Offset"
+	UploadLengthHeader = "Upload-Length"
+)
+
+// UploadStatus is the state of a chunked upload of app sources, identified by
+// the sha256 digest of their tarball. Offset is the number of bytes received
+// so far, where the next chunk has to start. Stored is set when the server
+// holds the sources with the digest already, and nothing has to be uploaded.
+type UploadStatus struct {
+	Digest string          `json:"digest"`
+	Offset int64           `json:"offset"`
+	Stored *UploadResponse `json:"stored,omitempty"`
+}
+
+// StageRequest is a request to stage app sources. Builder is the builder
+// detected from the sources, if any, used by apps detecting their builder.
+// ClearCache stages without the build cache of the app, and replaces it.
 type StageRequest struct {
-	App   AppRef  `json:"app,omitempty"`
-	Git   *GitRef `json:"git,omitempty"`
-	Route string  `json:"route,omitempty"`
+	App        AppRef  `json:"app,omitempty"`
+	Git        *GitRef `json:"git,omitempty"`
+	Builder    string  `json:"builder,omitempty"`
+	ClearCache bool    `json:"clear_cache,omitempty"`
 }
 
 type StageResponse struct {
@@ -66,12 +206,25 @@ type StageResponse struct {
 }
 
 type DeployRequest struct {
-	App       AppRef   `json:"app,omitempty"`
-	Instances *int32   `json:"instances,omitempty"`
-	Stage     StageRef `json:"stage,omitempty"`
-	Route     string   `json:"route,omitempty"`
-	Git       *GitRef  `json:"git,omitempty"`
-	ImageURL  string   `json:"image,omitempty"`
+	App       AppRef       `json:"app,omitempty"`
+	Instances *int32       `json:"instances,omitempty"`
+	Stage     StageRef     `json:"stage,omitempty"`
+	Routes    []string     `json:"routes,omitempty"` // replace the app's routes when given
+	Git       *GitRef      `json:"git,omitempty"`
+	ImageURL  string       `json:"image,omitempty"`
+	Settings  *AppSettings `json:"settings,omitempty"`
+}
+
+type DeployResponse struct {
+	Routes []string `json:"routes,omitempty"`
+}
+
+type RouteRequest struct {
+	Route string `json:"route"`
+}
+
+type RollbackRequest struct {
+	Release int `json:"release,omitempty"` // zero selects the release before the current one
 }
 
 type ApplicationDeleteResponse struct {
@@ -11,6 +11,7 @@ import (
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/auth"
 	"github.com/epinio/epinio/internal/cli/clients/gitea"
 	"github.com/epinio/epinio/internal/organizations"
 	"github.com/epinio/epinio/internal/services"
@@ -36,8 +37,14 @@ func (oc OrganizationsController) Index(w http.ResponseWriter, r *http.Request)
 		return InternalError(err)
 	}
 
+	// Users see only the orgs they have a role in. Admins see all.
+	user := auth.CurrentUser(ctx)
+
 	orgNames := []string{}
 	for _, org := range orgList {
+		if user != nil && !user.Allowed(org.Name, http.MethodGet) {
+			continue
+		}
 		orgNames = append(orgNames, org.Name)
 	}
 
@@ -160,6 +167,83 @@ func (oc OrganizationsController) Delete(w http.ResponseWriter, r *http.Request)
 	return nil
 }
 
+// Show returns the org, with the buildpacks settings its apps default to
+func (oc OrganizationsController) Show(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	buildpacks, err := organizations.Buildpacks(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, models.Org{Name: org, Buildpacks: buildpacks})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Update changes the buildpacks settings the apps of the org default to
+func (oc OrganizationsController) Update(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var updateRequest models.OrgUpdateRequest
+	err = json.Unmarshal(bodyBytes, &updateRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if updateRequest.Buildpacks != nil {
+		if err := application.ValidateBuildpacks(*updateRequest.Buildpacks); err != nil {
+			return BadRequest(err)
+		}
+
+		_, err = organizations.BuildpacksUpdate(ctx, cluster, org, *updateRequest.Buildpacks)
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	return nil
+}
+
 // deleteApps removes the application and its resources
 func deleteApps(ctx context.Context, cluster *kubernetes.Cluster, gitea *gitea.Client, org string) error {
 	appRefs, err := application.ListAppRefs(ctx, cluster, org)
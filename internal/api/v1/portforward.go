@@ -0,0 +1,107 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/gorilla/websocket"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PortForward connects a websocket to a port of a running instance of the
+// app. Each websocket carries a single TCP connection, as binary messages in
+// both directions. The query parameters are `port`, the port of the
+// instance, and `instance`, the index of the instance.
+func (hc ApplicationsController) PortForward(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	query := r.URL.Query()
+	port, err := strconv.Atoi(query.Get("port"))
+	if err != nil || port < 1 || port > 65535 {
+		jsonErrorResponse(w, NewBadRequest("port should be a number between 1 and 65535"))
+		return
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+
+	pod, apiErr := instancePod(ctx, cluster, org, appName, query.Get("instance"))
+	if apiErr != nil {
+		jsonErrorResponse(w, apiErr)
+		return
+	}
+
+	podConn, err := cluster.PortForward(org, pod.Name, port)
+	if err != nil {
+		jsonErrorResponse(w, InternalError(err))
+		return
+	}
+	defer podConn.Close()
+
+	var upgrader = websocket.Upgrader{}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader responded already
+		log.V(1).Error(err, "failed to upgrade to websockets")
+		return
+	}
+	defer conn.Close()
+
+	log.Info("port-forward to app", "org", org, "app", appName, "pod", pod.Name, "port", port)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// Closing the pod connection ends the copy below as well
+		defer podConn.Close()
+		for {
+			kind, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if kind != websocket.BinaryMessage {
+				continue
+			}
+			if _, err := podConn.Write(data); err != nil {
+				return
+			}
+		}
+	}()
+
+	closeCode, closeText := websocket.CloseNormalClosure, ""
+	buffer := make([]byte, 32*1024)
+	for {
+		n, err := podConn.Read(buffer)
+		if n > 0 {
+			if err := conn.WriteMessage(websocket.BinaryMessage, buffer[:n]); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			if err != io.EOF {
+				log.V(1).Info("port-forward ended", "error", err.Error())
+				closeCode, closeText = websocket.CloseInternalServerErr, err.Error()
+			}
+			break
+		}
+	}
+
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeCode, closeText), time.Now().Add(time.Second))
+	conn.Close()
+	wg.Wait()
+}
@@ -0,0 +1,187 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/epinio/epinio/internal/domain"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Releases returns the release history of the app, oldest first
+func (hc ApplicationsController) Releases(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	releases, err := application.Releases(ctx, cluster, models.NewAppRef(appName, org))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, releases)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Rollback deploys the image and the environment of an earlier release of
+// the app again, without staging. The rollback is recorded as a new release.
+func (hc ApplicationsController) Rollback(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	params := httprouter.ParamsFromContext(ctx)
+	org := params.ByName("org")
+	appName := params.ByName("app")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	req := models.RollbackRequest{}
+	if len(bodyBytes) > 0 {
+		if err := json.Unmarshal(bodyBytes, &req); err != nil {
+			return NewBadRequest("Failed to unmarshal rollback request", err.Error())
+		}
+	}
+	if req.Release < 0 {
+		return NewBadRequest("release param should be a positive integer")
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+
+	applicationCR, err := application.Get(ctx, cluster, app)
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return AppIsNotKnown(appName)
+		}
+		return InternalError(err, "failed to get the application resource")
+	}
+
+	releases, err := application.Releases(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	target, err := application.RollbackTarget(releases, req.Release)
+	if err != nil {
+		if err == application.ErrNoRelease {
+			return NewBadRequest("cannot roll back, the application has no earlier release")
+		}
+		return NewAPIError(err.Error(), "", http.StatusNotFound)
+	}
+
+	instances, err := deployInstances(ctx, cluster, app, nil)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	mainDomain, err := domain.MainDomain(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	settings, err := application.Settings(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err, "failed to get the application settings")
+	}
+
+	routes, apiErr := updateRoutes(ctx, cluster, app, nil, mainDomain)
+	if apiErr != nil {
+		return apiErr
+	}
+
+	environment, err := application.ReleaseEnvironment(ctx, cluster, app, *target)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if err := application.EnvironmentReplace(ctx, cluster, app, environment); err != nil {
+		return InternalError(err, "failed to restore the application environment")
+	}
+
+	deployParams := deployParam{
+		AppRef:      app,
+		Git:         target.Git,
+		Routes:      routes,
+		ImageURL:    target.ImageURL,
+		Instances:   instances,
+		Domain:      mainDomain,
+		Stage:       models.NewStage(target.StageID),
+		Environment: environment,
+		Settings:    settings,
+		Owner: metav1.OwnerReference{
+			APIVersion: applicationCR.GetAPIVersion(),
+			Kind:       applicationCR.GetKind(),
+			Name:       applicationCR.GetName(),
+			UID:        applicationCR.GetUID(),
+		},
+	}
+
+	log.Info("rolling back app", "org", org, "app", appName, "release", target.ID)
+	if err := deploy(ctx, cluster, deployParams); err != nil {
+		return InternalError(err)
+	}
+
+	release := newRelease(ctx, deployParams)
+	release.RollbackOf = target.ID
+	recorded, err := application.AddRelease(ctx, cluster, app, release)
+	if err != nil {
+		// The app is rolled back already, a missing release only
+		// shortens its history
+		log.Error(err, "failed to record the release", "org", org, "app", appName, "rollback_of", target.ID)
+		recorded = release
+		recorded.Environment = nil
+	}
+	release = recorded
+
+	err = jsonResponse(w, release)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
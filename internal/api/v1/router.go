@@ -65,18 +65,46 @@ func patch(path string, h http.HandlerFunc) routes.Route {
 }
 
 var Routes = routes.NamedRoutes{
-	"Info": get("/info", errorHandler(InfoController{}.Info)),
-
-	"Apps":        get("/orgs/:org/applications", errorHandler(ApplicationsController{}.Index)),
-	"AppCreate":   post("/orgs/:org/applications", errorHandler(ApplicationsController{}.Create)),
-	"AppShow":     get("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Show)),
-	"AppLogs":     get("/orgs/:org/applications/:app/logs", ApplicationsController{}.Logs),
-	"StagingLogs": get("/orgs/:org/staging/:stage_id/logs", ApplicationsController{}.Logs),
-	"AppDelete":   delete("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Delete)),
-	"AppUpload":   post("/orgs/:org/applications/:app/store", errorHandler(ApplicationsController{}.Upload)), // See upload.go
-	"AppStage":    post("/orgs/:org/applications/:app/stage", errorHandler(ApplicationsController{}.Stage)),  // See stage.go
-	"AppDeploy":   post("/orgs/:org/applications/:app/deploy", errorHandler(ApplicationsController{}.Deploy)),
-	"AppUpdate":   patch("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Update)),
+	"Info":       get("/info", errorHandler(InfoController{}.Info)),
+	"AuthConfig": get("/auth/config", errorHandler(AuthConfig)),
+
+	"Apps":            get("/orgs/:org/applications", errorHandler(ApplicationsController{}.Index)),
+	"AppCreate":       post("/orgs/:org/applications", errorHandler(ApplicationsController{}.Create)),
+	"AppShow":         get("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Show)),
+	"AppLogs":         get("/orgs/:org/applications/:app/logs", ApplicationsController{}.Logs),
+	"AppEvents":       get("/orgs/:org/applications/:app/events", errorHandler(ApplicationsController{}.Events)), // See events.go
+	"StagingLogs":     get("/orgs/:org/staging/:stage_id/logs", ApplicationsController{}.Logs),
+	"StagingStatus":   get("/orgs/:org/staging/:stage_id/status", errorHandler(ApplicationsController{}.StageStatus)), // See stage.go
+	"StagingCancel":   delete("/orgs/:org/staging/:stage_id", errorHandler(ApplicationsController{}.StageCancel)),     // See stage.go
+	"AppExec":         get("/orgs/:org/applications/:app/exec", ApplicationsController{}.Exec),
+	"AppPortForward":  get("/orgs/:org/applications/:app/portforward", ApplicationsController{}.PortForward),
+	"AppDelete":       delete("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Delete)),
+	"AppUpload":       post("/orgs/:org/applications/:app/store", errorHandler(ApplicationsController{}.Upload)), // See upload.go
+	"AppUploadStatus": get("/orgs/:org/applications/:app/store/:digest", errorHandler(ApplicationsController{}.UploadStatus)),
+	"AppUploadChunk":  patch("/orgs/:org/applications/:app/store/:digest", errorHandler(ApplicationsController{}.UploadChunk)),
+	"AppUploadFinish": post("/orgs/:org/applications/:app/store/:digest", errorHandler(ApplicationsController{}.UploadFinish)),
+	"AppStage":        post("/orgs/:org/applications/:app/stage", errorHandler(ApplicationsController{}.Stage)),         // See stage.go
+	"AppStaging":      get("/orgs/:org/applications/:app/staging", errorHandler(ApplicationsController{}.StagingQueue)), // See stage.go
+	"AppDeploy":       post("/orgs/:org/applications/:app/deploy", errorHandler(ApplicationsController{}.Deploy)),
+	"AppUpdate":       patch("/orgs/:org/applications/:app", errorHandler(ApplicationsController{}.Update)),
+
+	// See approutes.go
+	"AppRoutes":      get("/orgs/:org/applications/:app/routes", errorHandler(ApplicationsController{}.RouteIndex)),
+	"AppRouteAdd":    post("/orgs/:org/applications/:app/routes", errorHandler(ApplicationsController{}.RouteAdd)),
+	"AppRouteRemove": delete("/orgs/:org/applications/:app/routes/:route", errorHandler(ApplicationsController{}.RouteRemove)),
+
+	// See autoscale.go
+	"AppAutoscale":       post("/orgs/:org/applications/:app/autoscale", errorHandler(ApplicationsController{}.Autoscale)),
+	"AppAutoscaleDelete": delete("/orgs/:org/applications/:app/autoscale", errorHandler(ApplicationsController{}.AutoscaleDelete)),
+
+	// Stage and deploy pushes to the gitea repo of an app. See autodeploy.go
+	"AppAutoDeploy":       post("/orgs/:org/applications/:app/autodeploy", errorHandler(ApplicationsController{}.AutoDeploy)),
+	"AppAutoDeployDelete": delete("/orgs/:org/applications/:app/autodeploy", errorHandler(ApplicationsController{}.AutoDeployDelete)),
+	"AppWebhook":          post("/webhooks/gitea/:org/:app", errorHandler(ApplicationsController{}.Webhook)),
+
+	// See releases.go
+	"AppReleases": get("/orgs/:org/applications/:app/releases", errorHandler(ApplicationsController{}.Releases)),
+	"AppRollback": post("/orgs/:org/applications/:app/rollback", errorHandler(ApplicationsController{}.Rollback)),
 
 	// See env.go
 	"EnvList":  get("/orgs/:org/applications/:app/environment", errorHandler(ApplicationsController{}.EnvIndex)),
@@ -91,9 +119,11 @@ var Routes = routes.NamedRoutes{
 	"ServiceBindingDelete": delete("/orgs/:org/applications/:app/servicebindings/:service",
 		errorHandler(ServicebindingsController{}.Delete)),
 
-	// List, create, show and delete organizations
+	// List, create, show, update and delete organizations
 	"Orgs":      get("/orgs", errorHandler(OrganizationsController{}.Index)),
 	"OrgCreate": post("/orgs", errorHandler(OrganizationsController{}.Create)),
+	"OrgShow":   get("/orgs/:org", errorHandler(OrganizationsController{}.Show)),
+	"OrgUpdate": patch("/orgs/:org", errorHandler(OrganizationsController{}.Update)),
 	"OrgDelete": delete("/orgs/:org", errorHandler(OrganizationsController{}.Delete)),
 
 	// List, show, create and delete services, catalog and custom
@@ -106,13 +136,69 @@ var Routes = routes.NamedRoutes{
 	// list service classes and plans (of catalog services)
 	"ServiceClasses": get("/serviceclasses", errorHandler(ServiceClassesController{}.Index)),
 	"ServicePlans":   get("/serviceclasses/:serviceclass/serviceplans", errorHandler(ServicePlansController{}.Index)),
+
+	// List, upload and delete the TLS certificates of an org. See certificates.go
+	"Certificates":      get("/orgs/:org/certificates", errorHandler(CertificatesController{}.Index)),
+	"CertificateCreate": post("/orgs/:org/certificates", errorHandler(CertificatesController{}.Create)),
+	"CertificateDelete": delete("/orgs/:org/certificates/:domain", errorHandler(CertificatesController{}.Delete)),
+
+	// List, create and delete the git credentials of an org. See gitcredentials.go
+	"GitCredentials":       get("/orgs/:org/gitcredentials", errorHandler(GitCredentialsController{}.Index)),
+	"GitCredentialsCreate": post("/orgs/:org/gitcredentials", errorHandler(GitCredentialsController{}.Create)),
+	"GitCredentialsDelete": delete("/orgs/:org/gitcredentials/:credentials", errorHandler(GitCredentialsController{}.Delete)),
+
+	// List, create and delete API users, and manage their org roles. See users.go
+	"Users":         get("/users", errorHandler(UsersController{}.Index)),
+	"UserCreate":    post("/users", errorHandler(UsersController{}.Create)),
+	"UserDelete":    delete("/users/:user", errorHandler(UsersController{}.Delete)),
+	"UserRoleSet":   post("/users/:user/orgs/:org", errorHandler(UsersController{}.SetRole)),
+	"UserRoleUnset": delete("/users/:user/orgs/:org", errorHandler(UsersController{}.UnsetRole)),
+
+	// List, create and revoke the API tokens of the current user. See tokens.go
+	"Tokens":      get("/tokens", errorHandler(TokensController{}.Index)),
+	"TokenCreate": post("/tokens", errorHandler(TokensController{}.Create)),
+	"TokenDelete": delete("/tokens/:token", errorHandler(TokensController{}.Delete)),
+}
+
+// publicRoutes names the routes which do not require authentication
+var publicRoutes = map[string]bool{
+	"AuthConfig": true,
+	"AppWebhook": true, // Signed by gitea instead
+}
+
+// adminRoutes names the routes restricted to admin users. All other routes
+// are open to users with a suitable role in the `:org` of the route, if any.
+var adminRoutes = map[string]bool{
+	"OrgCreate":     true,
+	"OrgDelete":     true,
+	"OrgUpdate":     true,
+	"Users":         true,
+	"UserCreate":    true,
+	"UserDelete":    true,
+	"UserRoleSet":   true,
+	"UserRoleUnset": true,
+}
+
+// instanceRoutes names the websocket routes giving access to the instances
+// of an app. They are GET requests, but need the permission to change the
+// org of the route.
+var instanceRoutes = map[string]bool{
+	"AppExec":        true,
+	"AppPortForward": true,
 }
 
-func Router() *httprouter.Router {
+// Router returns the handler of the API. All requests, except for the public
+// routes, have to be authenticated, and each route is guarded by the roles of
+// the user.
+func Router() http.Handler {
 	router := httprouter.New()
 
-	for _, r := range Routes {
-		router.HandlerFunc(r.Method, r.Path, r.Handler)
+	for name, r := range Routes {
+		if publicRoutes[name] {
+			router.HandlerFunc(r.Method, r.Path, r.Handler)
+			continue
+		}
+		router.Handler(r.Method, r.Path, Authenticate(authorize(r.Handler, adminRoutes[name], instanceRoutes[name])))
 	}
 
 	router.NotFound = http.NotFoundHandler()
@@ -1,10 +1,12 @@
 package v1
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 
 	"github.com/julienschmidt/httprouter"
 	"github.com/spf13/viper"
@@ -22,8 +24,8 @@ import (
 	"github.com/epinio/epinio/helpers/tracelog"
 	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/application"
-	"github.com/epinio/epinio/internal/auth"
 	"github.com/epinio/epinio/internal/domain"
+	"github.com/epinio/epinio/internal/organizations"
 )
 
 const (
@@ -37,6 +39,11 @@ type stageParam struct {
 	Owner       metav1.OwnerReference
 	Environment models.EnvVariableList
 	RegistryURL string
+	Builder     string
+	Buildpacks  models.Buildpacks
+	Cache       string // The claim of the build cache, none if empty
+	ClearCache  bool
+	GitAccount  string // The service account cloning with the git credentials, none if empty
 }
 
 // GitURL returns the git URL by combining the server with the org and name
@@ -45,15 +52,29 @@ func (app *stageParam) GitURL(server string) string {
 }
 
 // ImageURL returns the URL of the image, using the ImageID. The ImageURL is
-// later used in app.yml and to send in the stage response.
+// later used in app.yml and to send in the stage response. Staging the head
+// of a branch has no revision to tag the image with, the stage ID is used
+// instead.
 func (app *stageParam) ImageURL(registryURL string) string {
-	return fmt.Sprintf("%s/%s-%s", registryURL, app.Name, app.Git.Revision)
+	tag := app.Git.Revision
+	if tag == "" {
+		tag = app.Stage.ID
+	}
+	return fmt.Sprintf("%s/%s-%s", registryURL, app.Name, tag)
+}
+
+// GitRevision returns the revision to clone, the head of the branch if no
+// revision is set
+func (app *stageParam) GitRevision() string {
+	if app.Git.Revision != "" {
+		return app.Git.Revision
+	}
+	return app.Git.Branch
 }
 
 // Stage will create a Tekton PipelineRun resource to stage the app
 func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
-	log := tracelog.Logger(ctx)
 
 	p := httprouter.ParamsFromContext(ctx)
 	org := p.ByName("org")
@@ -76,51 +97,116 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 	if org != req.App.Org {
 		return NewBadRequest("org parameter from URL does not match org param in body")
 	}
+	if req.Git == nil || req.Git.URL == "" {
+		return NewBadRequest("git repository of the sources is missing")
+	}
 
 	cluster, err := kubernetes.GetCluster(ctx)
 	if err != nil {
 		return InternalError(err, "failed to get access to a kube client")
 	}
 
+	resp, apierr := stage(ctx, cluster, req)
+	if apierr != nil {
+		return apierr
+	}
+
+	err = jsonResponse(w, resp)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// stage creates the Tekton PipelineRun staging the app from the git
+// repository of the request, and returns the image it will build
+func stage(ctx context.Context, cluster *kubernetes.Cluster, req models.StageRequest) (*models.StageResponse, APIErrors) {
+	log := tracelog.Logger(ctx)
+	org := req.App.Org
+
+	gitAccount := ""
+	if req.Git.Credentials != "" {
+		var err error
+		gitAccount, err = organizations.GitCredentialsServiceAccount(ctx, cluster, org, req.Git.Credentials)
+		if err != nil {
+			return nil, InternalError(err)
+		}
+		if gitAccount == "" {
+			return nil, GitCredentialsAreNotKnown(req.Git.Credentials)
+		}
+	}
+
 	// check application resource
 	app, err := application.Get(ctx, cluster, req.App)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
-			return AppIsNotKnown("cannot stage app, application resource is missing")
+			return nil, AppIsNotKnown("cannot stage app, application resource is missing")
 		}
-		return InternalError(err, "failed to get the application resource")
+		return nil, InternalError(err, "failed to get the application resource")
 	}
 
 	log.Info("staging app", "org", org, "app", req)
 
 	cs, err := versioned.NewForConfig(cluster.RestConfig)
 	if err != nil {
-		return InternalError(err, "failed to get access to a tekton client")
+		return nil, InternalError(err, "failed to get access to a tekton client")
 	}
 	client := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
 
 	uid, err := randstr.Hex16()
 	if err != nil {
-		return InternalError(err, "failed to generate a uid")
+		return nil, InternalError(err, "failed to generate a uid")
 	}
 
-	l, err := client.List(ctx, metav1.ListOptions{
-		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s", req.App.Name, req.App.Org),
-	})
+	// An app staged already handles the request according to its policy
+	policy, err := application.StagingPolicy(ctx, cluster, req.App)
 	if err != nil {
-		return InternalError(err)
+		return nil, InternalError(err)
 	}
-
-	// assume that completed pipelineruns are from the past and have a CompletionTime
-	for _, pr := range l.Items {
-		if pr.Status.CompletionTime == nil {
-			return NewBadRequest("pipelinerun for image ID still running")
+	unfinished, err := application.UnfinishedStaging(ctx, cluster, req.App)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+	queued := false
+	if len(unfinished) > 0 {
+		switch policy {
+		case models.StagingReject:
+			return nil, NewAPIError("application is staging already, and its staging policy rejects another staging",
+				"", http.StatusConflict)
+		case models.StagingSupersede:
+			log.Info("superseding staging", "org", org, "app", req.App.Name, "runs", len(unfinished))
+			if err := application.CancelStaging(ctx, cluster, unfinished); err != nil {
+				return nil, InternalError(err, "failed to cancel the current staging")
+			}
+		default:
+			queued = true
 		}
 	}
 
+	builder, err := application.Builder(ctx, cluster, req.App)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	buildpacks, err := application.StagingBuildpacks(ctx, cluster, req.App)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
 	environment, err := application.Environment(ctx, cluster, req.App)
 	if err != nil {
-		return InternalError(err, "failed to access application runtime environment")
+		return nil, InternalError(err, "failed to access application runtime environment")
+	}
+
+	builder = application.StagingBuilder(builder, req.Builder)
+
+	cache := ""
+	if builder == models.BuilderBuildpacks {
+		cache, err = application.StagingCache(ctx, cluster, req.App, viper.GetString("build-cache-size"))
+		if err != nil {
+			return nil, InternalError(err, "failed to provide the build cache")
+		}
 	}
 
 	owner := metav1.OwnerReference{
@@ -132,48 +218,164 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 
 	mainDomain, err := domain.MainDomain(ctx)
 	if err != nil {
-		return InternalError(err)
+		return nil, InternalError(err)
 	}
 	params := stageParam{
 		AppRef:      req.App,
 		Git:         req.Git,
+		Stage:       models.NewStage(uid),
 		Owner:       owner,
 		Environment: environment,
 		RegistryURL: fmt.Sprintf("%s.%s/%s", deployments.RegistryDeploymentID, mainDomain, "apps"),
+		Builder:     builder,
+		Buildpacks:  buildpacks,
+		Cache:       cache,
+		ClearCache:  req.ClearCache,
+		GitAccount:  gitAccount,
 	}
 
 	pr := newPipelineRun(uid, params)
-	o, err := client.Create(ctx, pr, metav1.CreateOptions{})
-	if err != nil {
-		return InternalError(err, fmt.Sprintf("failed to create pipeline run: %#v", o))
+	if queued {
+		// Started by the staging queue, once the runs before it finished
+		pr.Spec.Status = v1beta1.PipelineRunSpecStatusPending
 	}
-
-	cert := auth.CertParam{
-		Name:      params.Name,
-		Namespace: params.Org,
-		Issuer:    viper.GetString("tls-issuer"),
-		Domain:    mainDomain,
-	}
-
-	log.Info("app cert", "domain", cert.Domain, "issuer", cert.Issuer)
-
-	err = auth.CreateCertificate(ctx, cluster, cert, &owner)
+	o, err := client.Create(ctx, pr, metav1.CreateOptions{})
 	if err != nil {
-		return InternalError(err)
+		return nil, InternalError(err, fmt.Sprintf("failed to create pipeline run: %#v", o))
 	}
 
-	log.Info("staged app", "org", org, "app", params.AppRef, "uid", uid)
+	log.Info("staged app", "org", org, "app", params.AppRef, "uid", uid, "builder", params.Builder)
 	// The ImageURL in the response should be the one accessible by kubernetes.
 	// In stageParam above, the registry is passed with the registry ingress url,
 	// since it's where tekton will push.
 	if viper.GetBool("use-internal-registry-node-port") {
 		params.RegistryURL = LocalRegistry
 	}
-	resp := models.StageResponse{
+	return &models.StageResponse{
 		Stage:    models.NewStage(uid),
 		ImageURL: params.ImageURL(params.RegistryURL),
+	}, nil
+}
+
+// StageStatus reports the state of a staging run of the org, see
+// models.StageStatus
+func (hc ApplicationsController) StageStatus(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	stageID := p.ByName("stage_id")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
 	}
-	err = jsonResponse(w, resp)
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	status, err := application.StageStatus(ctx, cluster, org, stageID)
+	if err != nil {
+		return InternalError(err)
+	}
+	if status == nil {
+		return StageIsNotKnown(stageID)
+	}
+
+	err = jsonResponse(w, status)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// StageCancel cancels a staging run of the org, running or queued. The
+// workspace of the run is removed, see application.CancelStaging.
+func (hc ApplicationsController) StageCancel(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	stageID := p.ByName("stage_id")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	pr, err := application.StagingRun(ctx, cluster, org, stageID)
+	if err != nil {
+		return InternalError(err)
+	}
+	if pr == nil {
+		return StageIsNotKnown(stageID)
+	}
+	if pr.IsDone() || pr.IsCancelled() {
+		return NewBadRequest("staging run finished already")
+	}
+
+	log.Info("cancelling staging", "org", org, "stage", stageID)
+	if err := application.CancelStaging(ctx, cluster, []v1beta1.PipelineRun{*pr}); err != nil {
+		return InternalError(err)
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// StagingQueue lists the unfinished staging runs of the app, oldest first,
+// see application.StagingQueue
+func (hc ApplicationsController) StagingQueue(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	p := httprouter.ParamsFromContext(ctx)
+	org := p.ByName("org")
+	appName := p.ByName("app")
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	app := models.NewAppRef(appName, org)
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return AppIsNotKnown(appName)
+	}
+
+	queue, err := application.StagingQueue(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = jsonResponse(w, queue)
 	if err != nil {
 		return InternalError(err)
 	}
@@ -184,6 +386,71 @@ func (hc ApplicationsController) Stage(w http.ResponseWriter, r *http.Request) A
 func newPipelineRun(uid string, app stageParam) *v1beta1.PipelineRun {
 	str := v1beta1.NewArrayOrString
 
+	params := []v1beta1.Param{
+		{Name: "APP_NAME", Value: *str(app.Name)},
+		{Name: "ORG", Value: *str(app.Org)},
+		{Name: "APP_IMAGE", Value: *str(app.ImageURL(app.RegistryURL))},
+		{Name: "STAGE_ID", Value: *str(uid)},
+		{Name: "ENV_VARS", Value: v1beta1.ArrayOrString{
+			Type:     v1beta1.ParamTypeArray,
+			ArrayVal: app.Environment.StagingEnvArray()},
+		},
+	}
+
+	// The pipelines share parameters and the source workspace. Staging by
+	// buildpacks takes the builder image, the buildpacks and the build cache
+	// in addition. Without the cache of the app it builds without a cache.
+	pipeline := "staging-pipeline"
+	if app.Builder == models.BuilderDockerfile {
+		pipeline = "dockerfile-staging-pipeline"
+	} else {
+		params = append(params,
+			v1beta1.Param{Name: "BUILDER_IMAGE", Value: *str(app.Buildpacks.BuilderImage)},
+			v1beta1.Param{Name: "BUILDPACKS", Value: v1beta1.ArrayOrString{
+				Type:     v1beta1.ParamTypeArray,
+				ArrayVal: append([]string{}, app.Buildpacks.Buildpacks...)},
+			},
+			v1beta1.Param{Name: "CLEAR_CACHE", Value: *str(strconv.FormatBool(app.ClearCache))},
+		)
+	}
+
+	workspaces := []v1beta1.WorkspaceBinding{
+		{
+			Name: "source",
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
+				Spec: corev1.PersistentVolumeClaimSpec{
+					AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+					Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+						corev1.ResourceName(corev1.ResourceStorage): resource.MustParse("1Gi"),
+					}},
+				},
+			},
+		},
+	}
+	switch {
+	case app.Builder == models.BuilderDockerfile:
+		// No cache
+	case app.Cache != "":
+		workspaces = append(workspaces, v1beta1.WorkspaceBinding{
+			Name:                  "cache",
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: app.Cache},
+		})
+	default:
+		workspaces = append(workspaces, v1beta1.WorkspaceBinding{
+			Name:     "cache",
+			EmptyDir: &corev1.EmptyDirVolumeSource{},
+		})
+	}
+
+	// Only the clone task runs with the git credentials, if any
+	var taskRunSpecs []v1beta1.PipelineTaskRunSpec
+	if app.GitAccount != "" {
+		taskRunSpecs = append(taskRunSpecs, v1beta1.PipelineTaskRunSpec{
+			PipelineTaskName:       "clone",
+			TaskServiceAccountName: app.GitAccount,
+		})
+	}
+
 	return &v1beta1.PipelineRun{
 		ObjectMeta: metav1.ObjectMeta{
 			Name: uid,
@@ -197,37 +464,17 @@ func newPipelineRun(uid string, app stageParam) *v1beta1.PipelineRun {
 		},
 		Spec: v1beta1.PipelineRunSpec{
 			ServiceAccountName: "staging-triggers-admin",
-			PipelineRef:        &v1beta1.PipelineRef{Name: "staging-pipeline"},
-			Params: []v1beta1.Param{
-				{Name: "APP_NAME", Value: *str(app.Name)},
-				{Name: "ORG", Value: *str(app.Org)},
-				{Name: "APP_IMAGE", Value: *str(app.ImageURL(app.RegistryURL))},
-				{Name: "STAGE_ID", Value: *str(uid)},
-				{Name: "ENV_VARS", Value: v1beta1.ArrayOrString{
-					Type:     v1beta1.ParamTypeArray,
-					ArrayVal: app.Environment.StagingEnvArray()},
-				},
-			},
-			Workspaces: []v1beta1.WorkspaceBinding{
-				{
-					Name: "source",
-					VolumeClaimTemplate: &corev1.PersistentVolumeClaim{
-						Spec: corev1.PersistentVolumeClaimSpec{
-							AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
-							Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
-								corev1.ResourceName(corev1.ResourceStorage): resource.MustParse("1Gi"),
-							}},
-						},
-					},
-				},
-			},
+			PipelineRef:        &v1beta1.PipelineRef{Name: pipeline},
+			Params:             params,
+			Workspaces:         workspaces,
+			TaskRunSpecs:       taskRunSpecs,
 			Resources: []v1beta1.PipelineResourceBinding{
 				{
 					Name: "source-repo",
 					ResourceSpec: &v1alpha1.PipelineResourceSpec{
 						Type: v1alpha1.PipelineResourceTypeGit,
 						Params: []v1alpha1.ResourceParam{
-							{Name: "revision", Value: app.Git.Revision},
+							{Name: "revision", Value: app.GitRevision()},
 							{Name: "url", Value: app.Git.URL},
 						},
 					},
@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+)
+
+// TokensController manages the API tokens of the current user
+type TokensController struct {
+}
+
+// Index lists the tokens of the current user. The token values are not
+// available anymore.
+func (tc TokensController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	user := auth.CurrentUser(ctx)
+	if user == nil {
+		return UserNotAuthenticated()
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	tokens, err := auth.ListTokens(ctx, cluster, user.Username)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	responseData := models.TokenResponseList{}
+	for _, token := range tokens {
+		responseData = append(responseData, tokenResponse(token, ""))
+	}
+
+	err = jsonResponse(w, responseData)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Create adds a token for the current user. The response is the only place
+// where the value of the new token is shown.
+func (tc TokensController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	user := auth.CurrentUser(ctx)
+	if user == nil {
+		return UserNotAuthenticated()
+	}
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var createRequest models.TokenCreateRequest
+	err = json.Unmarshal(bodyBytes, &createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if createRequest.Name == "" {
+		return BadRequest(errors.New("name of token to create not found"))
+	}
+
+	var ttl time.Duration
+	if createRequest.ExpiresIn != "" {
+		ttl, err = time.ParseDuration(createRequest.ExpiresIn)
+		if err != nil {
+			return BadRequest(err, "bad token expiration")
+		}
+		if ttl <= 0 {
+			return NewBadRequest("token expiration must be positive")
+		}
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	value, token, err := auth.CreateToken(ctx, cluster, user.Username, createRequest.Name, ttl)
+	if err != nil {
+		if err == auth.ErrTokenAlreadyKnown {
+			return TokenAlreadyKnown(createRequest.Name)
+		}
+		return InternalError(err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	err = jsonResponse(w, tokenResponse(*token, value))
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Delete revokes the named token of the current user
+func (tc TokensController) Delete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	name := params.ByName("token")
+
+	user := auth.CurrentUser(ctx)
+	if user == nil {
+		return UserNotAuthenticated()
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = auth.RevokeToken(ctx, cluster, user.Username, name)
+	if err != nil {
+		if err == auth.ErrTokenNotKnown {
+			return TokenIsNotKnown(name)
+		}
+		return InternalError(err)
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+func tokenResponse(token auth.Token, value string) models.TokenResponse {
+	response := models.TokenResponse{
+		Name:    token.Name,
+		Created: token.Created,
+		Token:   value,
+	}
+	if !token.Expires.IsZero() {
+		expires := token.Expires
+		response.Expires = &expires
+	}
+	return response
+}
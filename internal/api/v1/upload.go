@@ -1,22 +1,36 @@
 package v1
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
 	"os"
 	"path"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/epinio/epinio/helpers"
+	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/tracelog"
 	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/cli/clients/gitea"
+	"github.com/epinio/epinio/internal/organizations"
 	"github.com/julienschmidt/httprouter"
-	"github.com/mholt/archiver/v3"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"k8s.io/apimachinery/pkg/api/resource"
 )
 
-// Upload receives the application data, as tarball, and creates the gitea as
-// well as k8s resources to trigger staging
+// Upload receives the application data, as archive, and creates the gitea as
+// well as k8s resources to trigger staging. The archive is streamed to disk,
+// up to the maximum upload size.
 func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request) APIErrors {
 	ctx := r.Context()
 	log := tracelog.Logger(ctx)
@@ -27,17 +41,37 @@ func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request)
 
 	log.Info("processing upload", "org", org, "app", name)
 
-	client, err := gitea.New(ctx)
+	if apierr := checkUploadApp(ctx, models.NewAppRef(name, org)); apierr != nil {
+		return apierr
+	}
+
+	maxSize, err := uploadMaxSize()
 	if err != nil {
 		return InternalError(err)
 	}
 
 	log.V(2).Info("parsing multipart form")
 
-	file, _, err := r.FormFile("file")
+	reader, err := r.MultipartReader()
 	if err != nil {
 		return BadRequest(err, "can't read multipart file input")
 	}
+
+	var file io.ReadCloser
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return NewBadRequest("can't read multipart file input", "no file part")
+		}
+		if err != nil {
+			return BadRequest(err, "can't read multipart file input")
+		}
+		if part.FormName() == "file" {
+			file = part
+			break
+		}
+		part.Close()
+	}
 	defer file.Close()
 
 	tmpDir, err := ioutil.TempDir("", "epinio-app")
@@ -46,38 +80,218 @@ func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request)
 	}
 	defer os.RemoveAll(tmpDir)
 
-	blob := path.Join(tmpDir, "blob.tar")
+	blob := path.Join(tmpDir, "blob")
 	f, err := os.OpenFile(blob, os.O_WRONLY|os.O_CREATE, 0666)
 	if err != nil {
 		return InternalError(err, "failed create file for writing app sources to temp location")
 	}
 	defer f.Close()
 
-	_, err = io.Copy(f, file)
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, hash), limitReader(file, maxSize, 0))
 	if err != nil {
 		return InternalError(err, "failed to copy app sources to temp location")
 	}
+	if maxSize > 0 && size > maxSize {
+		return UploadTooLarge(maxSize)
+	}
 
-	log.V(2).Info("unpacking temp dir")
-	appDir := path.Join(tmpDir, "app")
-	err = archiver.Unarchive(blob, appDir)
+	resp, apierr := storeUpload(ctx, models.NewAppRef(name, org), blob, hex.EncodeToString(hash.Sum(nil)))
+	if apierr != nil {
+		return apierr
+	}
+
+	err = jsonResponse(w, resp)
 	if err != nil {
-		return InternalError(err, "failed to unpack app sources to temp location")
+		return InternalError(err)
 	}
 
-	log.V(2).Info("create gitea app repo")
-	app := models.NewAppRef(name, org)
-	g, err := client.Upload(app, appDir)
+	return nil
+}
+
+// UploadStatus returns the state of the chunked upload of the app sources
+// with the digest. The upload starts, or resumes, at the returned offset. It
+// is not needed at all when the sources are stored already.
+func (hc ApplicationsController) UploadStatus(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	app, digest, apierr := uploadParams(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
 	if err != nil {
 		return InternalError(err)
 	}
 
-	log.Info("uploaded app", "org", org, "app", name)
+	stored, err := application.StoredUpload(ctx, cluster, app, digest)
+	if err != nil {
+		return InternalError(err)
+	}
 
-	// Extend url to contain the full repo path
-	g.URL = fmt.Sprintf("%s/%s/%s", g.URL, org, name)
+	status := models.UploadStatus{Digest: digest, Stored: stored}
+	if stored == nil {
+		status.Offset, err = uploadOffset(uploadPath(app, digest))
+		if err != nil {
+			return InternalError(err)
+		}
+	}
+
+	err = jsonResponse(w, status)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadChunk appends the request body to the chunked upload of the app
+// sources with the digest. The chunk has to start at the current offset of
+// the upload, see UploadStatus.
+func (hc ApplicationsController) UploadChunk(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	app, digest, apierr := uploadParams(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	maxSize, err := uploadMaxSize()
+	if err != nil {
+		return InternalError(err)
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get(models.UploadOffsetHeader), 10, 64)
+	if err != nil {
+		return BadRequest(err, "bad upload offset")
+	}
+
+	if length := r.Header.Get(models.UploadLengthHeader); length != "" {
+		total, err := strconv.ParseInt(length, 10, 64)
+		if err != nil {
+			return BadRequest(err, "bad upload length")
+		}
+		if maxSize > 0 && total > maxSize {
+			return UploadTooLarge(maxSize)
+		}
+	}
+
+	blob := uploadPath(app, digest)
+	if err := os.MkdirAll(path.Dir(blob), 0700); err != nil {
+		return InternalError(err, "can't create upload directory")
+	}
+
+	if !uploadLock(blob) {
+		return UploadIsBusy(digest)
+	}
+	defer uploadUnlock(blob)
+
+	// A new upload replaces the unfinished uploads of the app, and
+	// removes the abandoned uploads of all apps
+	if _, err := os.Stat(blob); os.IsNotExist(err) {
+		if err := uploadsReplace(app, digest); err != nil {
+			log.Error(err, "failed to remove the replaced uploads", "org", app.Org, "app", app.Name)
+		}
+		if err := UploadsExpire(); err != nil {
+			log.Error(err, "failed to remove the expired uploads")
+		}
+	}
+
+	f, err := os.OpenFile(blob, os.O_WRONLY|os.O_CREATE, 0600)
+	if err != nil {
+		return InternalError(err, "can't open upload")
+	}
+	defer f.Close()
+
+	current, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return InternalError(err, "can't open upload")
+	}
+	if offset != current {
+		return NewAPIError(
+			fmt.Sprintf("Upload offset %d does not match the %d bytes received", offset, current),
+			"", http.StatusConflict)
+	}
+
+	log.V(2).Info("receiving upload chunk", "digest", digest, "offset", offset)
+
+	n, err := io.Copy(f, limitReader(r.Body, maxSize, current))
+	if maxSize > 0 && current+n > maxSize {
+		// Drop the excess, the upload stays resumable at the limit
+		_ = f.Truncate(current)
+		return UploadTooLarge(maxSize)
+	}
+	if err != nil {
+		// Keep what arrived, the client resumes after it
+		log.Info("upload chunk interrupted", "digest", digest, "error", err.Error())
+	}
+
+	err = jsonResponse(w, models.UploadStatus{Digest: digest, Offset: current + n})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// UploadFinish completes the chunked upload of the app sources with the
+// digest. The received archive has to match the digest. It is then handled
+// as by Upload.
+func (hc ApplicationsController) UploadFinish(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	log := tracelog.Logger(ctx)
+
+	app, digest, apierr := uploadParams(r)
+	if apierr != nil {
+		return apierr
+	}
+
+	log.Info("processing chunked upload", "org", app.Org, "app", app.Name, "digest", digest)
+
+	blob := uploadPath(app, digest)
+	if !uploadLock(blob) {
+		return UploadIsBusy(digest)
+	}
+	defer uploadUnlock(blob)
+
+	f, err := os.Open(blob)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return NewBadRequest(fmt.Sprintf("Upload '%s' has not started", digest))
+		}
+		return InternalError(err, "can't open upload")
+	}
+	defer os.Remove(blob)
+
+	hash := sha256.New()
+	_, err = io.Copy(hash, f)
+	f.Close()
+	if err != nil {
+		return InternalError(err, "can't read upload")
+	}
+	if received := hex.EncodeToString(hash.Sum(nil)); received != digest {
+		return NewBadRequest(fmt.Sprintf("Upload digest mismatch, received '%s'", received))
+	}
+
+	tmpDir, err := ioutil.TempDir("", "epinio-app")
+	if err != nil {
+		return InternalError(err, "can't create temp directory")
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Unpack the sources next to the archive, in a directory of their own
+	archive := path.Join(tmpDir, "blob")
+	if err := os.Rename(blob, archive); err != nil {
+		return InternalError(err, "can't move upload")
+	}
+
+	resp, apierr := storeUpload(ctx, app, archive, digest)
+	if apierr != nil {
+		return apierr
+	}
 
-	resp := models.UploadResponse{Git: &g}
 	err = jsonResponse(w, resp)
 	if err != nil {
 		return InternalError(err)
@@ -85,3 +299,225 @@ func (hc ApplicationsController) Upload(w http.ResponseWriter, r *http.Request)
 
 	return nil
 }
+
+// storeUpload unpacks the archive of the app sources, pushes them into the
+// gitea repo of the app, and remembers their digest. The archive is a tar or
+// zip archive, possibly compressed, or a Java archive. The latter are
+// unpacked too, as the buildpacks expect.
+func storeUpload(ctx context.Context, app models.AppRef, blob, digest string) (*models.UploadResponse, APIErrors) {
+	log := tracelog.Logger(ctx)
+
+	format, err := helpers.ArchiveFormat(blob)
+	if err != nil {
+		return nil, InternalError(err, "failed to read app sources")
+	}
+	if format == "" {
+		return nil, ArchiveIsNotSupported()
+	}
+
+	client, err := gitea.New(ctx)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	log.V(2).Info("unpacking temp dir", "format", format)
+	appDir := path.Join(path.Dir(blob), "app")
+	err = helpers.Unarchive(blob, appDir, format)
+	if err != nil {
+		return nil, InternalError(err, "failed to unpack app sources to temp location")
+	}
+
+	log.V(2).Info("create gitea app repo")
+	g, err := client.Upload(app, appDir, func(revision string) error {
+		return application.UploadRevisionSet(ctx, cluster, app, revision)
+	})
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	log.Info("uploaded app", "org", app.Org, "app", app.Name)
+
+	// Extend url to contain the full repo path
+	g.URL = fmt.Sprintf("%s/%s/%s", g.URL, app.Org, app.Name)
+
+	resp := models.UploadResponse{Git: &g, Builder: application.DetectBuilder(appDir)}
+
+	err = application.UploadRecord(ctx, cluster, app, digest, resp)
+	if err != nil {
+		return nil, InternalError(err)
+	}
+
+	return &resp, nil
+}
+
+// uploadParams returns the app and the digest of a chunked upload, after
+// checking that both are valid
+func uploadParams(r *http.Request) (models.AppRef, string, APIErrors) {
+	ctx := r.Context()
+
+	params := httprouter.ParamsFromContext(ctx)
+	app := models.NewAppRef(params.ByName("app"), params.ByName("org"))
+	digest := params.ByName("digest")
+
+	if err := application.ValidateDigest(digest); err != nil {
+		return app, digest, BadRequest(err)
+	}
+
+	return app, digest, checkUploadApp(ctx, app)
+}
+
+// checkUploadApp checks that the app receiving an upload, and its org,
+// exist
+func checkUploadApp(ctx context.Context, app models.AppRef) APIErrors {
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, app.Org)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return OrgIsNotKnown(app.Org)
+	}
+
+	exists, err = application.Exists(ctx, cluster, app)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	if !exists {
+		return AppIsNotKnown(app.Name)
+	}
+
+	return nil
+}
+
+// uploadLocks holds the chunked uploads which are written to, or finished,
+// by a request. The requests for the same upload are serialized with them,
+// so that a chunk is appended only at the offset it was checked against. The
+// server runs as a single instance, which makes a lock in memory enough.
+var uploadLocks sync.Map
+
+// uploadLock takes the lock of the upload file, if it is free, and returns
+// true then
+func uploadLock(blob string) bool {
+	_, busy := uploadLocks.LoadOrStore(blob, true)
+	return !busy
+}
+
+// uploadUnlock releases the lock of the upload file
+func uploadUnlock(blob string) {
+	uploadLocks.Delete(blob)
+}
+
+// uploadExpiry is the time after which a chunked upload which was not
+// continued is removed
+const uploadExpiry = 24 * time.Hour
+
+// uploadsDir returns the directory holding the chunked uploads
+func uploadsDir() string {
+	return path.Join(os.TempDir(), "epinio-uploads")
+}
+
+// uploadPath returns the file receiving the chunked upload of the app sources
+// with the digest. Each app has a directory of its own, as org and app names
+// cannot contain a slash.
+func uploadPath(app models.AppRef, digest string) string {
+	return path.Join(uploadsDir(), app.Org, app.Name, digest+".tar")
+}
+
+// UploadsExpire removes the chunked uploads which were not continued within
+// the uploadExpiry. The server removes them on start, and whenever an upload
+// starts.
+func UploadsExpire() error {
+	return filepath.Walk(uploadsDir(), func(blob string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || time.Since(info.ModTime()) < uploadExpiry {
+			return nil
+		}
+		return uploadRemove(blob)
+	})
+}
+
+// uploadsReplace removes the chunked uploads of the app, except the one with
+// the digest
+func uploadsReplace(app models.AppRef, digest string) error {
+	blobs, err := filepath.Glob(path.Join(path.Dir(uploadPath(app, digest)), "*.tar"))
+	if err != nil {
+		return err
+	}
+
+	for _, blob := range blobs {
+		if blob == uploadPath(app, digest) {
+			continue
+		}
+		if err := uploadRemove(blob); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uploadRemove removes the upload file, unless a request is busy with it
+func uploadRemove(blob string) error {
+	if !uploadLock(blob) {
+		return nil
+	}
+	defer uploadUnlock(blob)
+
+	err := os.Remove(blob)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// uploadOffset returns the number of bytes received in the upload file
+func uploadOffset(blob string) (int64, error) {
+	info, err := os.Stat(blob)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+// uploadMaxSize returns the maximum size of uploaded sources, in bytes. Zero
+// means no limit.
+func uploadMaxSize() (int64, error) {
+	size := viper.GetString("upload-max-size")
+	if size == "" {
+		return 0, nil
+	}
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return 0, errors.Wrapf(err, "bad upload max size '%s'", size)
+	}
+	return quantity.Value(), nil
+}
+
+// limitReader returns a reader delivering at most one byte more than is left
+// of the maximum size after the received bytes, for the detection of
+// oversized uploads. A maximum of zero means no limit.
+func limitReader(r io.Reader, maxSize, received int64) io.Reader {
+	if maxSize <= 0 {
+		return r
+	}
+	return io.LimitReader(r, maxSize-received+1)
+}
@@ -0,0 +1,195 @@
+package v1
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sort"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/julienschmidt/httprouter"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// UsersController manages the API users and their roles in the orgs.
+// All its actions are restricted to admins, see adminRoutes.
+type UsersController struct {
+}
+
+// Index lists all API users, with their roles.
+func (uc UsersController) Index(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	users, err := auth.ListUsers(ctx, cluster)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	responseData := models.UserResponseList{}
+	for _, user := range users {
+		responseData = append(responseData, models.UserResponse{
+			Username: user.Username,
+			Admin:    user.Admin,
+			Orgs:     user.Orgs,
+		})
+	}
+
+	sort.Slice(responseData, func(i, j int) bool {
+		return responseData[i].Username < responseData[j].Username
+	})
+
+	err = jsonResponse(w, responseData)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Create adds a new API user.
+func (uc UsersController) Create(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var createRequest models.UserCreateRequest
+	err = json.Unmarshal(bodyBytes, &createRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if createRequest.Username == "" {
+		return BadRequest(errors.New("name of user to create not found"))
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = auth.CreateUser(ctx, cluster, createRequest.Username, createRequest.Password, createRequest.Admin)
+	if err != nil {
+		if apierrors.IsAlreadyExists(err) {
+			return UserAlreadyKnown(createRequest.Username)
+		}
+		return InternalError(err)
+	}
+
+	w.WriteHeader(http.StatusCreated)
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// Delete removes the named API user.
+func (uc UsersController) Delete(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	username := params.ByName("user")
+
+	if current := auth.CurrentUser(ctx); current != nil && current.Username == username {
+		return NewBadRequest("cannot delete the current user")
+	}
+
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	err = auth.DeleteUser(ctx, cluster, username)
+	if err != nil {
+		if err == auth.ErrUserNotKnown {
+			return UserIsNotKnown(username)
+		}
+		return InternalError(err)
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
+
+// SetRole grants a role in the org to the named user.
+func (uc UsersController) SetRole(w http.ResponseWriter, r *http.Request) APIErrors {
+	ctx := r.Context()
+	params := httprouter.ParamsFromContext(ctx)
+	username := params.ByName("user")
+	org := params.ByName("org")
+
+	defer r.Body.Close()
+	bodyBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	var roleRequest models.UserRoleRequest
+	err = json.Unmarshal(bodyBytes, &roleRequest)
+	if err != nil {
+		return BadRequest(err)
+	}
+
+	if !auth.ValidOrgRole(roleRequest.Role) {
+		return NewBadRequest("unknown role '"+roleRequest.Role+"'",
+			"use one of "+auth.RoleOrgDeveloper+", "+auth.RoleOrgViewer)
+	}
+
+	return uc.updateRole(w, r, username, org, roleRequest.Role)
+}
+
+// UnsetRole revokes all access to the org from the named user.
+func (uc UsersController) UnsetRole(w http.ResponseWriter, r *http.Request) APIErrors {
+	params := httprouter.ParamsFromContext(r.Context())
+	username := params.ByName("user")
+	org := params.ByName("org")
+
+	return uc.updateRole(w, r, username, org, "")
+}
+
+func (uc UsersController) updateRole(w http.ResponseWriter, r *http.Request, username, org, role string) APIErrors {
+	ctx := r.Context()
+	cluster, err := kubernetes.GetCluster(ctx)
+	if err != nil {
+		return InternalError(err)
+	}
+
+	exists, err := organizations.Exists(ctx, cluster, org)
+	if err != nil {
+		return InternalError(err)
+	}
+	if !exists {
+		return OrgIsNotKnown(org)
+	}
+
+	err = auth.SetOrgRole(ctx, cluster, username, org, role)
+	if err != nil {
+		if err == auth.ErrUserNotKnown {
+			return UserIsNotKnown(username)
+		}
+		return InternalError(err)
+	}
+
+	_, err = w.Write([]byte{})
+	if err != nil {
+		return InternalError(err)
+	}
+
+	return nil
+}
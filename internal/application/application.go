@@ -18,6 +18,7 @@ import (
 	pkgerrors "github.com/pkg/errors"
 	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -228,6 +229,11 @@ func Delete(ctx context.Context, cluster *kubernetes.Cluster, gitea GiteaInterfa
 		return err
 	}
 
+	err = StagingCacheDelete(ctx, cluster, appRef)
+	if err != nil {
+		return err
+	}
+
 	err = cluster.WaitForPodBySelectorMissing(ctx, nil,
 		appRef.Org,
 		fmt.Sprintf("app.kubernetes.io/name=%s", appRef.Name),
@@ -239,7 +245,8 @@ func Delete(ctx context.Context, cluster *kubernetes.Cluster, gitea GiteaInterfa
 	return nil
 }
 
-// Unstage deletes either all PipelineRuns of the named application, or all but the current.
+// Unstage deletes either all PipelineRuns of the named application, or all
+// finished ones but the current. The latter keeps the queued staging runs.
 func Unstage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, stageIDCurrent string) error {
 	cs, err := versioned.NewForConfig(cluster.RestConfig)
 	if err != nil {
@@ -257,7 +264,7 @@ func Unstage(ctx context.Context, cluster *kubernetes.Cluster, appRef models.App
 	}
 
 	for _, pr := range l.Items {
-		if stageIDCurrent != "" && stageIDCurrent == pr.ObjectMeta.Name {
+		if stageIDCurrent != "" && (stageIDCurrent == pr.ObjectMeta.Name || !pr.IsDone()) {
 			continue
 		}
 
@@ -318,9 +325,45 @@ func Logs(ctx context.Context, logChan chan tailer.ContainerLogLine, wg *sync.Wa
 		PodQuery:              regexp.MustCompile(".*"),
 	}
 
+	if follow && stageID != "" {
+		// Report a cancellation of the staging in the stream as well
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			StagingCancelled(watchCtx, logChan, cluster, org, stageID)
+		}()
+
+		err := tailer.StreamLogs(ctx, logChan, wg, config, cluster)
+		stopWatch()
+		return err
+	}
+
 	if follow {
 		return tailer.StreamLogs(ctx, logChan, wg, config, cluster)
 	}
 
 	return tailer.FetchLogs(ctx, logChan, wg, config, cluster)
 }
+
+// ownerOf returns the reference to the application resource, for the
+// resources owned by the app
+func ownerOf(app *unstructured.Unstructured) metav1.OwnerReference {
+	return metav1.OwnerReference{
+		APIVersion: app.GetAPIVersion(),
+		Kind:       app.GetKind(),
+		Name:       app.GetName(),
+		UID:        app.GetUID(),
+	}
+}
+
+// secretApply creates the secret, or updates it if it exists already
+func secretApply(ctx context.Context, cluster *kubernetes.Cluster, secret *corev1.Secret) error {
+	client := cluster.Kubectl.CoreV1().Secrets(secret.Namespace)
+
+	_, err := client.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = client.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	return err
+}
@@ -0,0 +1,156 @@
+package application
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"regexp"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// AutoDeployAnnotation is the annotation of the application resource holding
+// the JSON encoded auto-deploy mode of the app, see AutoDeployHook. The
+// secret of the webhook is kept in a secret of its own, see
+// AutoDeploySecretName.
+const AutoDeployAnnotation = "epinio.suse.org/auto-deploy"
+
+// autoDeploySecretKey is the key of the webhook secret in the secret named
+// by AutoDeploySecretName
+const autoDeploySecretKey = "secret"
+
+var branchRegexp = regexp.MustCompile(`^[A-Za-z0-9._/-]+$`)
+
+// AutoDeployHook is the auto-deploy mode of an app, i.e. the branch which is
+// deployed, and the gitea webhook reporting the pushes to it. Gitea signs
+// the payloads of the webhook with the secret.
+type AutoDeployHook struct {
+	Branch string `json:"branch"`
+	HookID int64  `json:"hook_id"`
+	Secret string `json:"-"`
+}
+
+// AutoDeploySecretName returns the name of the secret holding the webhook
+// secret of the app, in the namespace of its org
+func AutoDeploySecretName(appRef models.AppRef) string {
+	return appRef.Name + ".auto-deploy"
+}
+
+// AutoDeploy returns the auto-deploy mode of the app, or nil when the app is
+// not deployed automatically. The secret of the webhook is empty when it is
+// missing.
+func AutoDeploy(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*AutoDeployHook, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, err := autoDeployOf(app)
+	if err != nil || hook == nil {
+		return hook, err
+	}
+
+	secret, err := cluster.GetSecret(ctx, appRef.Org, AutoDeploySecretName(appRef))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return hook, nil
+		}
+		return nil, err
+	}
+	if secret.GetLabels()["app.kubernetes.io/name"] == appRef.Name {
+		hook.Secret = string(secret.Data[autoDeploySecretKey])
+	}
+
+	return hook, nil
+}
+
+// AutoDeploySet changes the auto-deploy mode of the app. Nil turns it off.
+func AutoDeploySet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, hook *AutoDeployHook) error {
+	name := AutoDeploySecretName(appRef)
+
+	if hook == nil {
+		err := updateAnnotation(ctx, cluster, appRef, AutoDeployAnnotation, func(*unstructured.Unstructured) (string, error) {
+			return "", nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Delete(ctx, name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		return nil
+	}
+
+	return updateAnnotation(ctx, cluster, appRef, AutoDeployAnnotation, func(app *unstructured.Unstructured) (string, error) {
+		err := secretApply(ctx, cluster, &v1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: appRef.Org,
+				Labels: map[string]string{
+					"app.kubernetes.io/name":       appRef.Name,
+					"app.kubernetes.io/part-of":    appRef.Org,
+					"app.kubernetes.io/managed-by": "epinio",
+					"app.kubernetes.io/component":  "auto-deploy",
+				},
+				OwnerReferences: []metav1.OwnerReference{ownerOf(app)},
+			},
+			Data: map[string][]byte{autoDeploySecretKey: []byte(hook.Secret)},
+		})
+		if err != nil {
+			return "", err
+		}
+
+		data, err := json.Marshal(hook)
+		return string(data), err
+	})
+}
+
+// ValidateBranch checks that the branch is a plausible git branch name
+func ValidateBranch(branch string) error {
+	if !branchRegexp.MatchString(branch) ||
+		strings.HasPrefix(branch, "-") ||
+		strings.HasPrefix(branch, "/") ||
+		strings.HasSuffix(branch, "/") ||
+		strings.Contains(branch, "..") {
+		return errors.Errorf("bad branch name '%s'", branch)
+	}
+	return nil
+}
+
+// VerifyWebhookSignature returns true if the signature is the hex encoded
+// HMAC-SHA256 of the payload with the secret, as sent by gitea. The HMAC is
+// computed, and compared in constant time, whatever the secret and the
+// signature, so that the time taken does not tell whether there is a
+// secret at all.
+func VerifyWebhookSignature(secret string, payload []byte, signature string) bool {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	expected := []byte(hex.EncodeToString(mac.Sum(nil)))
+
+	match := hmac.Equal(expected, []byte(strings.ToLower(signature)))
+	return match && secret != ""
+}
+
+func autoDeployOf(app *unstructured.Unstructured) (*AutoDeployHook, error) {
+	data, ok := app.GetAnnotations()[AutoDeployAnnotation]
+	if !ok || data == "" {
+		return nil, nil
+	}
+
+	hook := AutoDeployHook{}
+	if err := json.Unmarshal([]byte(data), &hook); err != nil {
+		return nil, errors.Wrap(err, "bad auto-deploy settings")
+	}
+	return &hook, nil
+}
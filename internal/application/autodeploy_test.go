@@ -0,0 +1,66 @@
+package application_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AutoDeploy", func() {
+	Describe("ValidateBranch", func() {
+		It("accepts branch names", func() {
+			for _, branch := range []string{"main", "release-1.2", "feature/auto_deploy"} {
+				Expect(application.ValidateBranch(branch)).To(Succeed(), branch)
+			}
+		})
+
+		It("rejects bad branch names", func() {
+			for _, branch := range []string{"", "-main", "/main", "main/", "a..b", "main branch", "main~1"} {
+				Expect(application.ValidateBranch(branch)).To(
+					MatchError(ContainSubstring("bad branch name")), branch)
+			}
+		})
+	})
+
+	Describe("AutoDeployHook", func() {
+		It("keeps the secret out of the application resource", func() {
+			data, err := json.Marshal(application.AutoDeployHook{Branch: "main", HookID: 7, Secret: "s3cret"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(data)).To(Equal(`{"branch":"main","hook_id":7}`))
+		})
+	})
+
+	Describe("VerifyWebhookSignature", func() {
+		payload := []byte(`{"ref":"refs/heads/main"}`)
+
+		sign := func(secret string, payload []byte) string {
+			mac := hmac.New(sha256.New, []byte(secret))
+			mac.Write(payload)
+			return hex.EncodeToString(mac.Sum(nil))
+		}
+
+		It("accepts payloads signed with the secret", func() {
+			Expect(application.VerifyWebhookSignature("s3cret", payload, sign("s3cret", payload))).To(BeTrue())
+		})
+
+		It("rejects payloads signed otherwise", func() {
+			Expect(application.VerifyWebhookSignature("s3cret", payload, sign("other", payload))).To(BeFalse())
+			Expect(application.VerifyWebhookSignature("s3cret", []byte(`{}`), sign("s3cret", payload))).To(BeFalse())
+		})
+
+		It("rejects missing and malformed signatures", func() {
+			Expect(application.VerifyWebhookSignature("s3cret", payload, "")).To(BeFalse())
+			Expect(application.VerifyWebhookSignature("s3cret", payload, "not hex")).To(BeFalse())
+		})
+
+		It("rejects everything without a secret", func() {
+			Expect(application.VerifyWebhookSignature("", payload, sign("", payload))).To(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,137 @@
+package application
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	autoscalingv1 "k8s.io/api/autoscaling/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+)
+
+// ErrNoCPURequest is returned when autoscaling an app without a cpu request.
+// The target utilization is relative to the request.
+var ErrNoCPURequest = errors.New("application has no cpu request")
+
+// Autoscaler returns the autoscaling of the app, or nil when the app is not
+// autoscaled.
+func Autoscaler(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (*models.Autoscale, error) {
+	hpa, err := cluster.Kubectl.AutoscalingV1().HorizontalPodAutoscalers(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	autoscale := &models.Autoscale{
+		Max:               hpa.Spec.MaxReplicas,
+		CurrentInstances:  hpa.Status.CurrentReplicas,
+		DesiredInstances:  hpa.Status.DesiredReplicas,
+		CurrentCPUPercent: hpa.Status.CurrentCPUUtilizationPercentage,
+	}
+	if hpa.Spec.MinReplicas != nil {
+		autoscale.Min = *hpa.Spec.MinReplicas
+	}
+	if hpa.Spec.TargetCPUUtilizationPercentage != nil {
+		autoscale.CPUPercent = *hpa.Spec.TargetCPUUtilizationPercentage
+	}
+
+	return autoscale, nil
+}
+
+// Autoscale creates or updates the horizontal pod autoscaler of the app. It
+// is owned by the application resource, and removed with it. The app needs
+// a cpu request, see ErrNoCPURequest.
+func Autoscale(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, autoscale models.Autoscale) error {
+	if err := ValidateAutoscale(autoscale); err != nil {
+		return err
+	}
+
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return err
+	}
+
+	settings, err := settingsOf(app)
+	if err != nil {
+		return err
+	}
+	if settings.Resources.CPURequest == "" {
+		return ErrNoCPURequest
+	}
+
+	client := cluster.Kubectl.AutoscalingV1().HorizontalPodAutoscalers(appRef.Org)
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		hpa, err := client.Get(ctx, appRef.Name, metav1.GetOptions{})
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return err
+			}
+
+			hpa = &autoscalingv1.HorizontalPodAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      appRef.Name,
+					Namespace: appRef.Org,
+					Labels: map[string]string{
+						"app.kubernetes.io/component":  "application",
+						"app.kubernetes.io/managed-by": "epinio",
+						"app.kubernetes.io/name":       appRef.Name,
+						"app.kubernetes.io/part-of":    appRef.Org,
+					},
+					OwnerReferences: []metav1.OwnerReference{
+						{
+							APIVersion: app.GetAPIVersion(),
+							Kind:       app.GetKind(),
+							Name:       app.GetName(),
+							UID:        app.GetUID(),
+						},
+					},
+				},
+				Spec: autoscalingv1.HorizontalPodAutoscalerSpec{
+					ScaleTargetRef: autoscalingv1.CrossVersionObjectReference{
+						APIVersion: "apps/v1",
+						Kind:       "Deployment",
+						Name:       appRef.Name,
+					},
+				},
+			}
+		}
+
+		hpa.Spec.MinReplicas = &autoscale.Min
+		hpa.Spec.MaxReplicas = autoscale.Max
+		hpa.Spec.TargetCPUUtilizationPercentage = &autoscale.CPUPercent
+
+		if hpa.ResourceVersion == "" {
+			_, err = client.Create(ctx, hpa, metav1.CreateOptions{})
+		} else {
+			_, err = client.Update(ctx, hpa, metav1.UpdateOptions{})
+		}
+		return err
+	})
+}
+
+// AutoscaleDelete removes the horizontal pod autoscaler of the app. The app
+// keeps its current number of instances.
+func AutoscaleDelete(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) error {
+	return cluster.Kubectl.AutoscalingV1().HorizontalPodAutoscalers(appRef.Org).Delete(ctx, appRef.Name, metav1.DeleteOptions{})
+}
+
+// ValidateAutoscale checks the bounds and the target of the autoscaling
+func ValidateAutoscale(autoscale models.Autoscale) error {
+	if autoscale.Min < 1 {
+		return fmt.Errorf("minimum instances should be at least 1")
+	}
+	if autoscale.Max < autoscale.Min {
+		return fmt.Errorf("maximum instances should not be below the minimum")
+	}
+	if autoscale.CPUPercent < 1 {
+		return fmt.Errorf("cpu percentage should be at least 1")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ValidateAutoscale", func() {
+	It("accepts valid bounds", func() {
+		Expect(application.ValidateAutoscale(models.Autoscale{Min: 1, Max: 1, CPUPercent: 50})).To(Succeed())
+	})
+
+	It("rejects a minimum below one", func() {
+		Expect(application.ValidateAutoscale(models.Autoscale{Min: 0, Max: 3, CPUPercent: 50})).
+			To(MatchError("minimum instances should be at least 1"))
+	})
+
+	It("rejects a maximum below the minimum", func() {
+		Expect(application.ValidateAutoscale(models.Autoscale{Min: 3, Max: 2, CPUPercent: 50})).
+			To(MatchError("maximum instances should not be below the minimum"))
+	})
+
+	It("rejects a missing cpu target", func() {
+		Expect(application.ValidateAutoscale(models.Autoscale{Min: 1, Max: 2})).
+			To(MatchError("cpu percentage should be at least 1"))
+	})
+})
@@ -0,0 +1,78 @@
+package application
+
+import (
+	"context"
+	"os"
+	"path"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// BuilderAnnotation is the annotation of the application resource holding
+// the builder staging it
+const BuilderAnnotation = "epinio.suse.org/builder"
+
+// Builder returns the builder of the app, see models.BuilderAuto. Apps
+// detect their builder from their sources by default.
+func Builder(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (string, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return "", err
+	}
+
+	return builderOf(app), nil
+}
+
+// BuilderSet changes the builder of the app
+func BuilderSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, builder string) error {
+	if err := ValidateBuilder(builder); err != nil {
+		return err
+	}
+
+	return updateAnnotation(ctx, cluster, appRef, BuilderAnnotation, func(*unstructured.Unstructured) (string, error) {
+		return builder, nil
+	})
+}
+
+// ValidateBuilder checks that the builder is a known one
+func ValidateBuilder(builder string) error {
+	switch builder {
+	case models.BuilderAuto, models.BuilderBuildpacks, models.BuilderDockerfile:
+		return nil
+	}
+	return errors.Errorf("unknown builder '%s', expected one of %s, %s, %s",
+		builder, models.BuilderAuto, models.BuilderBuildpacks, models.BuilderDockerfile)
+}
+
+// DetectBuilder returns the builder for the app sources in dir. Sources with
+// a Dockerfile are built from it, all others by the buildpacks.
+func DetectBuilder(dir string) string {
+	info, err := os.Stat(path.Join(dir, "Dockerfile"))
+	if err == nil && info.Mode().IsRegular() {
+		return models.BuilderDockerfile
+	}
+	return models.BuilderBuildpacks
+}
+
+// StagingBuilder returns the builder staging an app with the given builder.
+// An app detecting its builder uses the detected one, if any, and the
+// buildpacks else, e.g. for sources which were not uploaded.
+func StagingBuilder(builder, detected string) string {
+	if builder != models.BuilderAuto && builder != "" {
+		return builder
+	}
+	if detected != "" {
+		return detected
+	}
+	return models.BuilderBuildpacks
+}
+
+func builderOf(app *unstructured.Unstructured) string {
+	if builder := app.GetAnnotations()[BuilderAnnotation]; builder != "" {
+		return builder
+	}
+	return models.BuilderAuto
+}
@@ -0,0 +1,54 @@
+package application_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Builder", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "epinio-builder")
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("builds sources with a Dockerfile from it", func() {
+		err := ioutil.WriteFile(path.Join(dir, "Dockerfile"), []byte("FROM alpine\n"), 0644)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(application.DetectBuilder(dir)).To(Equal(models.BuilderDockerfile))
+	})
+
+	It("builds other sources with the buildpacks", func() {
+		Expect(application.DetectBuilder(dir)).To(Equal(models.BuilderBuildpacks))
+
+		err := os.Mkdir(path.Join(dir, "Dockerfile"), 0755)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(application.DetectBuilder(dir)).To(Equal(models.BuilderBuildpacks))
+	})
+
+	It("stages with the builder of the app, or the detected one", func() {
+		Expect(application.StagingBuilder(models.BuilderBuildpacks, models.BuilderDockerfile)).To(Equal(models.BuilderBuildpacks))
+		Expect(application.StagingBuilder(models.BuilderAuto, models.BuilderDockerfile)).To(Equal(models.BuilderDockerfile))
+		Expect(application.StagingBuilder(models.BuilderAuto, "")).To(Equal(models.BuilderBuildpacks))
+	})
+
+	It("knows the builders", func() {
+		Expect(application.ValidateBuilder("auto")).To(Succeed())
+		Expect(application.ValidateBuilder("buildpacks")).To(Succeed())
+		Expect(application.ValidateBuilder("dockerfile")).To(Succeed())
+		Expect(application.ValidateBuilder("make")).To(MatchError(ContainSubstring("unknown builder 'make'")))
+	})
+})
@@ -0,0 +1,107 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/organizations"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// BuildpacksAnnotation is the annotation of the application resource holding
+// the JSON encoded buildpacks settings of the app
+const BuildpacksAnnotation = "epinio.suse.org/buildpacks"
+
+// DefaultBuilderImage is the builder image of apps and orgs which do not pin
+// one
+const DefaultBuilderImage = "paketobuildpacks/builder:full"
+
+// Buildpacks returns the buildpacks settings of the app itself, without the
+// defaults of its org
+func Buildpacks(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.Buildpacks, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return models.Buildpacks{}, err
+	}
+
+	return buildpacksOf(app)
+}
+
+// StagingBuildpacks returns the buildpacks settings in effect for the app.
+// These are its own settings, then the defaults of its org, and then the
+// default builder image.
+func StagingBuildpacks(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.Buildpacks, error) {
+	buildpacks, err := Buildpacks(ctx, cluster, appRef)
+	if err != nil {
+		return buildpacks, err
+	}
+
+	defaults, err := organizations.Buildpacks(ctx, cluster, appRef.Org)
+	if err != nil {
+		return buildpacks, err
+	}
+
+	return buildpacks.Over(defaults).Over(models.Buildpacks{BuilderImage: DefaultBuilderImage}), nil
+}
+
+// BuildpacksUpdate merges the update into the buildpacks settings of the app,
+// see models.Buildpacks.Merge, and returns the result
+func BuildpacksUpdate(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, update models.Buildpacks) (models.Buildpacks, error) {
+	var buildpacks models.Buildpacks
+
+	if err := ValidateBuildpacks(update); err != nil {
+		return buildpacks, err
+	}
+
+	err := updateAnnotation(ctx, cluster, appRef, BuildpacksAnnotation, func(app *unstructured.Unstructured) (string, error) {
+		current, err := buildpacksOf(app)
+		if err != nil {
+			return "", err
+		}
+
+		buildpacks = current.Merge(update)
+
+		data, err := json.Marshal(buildpacks)
+		return string(data), err
+	})
+
+	return buildpacks, err
+}
+
+// ValidateBuildpacks checks the builder image and the buildpacks of the
+// settings. They are references, and must not contain whitespace.
+func ValidateBuildpacks(buildpacks models.Buildpacks) error {
+	if strings.ContainsAny(buildpacks.BuilderImage, " \t\n") {
+		return errors.Errorf("bad builder image '%s'", buildpacks.BuilderImage)
+	}
+
+	for _, buildpack := range buildpacks.Buildpacks {
+		if buildpack == "" || strings.ContainsAny(buildpack, " \t\n") {
+			return errors.Errorf("bad buildpack '%s'", buildpack)
+		}
+		if buildpack == models.BuildpacksDefault && len(buildpacks.Buildpacks) > 1 {
+			return errors.Errorf("buildpack '%s' resets the buildpacks, and cannot be given with others",
+				models.BuildpacksDefault)
+		}
+	}
+
+	return nil
+}
+
+func buildpacksOf(app *unstructured.Unstructured) (models.Buildpacks, error) {
+	buildpacks := models.Buildpacks{}
+
+	data, ok := app.GetAnnotations()[BuildpacksAnnotation]
+	if !ok || data == "" {
+		return buildpacks, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &buildpacks); err != nil {
+		return buildpacks, errors.Wrap(err, "bad buildpacks settings")
+	}
+	return buildpacks, nil
+}
@@ -0,0 +1,46 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Buildpacks", func() {
+	current := models.Buildpacks{
+		BuilderImage: "paketobuildpacks/builder:base",
+		Buildpacks:   []string{"paketo-buildpacks/java@5.9.1"},
+	}
+
+	It("keeps the current settings for empty fields", func() {
+		Expect(current.Merge(models.Buildpacks{})).To(Equal(current))
+	})
+
+	It("overrides and resets the given fields", func() {
+		merged := current.Merge(models.Buildpacks{Buildpacks: []string{"paketo-buildpacks/nodejs"}})
+		Expect(merged.BuilderImage).To(Equal("paketobuildpacks/builder:base"))
+		Expect(merged.Buildpacks).To(Equal([]string{"paketo-buildpacks/nodejs"}))
+
+		merged = current.Merge(models.Buildpacks{BuilderImage: "default", Buildpacks: []string{"default"}})
+		Expect(merged).To(Equal(models.Buildpacks{}))
+	})
+
+	It("takes the empty fields from the defaults", func() {
+		app := models.Buildpacks{Buildpacks: []string{"paketo-buildpacks/nodejs"}}
+		result := app.Over(current)
+		Expect(result.BuilderImage).To(Equal("paketobuildpacks/builder:base"))
+		Expect(result.Buildpacks).To(Equal([]string{"paketo-buildpacks/nodejs"}))
+	})
+
+	It("validates builder image and buildpacks", func() {
+		Expect(application.ValidateBuildpacks(current)).To(Succeed())
+		Expect(application.ValidateBuildpacks(models.Buildpacks{BuilderImage: "my builder"})).
+			To(MatchError(ContainSubstring("bad builder image 'my builder'")))
+		Expect(application.ValidateBuildpacks(models.Buildpacks{Buildpacks: []string{""}})).
+			To(MatchError(ContainSubstring("bad buildpack ''")))
+		Expect(application.ValidateBuildpacks(models.Buildpacks{Buildpacks: []string{"default", "paketo-buildpacks/go"}})).
+			To(MatchError(ContainSubstring("cannot be given with others")))
+	})
+})
@@ -0,0 +1,105 @@
+package application
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/names"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// StagingCacheName returns the name of the claim holding the build cache of
+// the app, in the staging namespace. Org names contain no dots, which keeps
+// the names of different orgs apart.
+func StagingCacheName(appRef models.AppRef) string {
+	return names.GenerateDNS1123SubDomainName("cache", appRef.Org, appRef.Name)
+}
+
+// StagingCache returns the name of the claim holding the build cache of the
+// app. The claim is created with the given size, if missing. The cache is
+// reused by all stagings of the app, and kept until the app is deleted. A
+// size of zero disables the cache, and returns no name.
+func StagingCache(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, size string) (string, error) {
+	name := StagingCacheName(appRef)
+
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		return "", errors.Wrapf(err, "bad build cache size '%s'", size)
+	}
+	if quantity.IsZero() {
+		return "", nil
+	}
+
+	_, err = cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).Create(ctx,
+		&corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: name,
+				Labels: map[string]string{
+					"app.kubernetes.io/name":       appRef.Name,
+					"app.kubernetes.io/part-of":    appRef.Org,
+					"app.kubernetes.io/managed-by": "epinio",
+					"app.kubernetes.io/component":  "staging-cache",
+				},
+			},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceStorage: quantity,
+				}},
+			},
+		}, metav1.CreateOptions{})
+	if err == nil {
+		return name, nil
+	}
+	if !apierrors.IsAlreadyExists(err) {
+		return "", err
+	}
+
+	// Reuse the existing claim only if it is the cache of this app
+	claim, err := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace).
+		Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if !stagingCacheOf(claim, appRef) {
+		return "", errors.Errorf("build cache claim '%s' belongs to another application", name)
+	}
+
+	return name, nil
+}
+
+// StagingCacheDelete removes the build cache of the app, if any
+func StagingCacheDelete(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) error {
+	client := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace)
+
+	claim, err := client.Get(ctx, StagingCacheName(appRef), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		return err
+	}
+	if !stagingCacheOf(claim, appRef) {
+		return nil
+	}
+
+	err = client.Delete(ctx, claim.GetName(), metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// stagingCacheOf returns true if the claim is the build cache of the app
+func stagingCacheOf(claim *corev1.PersistentVolumeClaim, appRef models.AppRef) bool {
+	labels := claim.GetLabels()
+	return labels["app.kubernetes.io/name"] == appRef.Name &&
+		labels["app.kubernetes.io/part-of"] == appRef.Org &&
+		labels["app.kubernetes.io/component"] == "staging-cache"
+}
@@ -0,0 +1,35 @@
+package application_test
+
+import (
+	"context"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StagingCache", func() {
+	appRef := models.NewAppRef("sample", "workspace")
+
+	It("names the cache after the app", func() {
+		Expect(application.StagingCacheName(appRef)).To(Equal("cache.workspace.sample"))
+	})
+
+	It("keeps the caches of different orgs apart", func() {
+		Expect(application.StagingCacheName(models.NewAppRef("b-c", "a"))).ToNot(
+			Equal(application.StagingCacheName(models.NewAppRef("c", "a-b"))))
+	})
+
+	It("provides no cache of size zero", func() {
+		name, err := application.StagingCache(context.Background(), nil, appRef, "0")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(name).To(BeEmpty())
+	})
+
+	It("rejects a bad size", func() {
+		_, err := application.StagingCache(context.Background(), nil, appRef, "lots")
+		Expect(err).To(MatchError(ContainSubstring("bad build cache size 'lots'")))
+	})
+})
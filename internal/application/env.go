@@ -2,58 +2,170 @@ package application
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
 	v1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/util/retry"
 )
 
+// EnvScopesAnnotation is the annotation of the environment secret holding
+// the JSON encoded scopes of the variables not passed to both the staging
+// and the running app
+const EnvScopesAnnotation = "epinio.suse.org/env-scopes"
+
 func Environment(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.EnvVariableList, error) {
 	evSecret, err := envLoad(ctx, cluster, appRef)
 	if err != nil {
 		return nil, err
 	}
 
+	scopes, err := envScopesOf(evSecret)
+	if err != nil {
+		return nil, err
+	}
+
 	result := models.EnvVariableList{}
 	for name, value := range evSecret.Data {
+		scope, ok := scopes[name]
+		if !ok {
+			scope = models.EnvScopeBoth
+		}
 		result = append(result, models.EnvVariable{
 			Name:  name,
 			Value: string(value),
+			Scope: scope,
 		})
 	}
 
 	return result, nil
 }
 
+// EnvironmentSet adds or changes the assigned variables of the app. A
+// variable assigned without scope keeps its current scope, new variables
+// default to both.
 func EnvironmentSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, assignments models.EnvVariableList) error {
-	return envUpdate(ctx, cluster, appRef, func(evSecret *v1.Secret) {
+	for _, ev := range assignments {
+		if err := ValidateEnvScope(ev.Scope); err != nil {
+			return err
+		}
+	}
+
+	return envUpdate(ctx, cluster, appRef, func(evSecret *v1.Secret, scopes map[string]string) {
 		for _, ev := range assignments {
 			evSecret.Data[ev.Name] = []byte(ev.Value)
+
+			switch ev.Scope {
+			case "":
+				// Keep the current scope
+			case models.EnvScopeBoth:
+				delete(scopes, ev.Name)
+			default:
+				scopes[ev.Name] = ev.Scope
+			}
 		}
 	})
 }
 
 func EnvironmentUnset(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, varName string) error {
-	return envUpdate(ctx, cluster, appRef, func(evSecret *v1.Secret) {
+	return envUpdate(ctx, cluster, appRef, func(evSecret *v1.Secret, scopes map[string]string) {
 		delete(evSecret.Data, varName)
+		delete(scopes, varName)
+	})
+}
+
+// ValidateEnvScope checks that the scope is a known one. The empty scope is
+// accepted too.
+func ValidateEnvScope(scope string) error {
+	switch scope {
+	case "", models.EnvScopeBoth, models.EnvScopeBuild, models.EnvScopeRuntime:
+		return nil
+	}
+	return errors.Errorf("unknown scope '%s', expected one of %s, %s, %s",
+		scope, models.EnvScopeBoth, models.EnvScopeBuild, models.EnvScopeRuntime)
+}
+
+// EnvironmentReplace replaces the whole environment of the app with the
+// assignments. Unlike EnvironmentSet it does not restart the workload, the
+// caller is expected to deploy it with the new environment.
+func EnvironmentReplace(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, assignments models.EnvVariableList) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		evSecret, err := envLoad(ctx, cluster, appRef)
+		if err != nil {
+			return err
+		}
+
+		evSecret.Data = make(map[string][]byte)
+		scopes := map[string]string{}
+		for _, ev := range assignments {
+			evSecret.Data[ev.Name] = []byte(ev.Value)
+			if ev.Scope != "" && ev.Scope != models.EnvScopeBoth {
+				scopes[ev.Name] = ev.Scope
+			}
+		}
+
+		if err := setEnvScopes(evSecret, scopes); err != nil {
+			return err
+		}
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
+			ctx, evSecret, metav1.UpdateOptions{})
+		return err
 	})
 }
 
-func envNames(ev *v1.Secret) []string {
-	names := make([]string, len(ev.Data))
-	i := 0
+// envNames returns the names of the variables passed to the running app
+func envNames(ev *v1.Secret, scopes map[string]string) []string {
+	names := []string{}
 	for k := range ev.Data {
-		names[i] = k
-		i++
+		if scopes[k] == models.EnvScopeBuild {
+			continue
+		}
+		names = append(names, k)
 	}
 	return names
 }
 
+func envScopesOf(evSecret *v1.Secret) (map[string]string, error) {
+	scopes := map[string]string{}
+
+	data, ok := evSecret.GetAnnotations()[EnvScopesAnnotation]
+	if !ok || data == "" {
+		return scopes, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &scopes); err != nil {
+		return scopes, errors.Wrap(err, "bad environment scopes")
+	}
+	return scopes, nil
+}
+
+func setEnvScopes(evSecret *v1.Secret, scopes map[string]string) error {
+	annotations := evSecret.GetAnnotations()
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+
+	if len(scopes) == 0 {
+		delete(annotations, EnvScopesAnnotation)
+	} else {
+		data, err := json.Marshal(scopes)
+		if err != nil {
+			return err
+		}
+		annotations[EnvScopesAnnotation] = string(data)
+	}
+
+	evSecret.SetAnnotations(annotations)
+	return nil
+}
+
 func envUpdate(ctx context.Context, cluster *kubernetes.Cluster,
-	appRef models.AppRef, modifyEnvironment func(*v1.Secret)) error {
+	appRef models.AppRef, modifyEnvironment func(*v1.Secret, map[string]string)) error {
 
 	varNames := []string{}
 
@@ -67,14 +179,23 @@ func envUpdate(ctx context.Context, cluster *kubernetes.Cluster,
 			evSecret.Data = make(map[string][]byte)
 		}
 
-		modifyEnvironment(evSecret)
+		scopes, err := envScopesOf(evSecret)
+		if err != nil {
+			return err
+		}
+
+		modifyEnvironment(evSecret, scopes)
+
+		if err := setEnvScopes(evSecret, scopes); err != nil {
+			return err
+		}
 
 		_, err = cluster.Kubectl.CoreV1().Secrets(appRef.Org).Update(
 			ctx, evSecret, metav1.UpdateOptions{})
 
-		// Pass current set of environment variables out for
-		// use by the worload restart
-		varNames = envNames(evSecret)
+		// Pass current set of runtime environment variables out
+		// for use by the worload restart
+		varNames = envNames(evSecret, scopes)
 
 		return err
 	})
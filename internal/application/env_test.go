@@ -0,0 +1,38 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Environment", func() {
+	environment := models.EnvVariableList{
+		{Name: "TOKEN", Value: "build", Scope: models.EnvScopeBuild},
+		{Name: "SECRET", Value: "runtime", Scope: models.EnvScopeRuntime},
+		{Name: "MODE", Value: "both", Scope: models.EnvScopeBoth},
+		{Name: "LEGACY", Value: "none"},
+	}
+
+	It("stages with the build variables only", func() {
+		Expect(environment.StagingEnvArray()).To(Equal([]string{"TOKEN=build", "MODE=both", "LEGACY=none"}))
+	})
+
+	It("runs with the runtime variables only", func() {
+		names := []string{}
+		for _, ev := range environment.ToEnvVarArray(models.NewAppRef("app", "org")) {
+			names = append(names, ev.Name)
+		}
+		Expect(names).To(Equal([]string{"PORT", "SECRET", "MODE", "LEGACY"}))
+	})
+
+	It("knows the scopes", func() {
+		Expect(application.ValidateEnvScope("")).To(Succeed())
+		Expect(application.ValidateEnvScope("both")).To(Succeed())
+		Expect(application.ValidateEnvScope("build")).To(Succeed())
+		Expect(application.ValidateEnvScope("runtime")).To(Succeed())
+		Expect(application.ValidateEnvScope("test")).To(MatchError(ContainSubstring("unknown scope 'test'")))
+	})
+})
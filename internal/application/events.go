@@ -0,0 +1,180 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Events returns the kubernetes events about the resources of the app,
+// oldest first. These are its deployment, replica sets, pods, ingress and
+// route certificates in the namespace of the org, and the pipeline runs, task
+// runs and pods staging it.
+func Events(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.EventList, error) {
+	appObjects, err := appObjects(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+	stagingObjects, err := stagingObjects(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	events, err := namespaceEvents(ctx, cluster, appRef.Org, appObjects)
+	if err != nil {
+		return nil, err
+	}
+	staging, err := namespaceEvents(ctx, cluster, deployments.TektonStagingNamespace, stagingObjects)
+	if err != nil {
+		return nil, err
+	}
+	events = append(events, staging...)
+
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Time.Before(events[j].Time)
+	})
+
+	return events, nil
+}
+
+// EventOf returns the app event for the kubernetes event. Events reported
+// as a series carry their count and time in the series.
+func EventOf(event *corev1.Event) models.Event {
+	result := models.Event{
+		UID:     string(event.UID),
+		Time:    event.LastTimestamp.Time,
+		Type:    event.Type,
+		Reason:  event.Reason,
+		Object:  eventObject(event.InvolvedObject.Kind, event.InvolvedObject.Name),
+		Message: event.Message,
+		Count:   event.Count,
+	}
+
+	if event.Series != nil {
+		result.Time = event.Series.LastObservedTime.Time
+		result.Count = event.Series.Count
+	}
+	if result.Time.IsZero() {
+		result.Time = event.EventTime.Time
+	}
+	if result.Time.IsZero() {
+		result.Time = event.CreationTimestamp.Time
+	}
+	if result.Count == 0 {
+		result.Count = 1
+	}
+
+	return result
+}
+
+// appObjects returns the resources of the app in the namespace of the org
+func appObjects(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (map[string]bool, error) {
+	objects := map[string]bool{
+		eventObject("Deployment", appRef.Name): true,
+		eventObject("Ingress", appRef.Name):    true,
+	}
+
+	replicaSets, err := cluster.Kubectl.AppsV1().ReplicaSets(appRef.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/component=application,app.kubernetes.io/name=%s", appRef.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, replicaSet := range replicaSets.Items {
+		objects[eventObject("ReplicaSet", replicaSet.Name)] = true
+	}
+
+	pods, err := NewWorkload(cluster, appRef).Pods(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods {
+		objects[eventObject("Pod", pod.Name)] = true
+	}
+
+	// The cert-manager certificates are named after the routes
+	routes, err := Routes(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+	for _, route := range routes {
+		objects[eventObject("Certificate", route)] = true
+	}
+
+	return objects, nil
+}
+
+// stagingObjects returns the resources staging the app. Tekton passes the
+// labels of a pipeline run on to its task runs and their pods.
+func stagingObjects(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (map[string]bool, error) {
+	options := metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s",
+			appRef.Name, appRef.Org),
+	}
+	objects := map[string]bool{}
+
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	pipelineRuns, err := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, pr := range pipelineRuns.Items {
+		objects[eventObject("PipelineRun", pr.Name)] = true
+	}
+
+	taskRuns, err := cs.TektonV1beta1().TaskRuns(deployments.TektonStagingNamespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, tr := range taskRuns.Items {
+		objects[eventObject("TaskRun", tr.Name)] = true
+	}
+
+	pods, err := cluster.Kubectl.CoreV1().Pods(deployments.TektonStagingNamespace).List(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		objects[eventObject("Pod", pod.Name)] = true
+	}
+
+	return objects, nil
+}
+
+// namespaceEvents returns the events of the namespace about the objects
+func namespaceEvents(ctx context.Context, cluster *kubernetes.Cluster, namespace string, objects map[string]bool) (models.EventList, error) {
+	events := models.EventList{}
+	if len(objects) == 0 {
+		return events, nil
+	}
+
+	list, err := cluster.Kubectl.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range list.Items {
+		event := &list.Items[i]
+		if objects[eventObject(event.InvolvedObject.Kind, event.InvolvedObject.Name)] {
+			events = append(events, EventOf(event))
+		}
+	}
+
+	return events, nil
+}
+
+func eventObject(kind, name string) string {
+	return kind + "/" + name
+}
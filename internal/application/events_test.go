@@ -0,0 +1,62 @@
+package application_test
+
+import (
+	"time"
+
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("EventOf", func() {
+	var event *corev1.Event
+	created := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	last := created.Add(5 * time.Minute)
+
+	BeforeEach(func() {
+		event = &corev1.Event{
+			ObjectMeta: metav1.ObjectMeta{
+				UID:               "1f2e",
+				CreationTimestamp: metav1.NewTime(created),
+			},
+			InvolvedObject: corev1.ObjectReference{Kind: "Pod", Name: "app-6b9f7c-x2x4z"},
+			Type:           "Warning",
+			Reason:         "BackOff",
+			Message:        "Back-off restarting failed container",
+			Count:          3,
+			LastTimestamp:  metav1.NewTime(last),
+		}
+	})
+
+	It("reports the event about the object", func() {
+		result := application.EventOf(event)
+		Expect(result.UID).To(Equal("1f2e"))
+		Expect(result.Object).To(Equal("Pod/app-6b9f7c-x2x4z"))
+		Expect(result.Type).To(Equal("Warning"))
+		Expect(result.Reason).To(Equal("BackOff"))
+		Expect(result.Count).To(Equal(int32(3)))
+		Expect(result.Time).To(BeTemporally("==", last))
+	})
+
+	It("takes count and time of an event series", func() {
+		event.Count = 0
+		event.LastTimestamp = metav1.Time{}
+		event.Series = &corev1.EventSeries{Count: 7, LastObservedTime: metav1.NewMicroTime(last)}
+
+		result := application.EventOf(event)
+		Expect(result.Count).To(Equal(int32(7)))
+		Expect(result.Time).To(BeTemporally("==", last))
+	})
+
+	It("falls back to the creation of an event without timestamps", func() {
+		event.Count = 0
+		event.LastTimestamp = metav1.Time{}
+
+		result := application.EventOf(event)
+		Expect(result.Count).To(Equal(int32(1)))
+		Expect(result.Time).To(BeTemporally("==", created))
+	})
+})
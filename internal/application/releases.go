@@ -0,0 +1,196 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+const (
+	// ReleasesAnnotation is the annotation of the application resource
+	// holding the JSON encoded release history of the app
+	ReleasesAnnotation = "epinio.suse.org/releases"
+
+	// MaxReleases is the number of releases kept per app
+	MaxReleases = 20
+
+	// releaseEnvKey is the key of the JSON encoded environment in the
+	// secret of a release
+	releaseEnvKey = "environment"
+)
+
+// ErrNoRelease is returned when a rollback has no release to go back to
+var ErrNoRelease = errors.New("no release to roll back to")
+
+// Releases returns the release history of the app, oldest first
+func Releases(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.ReleaseList, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	return releasesOf(app)
+}
+
+// AddRelease records the release as the newest of the app. The ID and the
+// creation time of the release are assigned here, and the oldest releases
+// are dropped beyond MaxReleases. The environment of the release is moved
+// into a secret of its own, see ReleaseEnvironment, which is removed with the
+// release.
+func AddRelease(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, release models.Release) (models.Release, error) {
+	var dropped models.ReleaseList
+
+	err := updateAnnotation(ctx, cluster, appRef, ReleasesAnnotation, func(app *unstructured.Unstructured) (string, error) {
+		releases, err := releasesOf(app)
+		if err != nil {
+			return "", err
+		}
+
+		release.ID = 1
+		if len(releases) > 0 {
+			release.ID = releases[len(releases)-1].ID + 1
+		}
+		release.Created = time.Now().UTC().Truncate(time.Second)
+
+		releases = append(releases, release)
+		dropped = nil
+		if len(releases) > MaxReleases {
+			dropped = releases[:len(releases)-MaxReleases]
+			releases = releases[len(releases)-MaxReleases:]
+		}
+
+		// Releases recorded with their environment inline are moved
+		// into secrets as well
+		for i := range releases {
+			if releases[i].EnvironmentSecret != "" || releases[i].Environment == nil {
+				continue
+			}
+			name, err := releaseEnvStore(ctx, cluster, app, releases[i])
+			if err != nil {
+				return "", err
+			}
+			releases[i].EnvironmentSecret = name
+			releases[i].Environment = nil
+		}
+		release = releases[len(releases)-1]
+
+		data, err := json.Marshal(releases)
+		return string(data), err
+	})
+	if err != nil {
+		return release, err
+	}
+
+	for _, old := range dropped {
+		if old.EnvironmentSecret == "" {
+			continue
+		}
+		err := cluster.Kubectl.CoreV1().Secrets(appRef.Org).Delete(ctx, old.EnvironmentSecret, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return release, err
+		}
+	}
+
+	return release, nil
+}
+
+// ReleaseEnvSecret returns the name of the secret holding the environment of
+// the release of the app
+func ReleaseEnvSecret(appRef models.AppRef, id int) string {
+	return fmt.Sprintf("%s.release.%d", appRef.Name, id)
+}
+
+// ReleaseEnvironment returns the environment the release of the app was
+// deployed with
+func ReleaseEnvironment(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, release models.Release) (models.EnvVariableList, error) {
+	if release.EnvironmentSecret == "" {
+		return release.Environment, nil
+	}
+
+	secret, err := cluster.GetSecret(ctx, appRef.Org, release.EnvironmentSecret)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to get the environment of release %d", release.ID)
+	}
+	if secret.GetLabels()["app.kubernetes.io/name"] != appRef.Name {
+		return nil, errors.Errorf("environment secret of release %d belongs to another application", release.ID)
+	}
+
+	environment := models.EnvVariableList{}
+	if err := json.Unmarshal(secret.Data[releaseEnvKey], &environment); err != nil {
+		return nil, errors.Wrapf(err, "bad environment of release %d", release.ID)
+	}
+	return environment, nil
+}
+
+// RollbackTarget returns the release to roll back to. A zero id selects the
+// release before the current, i.e. newest, one.
+func RollbackTarget(releases models.ReleaseList, id int) (*models.Release, error) {
+	if id != 0 {
+		release := releases.Find(id)
+		if release == nil {
+			return nil, errors.Errorf("release %d not found", id)
+		}
+		return release, nil
+	}
+
+	if len(releases) < 2 {
+		return nil, ErrNoRelease
+	}
+	return &releases[len(releases)-2], nil
+}
+
+func releasesOf(app *unstructured.Unstructured) (models.ReleaseList, error) {
+	releases := models.ReleaseList{}
+
+	data, ok := app.GetAnnotations()[ReleasesAnnotation]
+	if !ok || data == "" {
+		return releases, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &releases); err != nil {
+		return nil, errors.Wrap(err, "bad release history")
+	}
+	return releases, nil
+}
+
+// releaseEnvStore writes the environment of the release into its secret,
+// owned by the app, and returns the name of the secret
+func releaseEnvStore(ctx context.Context, cluster *kubernetes.Cluster, app *unstructured.Unstructured, release models.Release) (string, error) {
+	appRef := models.NewAppRef(app.GetName(), app.GetNamespace())
+	name := ReleaseEnvSecret(appRef, release.ID)
+
+	data, err := json.Marshal(release.Environment)
+	if err != nil {
+		return "", err
+	}
+
+	secret := &v1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: appRef.Org,
+			Labels: map[string]string{
+				"app.kubernetes.io/name":       appRef.Name,
+				"app.kubernetes.io/part-of":    appRef.Org,
+				"app.kubernetes.io/managed-by": "epinio",
+				"app.kubernetes.io/component":  "release",
+			},
+			OwnerReferences: []metav1.OwnerReference{ownerOf(app)},
+		},
+		Data: map[string][]byte{releaseEnvKey: data},
+	}
+
+	if err := secretApply(ctx, cluster, secret); err != nil {
+		return "", err
+	}
+
+	return name, nil
+}
@@ -0,0 +1,39 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RollbackTarget", func() {
+	releases := models.ReleaseList{
+		{ID: 3, ImageURL: "image:3"},
+		{ID: 4, ImageURL: "image:4"},
+		{ID: 5, ImageURL: "image:5"},
+	}
+
+	It("selects the release before the current one by default", func() {
+		release, err := application.RollbackTarget(releases, 0)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(release.ID).To(Equal(4))
+	})
+
+	It("selects the requested release", func() {
+		release, err := application.RollbackTarget(releases, 3)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(release.ImageURL).To(Equal("image:3"))
+	})
+
+	It("fails for an unknown release", func() {
+		_, err := application.RollbackTarget(releases, 1)
+		Expect(err).To(MatchError("release 1 not found"))
+	})
+
+	It("fails without an earlier release", func() {
+		_, err := application.RollbackTarget(releases[:1], 0)
+		Expect(err).To(Equal(application.ErrNoRelease))
+	})
+})
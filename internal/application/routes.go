@@ -0,0 +1,152 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// RoutesAnnotation is the annotation of the application resource holding
+// the JSON encoded list of the app's routes
+const RoutesAnnotation = "epinio.suse.org/routes"
+
+// Routes returns the routes of the app. It is empty for an app which was
+// not deployed yet.
+func Routes(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) ([]string, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	return routesOf(app)
+}
+
+// RoutesSet replaces the routes of the app. The caller is responsible for
+// validating them, see ValidateRoute and RouteOwner.
+func RoutesSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, routes []string) error {
+	return updateAnnotation(ctx, cluster, appRef, RoutesAnnotation, func(app *unstructured.Unstructured) (string, error) {
+		data, err := json.Marshal(routes)
+		return string(data), err
+	})
+}
+
+// DefaultRoute returns the route of an app which has none configured
+func DefaultRoute(appRef models.AppRef, mainDomain string) string {
+	return fmt.Sprintf("%s.%s", appRef.Name, mainDomain)
+}
+
+// ValidateRoute checks that the route is a hostname usable in an ingress
+// rule, and returns it normalized to lower case
+func ValidateRoute(route string) (string, error) {
+	route = strings.ToLower(strings.TrimSpace(route))
+	if errs := validation.IsDNS1123Subdomain(route); len(errs) > 0 {
+		return "", fmt.Errorf("bad route '%s': %s", route, strings.Join(errs, ", "))
+	}
+	if !strings.Contains(route, ".") {
+		return "", fmt.Errorf("bad route '%s': not a fully qualified domain name", route)
+	}
+	return route, nil
+}
+
+// ErrRouteReserved is returned by RouteOwner for routes which are not
+// available to apps at all
+var ErrRouteReserved = errors.New("route is reserved")
+
+// ReservedRoute returns true if the route belongs to the epinio system, e.g.
+// the API server or gitea
+func ReservedRoute(route, mainDomain string) bool {
+	for _, system := range []string{
+		deployments.EpinioDeploymentID,
+		deployments.GiteaDeploymentID,
+		deployments.RegistryDeploymentID,
+	} {
+		if route == fmt.Sprintf("%s.%s", system, mainDomain) {
+			return true
+		}
+	}
+	return false
+}
+
+// RouteOwner returns the app claiming the route, or nil when the route is
+// free. Apps claim the routes of their ingresses, and the routes saved for
+// them, deployed or not, in all orgs. ErrRouteReserved is returned for the
+// routes of the system, and for the routes of any other ingress in the
+// cluster.
+func RouteOwner(ctx context.Context, cluster *kubernetes.Cluster, route, mainDomain string) (*models.AppRef, error) {
+	if ReservedRoute(route, mainDomain) {
+		return nil, ErrRouteReserved
+	}
+
+	ingresses, err := cluster.Kubectl.NetworkingV1().Ingresses(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ingress := range ingresses.Items {
+		labels := ingress.GetLabels()
+		// The ingresses of cert-manager solving the ACME challenges
+		// of a route are not owners
+		if labels["acme.cert-manager.io/http01-solver"] == "true" {
+			continue
+		}
+		for _, rule := range ingress.Spec.Rules {
+			if rule.Host != route {
+				continue
+			}
+			if labels["app.kubernetes.io/component"] != "application" ||
+				labels["app.kubernetes.io/managed-by"] != "epinio" {
+				return nil, ErrRouteReserved
+			}
+			owner := models.NewAppRef(labels["app.kubernetes.io/name"], ingress.Namespace)
+			return &owner, nil
+		}
+	}
+
+	client, err := cluster.ClientApp()
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := client.Namespace(metav1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range apps.Items {
+		routes, err := routesOf(&apps.Items[i])
+		if err != nil {
+			return nil, err
+		}
+		for _, r := range routes {
+			if r == route {
+				owner := models.NewAppRef(apps.Items[i].GetName(), apps.Items[i].GetNamespace())
+				return &owner, nil
+			}
+		}
+	}
+
+	return nil, nil
+}
+
+func routesOf(app *unstructured.Unstructured) ([]string, error) {
+	routes := []string{}
+
+	data, ok := app.GetAnnotations()[RoutesAnnotation]
+	if !ok || data == "" {
+		return routes, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &routes); err != nil {
+		return nil, errors.Wrap(err, "bad application routes")
+	}
+	return routes, nil
+}
@@ -0,0 +1,50 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("DefaultRoute", func() {
+	It("puts the app below the main domain", func() {
+		route := application.DefaultRoute(models.NewAppRef("app", "workspace"), "10.0.0.1.omg.howdoi.website")
+		Expect(route).To(Equal("app.10.0.0.1.omg.howdoi.website"))
+	})
+})
+
+var _ = Describe("ValidateRoute", func() {
+	It("normalizes the route to lower case", func() {
+		route, err := application.ValidateRoute(" Shop.Example.COM ")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(route).To(Equal("shop.example.com"))
+	})
+
+	It("rejects a route which is not a hostname", func() {
+		_, err := application.ValidateRoute("https://shop.example.com")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("bad route"))
+	})
+
+	It("rejects a route without domain", func() {
+		_, err := application.ValidateRoute("shop")
+		Expect(err).To(MatchError("bad route 'shop': not a fully qualified domain name"))
+	})
+})
+
+var _ = Describe("ReservedRoute", func() {
+	mainDomain := "10.0.0.1.omg.howdoi.website"
+
+	It("reserves the routes of the system", func() {
+		for _, route := range []string{"epinio.", "gitea.", "epinio-registry."} {
+			Expect(application.ReservedRoute(route+mainDomain, mainDomain)).To(BeTrue(), route)
+		}
+	})
+
+	It("leaves other routes to the apps", func() {
+		Expect(application.ReservedRoute("shop."+mainDomain, mainDomain)).To(BeFalse())
+		Expect(application.ReservedRoute("epinio.example.com", mainDomain)).To(BeFalse())
+	})
+})
@@ -0,0 +1,211 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/client-go/util/retry"
+)
+
+// SettingsAnnotation is the annotation of the application resource holding
+// the JSON encoded runtime settings of the app
+const SettingsAnnotation = "epinio.suse.org/settings"
+
+// Settings returns the runtime settings of the app
+func Settings(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (models.AppSettings, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return models.AppSettings{}, err
+	}
+
+	return settingsOf(app)
+}
+
+// SettingsUpdate merges the update into the runtime settings of the app, see
+// MergeSettings, and returns the result. The result is validated before it is
+// stored.
+func SettingsUpdate(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, update models.AppSettings) (models.AppSettings, error) {
+	var settings models.AppSettings
+
+	err := updateAnnotation(ctx, cluster, appRef, SettingsAnnotation, func(app *unstructured.Unstructured) (string, error) {
+		current, err := settingsOf(app)
+		if err != nil {
+			return "", err
+		}
+
+		settings = MergeSettings(current, update)
+		if err := ValidateSettings(settings); err != nil {
+			return "", err
+		}
+
+		data, err := json.Marshal(settings)
+		return string(data), err
+	})
+
+	return settings, err
+}
+
+// MergeSettings returns the current settings, overridden by the non-empty
+// fields of the update. A probe of type "none" removes the probe, and a
+// resource set to models.ResourceNone removes the request or limit.
+func MergeSettings(current, update models.AppSettings) models.AppSettings {
+	result := current
+
+	if update.Port != 0 {
+		result.Port = update.Port
+	}
+	result.Liveness = mergeProbe(current.Liveness, update.Liveness)
+	result.Readiness = mergeProbe(current.Readiness, update.Readiness)
+
+	result.Resources.MemoryRequest = mergeResource(current.Resources.MemoryRequest, update.Resources.MemoryRequest)
+	result.Resources.MemoryLimit = mergeResource(current.Resources.MemoryLimit, update.Resources.MemoryLimit)
+	result.Resources.CPURequest = mergeResource(current.Resources.CPURequest, update.Resources.CPURequest)
+	result.Resources.CPULimit = mergeResource(current.Resources.CPULimit, update.Resources.CPULimit)
+
+	return result
+}
+
+func mergeResource(current, update string) string {
+	switch update {
+	case "":
+		return current
+	case models.ResourceNone:
+		return ""
+	default:
+		return update
+	}
+}
+
+func mergeProbe(current, update *models.Probe) *models.Probe {
+	switch {
+	case update == nil:
+		return current
+	case update.Type == "none":
+		return nil
+	default:
+		return update
+	}
+}
+
+// ValidateSettings checks the settings for values kubernetes would reject
+func ValidateSettings(settings models.AppSettings) error {
+	if settings.Port < 0 || settings.Port > 65535 {
+		return fmt.Errorf("port %d out of range", settings.Port)
+	}
+
+	if err := validateProbe("liveness", settings.Liveness); err != nil {
+		return err
+	}
+	if err := validateProbe("readiness", settings.Readiness); err != nil {
+		return err
+	}
+
+	quantities := []struct{ name, value string }{
+		{"memory request", settings.Resources.MemoryRequest},
+		{"memory limit", settings.Resources.MemoryLimit},
+		{"cpu request", settings.Resources.CPURequest},
+		{"cpu limit", settings.Resources.CPULimit},
+	}
+	parsed := map[string]resource.Quantity{}
+	for _, q := range quantities {
+		if q.value == "" {
+			continue
+		}
+		quantity, err := resource.ParseQuantity(q.value)
+		if err != nil {
+			return fmt.Errorf("bad %s '%s'", q.name, q.value)
+		}
+		parsed[q.name] = quantity
+	}
+
+	for _, kind := range []string{"memory", "cpu"} {
+		request, hasRequest := parsed[kind+" request"]
+		limit, hasLimit := parsed[kind+" limit"]
+		if hasRequest && hasLimit && request.Cmp(limit) > 0 {
+			return fmt.Errorf("%s request exceeds the %s limit", kind, kind)
+		}
+	}
+
+	return nil
+}
+
+func validateProbe(name string, probe *models.Probe) error {
+	if probe == nil {
+		return nil
+	}
+
+	switch probe.Type {
+	case "http":
+		if probe.Path == "" || probe.Path[0] != '/' {
+			return fmt.Errorf("%s probe needs an absolute path", name)
+		}
+	case "tcp":
+		if probe.Path != "" {
+			return fmt.Errorf("%s probe of type tcp takes no path", name)
+		}
+	default:
+		return fmt.Errorf("bad %s probe type '%s', expected http or tcp", name, probe.Type)
+	}
+
+	if probe.InitialDelaySeconds < 0 || probe.PeriodSeconds < 0 ||
+		probe.TimeoutSeconds < 0 || probe.FailureThreshold < 0 {
+		return fmt.Errorf("%s probe timings should be equal or greater than zero", name)
+	}
+
+	return nil
+}
+
+func settingsOf(app *unstructured.Unstructured) (models.AppSettings, error) {
+	settings := models.AppSettings{}
+
+	data, ok := app.GetAnnotations()[SettingsAnnotation]
+	if !ok || data == "" {
+		return settings, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &settings); err != nil {
+		return settings, errors.Wrap(err, "bad application settings")
+	}
+	return settings, nil
+}
+
+// updateAnnotation sets the annotation of the application resource to the
+// value computed by modify from the current resource. Conflicting updates
+// are retried.
+func updateAnnotation(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef,
+	key string, modify func(*unstructured.Unstructured) (string, error)) error {
+
+	client, err := cluster.ClientApp()
+	if err != nil {
+		return err
+	}
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		app, err := client.Namespace(appRef.Org).Get(ctx, appRef.Name, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		value, err := modify(app)
+		if err != nil {
+			return err
+		}
+
+		annotations := app.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[key] = value
+		app.SetAnnotations(annotations)
+
+		_, err = client.Namespace(appRef.Org).Update(ctx, app, metav1.UpdateOptions{})
+		return err
+	})
+}
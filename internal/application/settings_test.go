@@ -0,0 +1,80 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Settings", func() {
+	current := models.AppSettings{
+		Port:     8000,
+		Liveness: &models.Probe{Type: "http", Path: "/healthz"},
+		Resources: models.Resources{
+			MemoryRequest: "256Mi",
+			MemoryLimit:   "512Mi",
+		},
+	}
+
+	Describe("MergeSettings", func() {
+		It("keeps the current settings for empty fields", func() {
+			Expect(application.MergeSettings(current, models.AppSettings{})).To(Equal(current))
+		})
+
+		It("overrides the given fields", func() {
+			merged := application.MergeSettings(current, models.AppSettings{
+				Readiness: &models.Probe{Type: "tcp"},
+				Resources: models.Resources{MemoryLimit: "1Gi"},
+			})
+			Expect(merged.Port).To(Equal(int32(8000)))
+			Expect(merged.Liveness.Path).To(Equal("/healthz"))
+			Expect(merged.Readiness.Type).To(Equal("tcp"))
+			Expect(merged.Resources.MemoryRequest).To(Equal("256Mi"))
+			Expect(merged.Resources.MemoryLimit).To(Equal("1Gi"))
+		})
+
+		It("removes a probe of type none", func() {
+			merged := application.MergeSettings(current, models.AppSettings{
+				Liveness: &models.Probe{Type: "none"},
+			})
+			Expect(merged.Liveness).To(BeNil())
+		})
+
+		It("removes resources set to none", func() {
+			merged := application.MergeSettings(current, models.AppSettings{
+				Resources: models.Resources{MemoryLimit: models.ResourceNone, CPURequest: models.ResourceNone},
+			})
+			Expect(merged.Resources.MemoryRequest).To(Equal("256Mi"))
+			Expect(merged.Resources.MemoryLimit).To(BeEmpty())
+			Expect(merged.Resources.CPURequest).To(BeEmpty())
+			Expect(application.ValidateSettings(merged)).To(Succeed())
+		})
+	})
+
+	Describe("ValidateSettings", func() {
+		It("accepts valid settings", func() {
+			Expect(application.ValidateSettings(current)).To(Succeed())
+		})
+
+		It("rejects a port out of range", func() {
+			Expect(application.ValidateSettings(models.AppSettings{Port: 70000})).ToNot(Succeed())
+		})
+
+		It("rejects an http probe without path", func() {
+			settings := models.AppSettings{Readiness: &models.Probe{Type: "http"}}
+			Expect(application.ValidateSettings(settings)).To(MatchError("readiness probe needs an absolute path"))
+		})
+
+		It("rejects bad quantities", func() {
+			settings := models.AppSettings{Resources: models.Resources{CPULimit: "lots"}}
+			Expect(application.ValidateSettings(settings)).To(MatchError("bad cpu limit 'lots'"))
+		})
+
+		It("rejects a request above the limit", func() {
+			settings := models.AppSettings{Resources: models.Resources{MemoryRequest: "1Gi", MemoryLimit: "512Mi"}}
+			Expect(application.ValidateSettings(settings)).To(MatchError("memory request exceeds the memory limit"))
+		})
+	})
+})
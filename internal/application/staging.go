@@ -0,0 +1,418 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/helpers/kubernetes/tailer"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/duration"
+	"github.com/go-logr/logr"
+	"github.com/pkg/errors"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+	"github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
+	tektonv1beta1 "github.com/tektoncd/pipeline/pkg/client/clientset/versioned/typed/pipeline/v1beta1"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// stagingLogLines is the number of log lines reported for a failed staging step
+const stagingLogLines = 20
+
+// StagingPolicyAnnotation is the annotation of the application resource
+// holding its staging policy
+const StagingPolicyAnnotation = "epinio.suse.org/staging-policy"
+
+// StagingPolicy returns the staging policy of the app, see
+// models.StagingQueue. Apps queue their staging requests by default.
+func StagingPolicy(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (string, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return "", err
+	}
+
+	return stagingPolicyOf(app), nil
+}
+
+// StagingPolicySet changes the staging policy of the app
+func StagingPolicySet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, policy string) error {
+	if err := ValidateStagingPolicy(policy); err != nil {
+		return err
+	}
+
+	return updateAnnotation(ctx, cluster, appRef, StagingPolicyAnnotation, func(*unstructured.Unstructured) (string, error) {
+		return policy, nil
+	})
+}
+
+// ValidateStagingPolicy checks that the policy is a known one
+func ValidateStagingPolicy(policy string) error {
+	switch policy {
+	case models.StagingQueue, models.StagingSupersede, models.StagingReject:
+		return nil
+	}
+	return errors.Errorf("unknown staging policy '%s', expected one of %s, %s, %s",
+		policy, models.StagingQueue, models.StagingSupersede, models.StagingReject)
+}
+
+func stagingPolicyOf(app *unstructured.Unstructured) string {
+	if policy := app.GetAnnotations()[StagingPolicyAnnotation]; policy != "" {
+		return policy
+	}
+	return models.StagingQueue
+}
+
+// UnfinishedStaging returns the pipeline runs staging the app which did not
+// finish yet, oldest first. Cancelled runs count as finished.
+func UnfinishedStaging(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) ([]v1beta1.PipelineRun, error) {
+	client, err := pipelineRuns(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := client.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/name=%s,app.kubernetes.io/part-of=%s", appRef.Name, appRef.Org),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return unfinished(l.Items), nil
+}
+
+// StagingQueue returns the state of the unfinished staging runs of the app,
+// oldest first. That is the current run, if any, followed by the queued runs,
+// with their position in the queue.
+func StagingQueue(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) ([]models.StageStatus, error) {
+	runs, err := UnfinishedStaging(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	queue := []models.StageStatus{}
+	position := 0
+	for i := range runs {
+		status, _, _ := StageStatusOf(&runs[i])
+		if status.Status == models.StageQueued {
+			position++
+			status.Position = position
+		}
+		queue = append(queue, status)
+	}
+
+	return queue, nil
+}
+
+// CancelStaging cancels the pipeline runs. Tekton stops them when running,
+// and does not start them when queued. The volume claims of their workspaces
+// are removed, kubernetes releases them when the pods of a run are gone.
+func CancelStaging(ctx context.Context, cluster *kubernetes.Cluster, runs []v1beta1.PipelineRun) error {
+	client, err := pipelineRuns(cluster)
+	if err != nil {
+		return err
+	}
+
+	patch := fmt.Sprintf(`{"spec":{"status":"%s"}}`, v1beta1.PipelineRunSpecStatusCancelled)
+	for _, pr := range runs {
+		_, err := client.Patch(ctx, pr.Name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return deleteWorkspaceClaims(ctx, cluster, runs)
+}
+
+// deleteWorkspaceClaims removes the volume claims tekton created for the
+// workspaces of the pipeline runs. They are owned by their run.
+func deleteWorkspaceClaims(ctx context.Context, cluster *kubernetes.Cluster, runs []v1beta1.PipelineRun) error {
+	owners := map[types.UID]bool{}
+	for _, pr := range runs {
+		owners[pr.UID] = true
+	}
+
+	claims := cluster.Kubectl.CoreV1().PersistentVolumeClaims(deployments.TektonStagingNamespace)
+	l, err := claims.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+
+	for _, claim := range l.Items {
+		for _, owner := range claim.OwnerReferences {
+			if !owners[owner.UID] {
+				continue
+			}
+			err := claims.Delete(ctx, claim.Name, metav1.DeleteOptions{})
+			if err != nil && !apierrors.IsNotFound(err) {
+				return err
+			}
+			break
+		}
+	}
+
+	return nil
+}
+
+// StagingCancelled sends a line to the log channel when the staging run is
+// cancelled, for the staging log stream. It watches the run until it finished
+// or the context is done.
+func StagingCancelled(ctx context.Context, logChan chan tailer.ContainerLogLine, cluster *kubernetes.Cluster, org, stageID string) {
+	_ = wait.PollImmediateUntil(duration.PollInterval(), func() (bool, error) {
+		pr, err := StagingRun(ctx, cluster, org, stageID)
+		if err != nil {
+			// Try again, until the context is done
+			return false, nil
+		}
+		if pr == nil {
+			return true, nil
+		}
+		if !pr.IsCancelled() {
+			return pr.IsDone(), nil
+		}
+
+		select {
+		case logChan <- tailer.ContainerLogLine{
+			Message:       fmt.Sprintf("Staging %s was cancelled", stageID),
+			ContainerName: "epinio",
+			PodName:       stageID,
+			Namespace:     deployments.TektonStagingNamespace,
+		}:
+		case <-ctx.Done():
+		}
+		return true, nil
+	}, ctx.Done())
+}
+
+// StartQueuedStaging starts the next queued staging run of the apps which are
+// not staged at the moment, see NextQueuedStaging
+func StartQueuedStaging(ctx context.Context, cluster *kubernetes.Cluster) error {
+	client, err := pipelineRuns(cluster)
+	if err != nil {
+		return err
+	}
+
+	l, err := client.List(ctx, metav1.ListOptions{
+		LabelSelector: "app.kubernetes.io/managed-by=epinio,app.kubernetes.io/component=staging",
+	})
+	if err != nil {
+		return err
+	}
+
+	apps := map[models.AppRef][]v1beta1.PipelineRun{}
+	for _, pr := range unfinished(l.Items) {
+		app := stagedApp(&pr)
+		apps[app] = append(apps[app], pr)
+	}
+
+	for _, runs := range apps {
+		next := NextQueuedStaging(runs)
+		if next == nil {
+			continue
+		}
+		_, err := client.Patch(ctx, next.Name, types.MergePatchType, []byte(`{"spec":{"status":null}}`), metav1.PatchOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RunStagingQueue starts queued staging runs, see StartQueuedStaging, until
+// the context is done
+func RunStagingQueue(ctx context.Context, cluster *kubernetes.Cluster, log logr.Logger) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := StartQueuedStaging(ctx, cluster); err != nil {
+			log.Error(err, "failed to start queued staging")
+		}
+	}, duration.PollInterval())
+}
+
+// NextQueuedStaging returns the queued run to start from the unfinished
+// staging runs of an app, oldest first. That is the oldest, when none of them
+// is running.
+func NextQueuedStaging(runs []v1beta1.PipelineRun) *v1beta1.PipelineRun {
+	if len(runs) == 0 {
+		return nil
+	}
+	for _, pr := range runs {
+		if !pr.IsPending() {
+			return nil
+		}
+	}
+	return &runs[0]
+}
+
+// unfinished returns the runs which did not finish yet, oldest first
+func unfinished(runs []v1beta1.PipelineRun) []v1beta1.PipelineRun {
+	result := []v1beta1.PipelineRun{}
+	for _, pr := range runs {
+		if !pr.IsDone() && !pr.IsCancelled() {
+			result = append(result, pr)
+		}
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		ti, tj := result[i].CreationTimestamp, result[j].CreationTimestamp
+		if ti.Equal(&tj) {
+			return result[i].Name < result[j].Name
+		}
+		return ti.Before(&tj)
+	})
+
+	return result
+}
+
+// stagedApp returns the app the pipeline run stages
+func stagedApp(pr *v1beta1.PipelineRun) models.AppRef {
+	return models.NewAppRef(pr.Labels["app.kubernetes.io/name"], pr.Labels["app.kubernetes.io/part-of"])
+}
+
+func pipelineRuns(cluster *kubernetes.Cluster) (tektonv1beta1.PipelineRunInterface, error) {
+	cs, err := versioned.NewForConfig(cluster.RestConfig)
+	if err != nil {
+		return nil, err
+	}
+	return cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace), nil
+}
+
+// StagingRun returns the pipeline run of the staging run of the org, or nil
+// if the org has no such run
+func StagingRun(ctx context.Context, cluster *kubernetes.Cluster, org, stageID string) (*v1beta1.PipelineRun, error) {
+	client, err := pipelineRuns(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := client.List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,app.kubernetes.io/part-of=%s", models.EpinioStageIDLabel, stageID, org),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(l.Items) == 0 {
+		return nil, nil
+	}
+
+	return &l.Items[0], nil
+}
+
+// StageStatus returns the state of the staging run of the org, or nil if the
+// org has no such run. The state of a failed run includes the last lines of
+// the log of the failed step, while its pod is around.
+func StageStatus(ctx context.Context, cluster *kubernetes.Cluster, org, stageID string) (*models.StageStatus, error) {
+	pr, err := StagingRun(ctx, cluster, org, stageID)
+	if err != nil || pr == nil {
+		return nil, err
+	}
+
+	status, pod, container := StageStatusOf(pr)
+	if status.Status == models.StageQueued {
+		queue, err := StagingQueue(ctx, cluster, stagedApp(pr))
+		if err != nil {
+			return nil, err
+		}
+		for _, queued := range queue {
+			if queued.ID == status.ID {
+				status.Position = queued.Position
+			}
+		}
+	}
+	if pod != "" {
+		status.Logs, err = stepLogs(ctx, cluster, pod, container)
+		if err != nil {
+			status.Logs = []string{fmt.Sprintf("(logs not available: %s)", err.Error())}
+		}
+	}
+
+	return &status, nil
+}
+
+// StageStatusOf returns the state of the staging run of the pipeline run,
+// without logs. For a failed run it returns the pod and container of the
+// failed step as well, if known.
+func StageStatusOf(pr *v1beta1.PipelineRun) (models.StageStatus, string, string) {
+	status := models.StageStatus{ID: pr.Name, Status: models.StagePending}
+
+	condition := pr.Status.GetCondition("Succeeded")
+	if condition != nil {
+		status.Message = condition.Message
+	}
+
+	switch {
+	case condition != nil && condition.Status == corev1.ConditionTrue:
+		status.Status = models.StageSucceeded
+		return status, "", ""
+	case pr.IsCancelled():
+		status.Status = models.StageCancelled
+		return status, "", ""
+	case pr.IsPending():
+		status.Status = models.StageQueued
+		return status, "", ""
+	case condition == nil:
+		return status, "", ""
+	case condition.Status == corev1.ConditionUnknown:
+		status.Status = models.StageRunning
+		return status, "", ""
+	}
+	status.Status = models.StageFailed
+
+	// Look for the failed task, in a stable order
+	names := []string{}
+	for name := range pr.Status.TaskRuns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		taskRun := pr.Status.TaskRuns[name]
+		if taskRun.Status == nil {
+			continue
+		}
+		condition := taskRun.Status.GetCondition("Succeeded")
+		if condition == nil || condition.Status != corev1.ConditionFalse {
+			continue
+		}
+
+		status.Task = taskRun.PipelineTaskName
+		status.Message = condition.Message
+
+		for _, step := range taskRun.Status.Steps {
+			if terminated := step.Terminated; terminated != nil && terminated.ExitCode != 0 {
+				status.Step = step.Name
+				status.ExitCode = terminated.ExitCode
+				return status, taskRun.Status.PodName, step.ContainerName
+			}
+		}
+		break
+	}
+
+	return status, "", ""
+}
+
+// stepLogs returns the last lines of the log of the staging step container
+func stepLogs(ctx context.Context, cluster *kubernetes.Cluster, pod, container string) ([]string, error) {
+	lines := int64(stagingLogLines)
+	raw, err := cluster.Kubectl.CoreV1().Pods(deployments.TektonStagingNamespace).GetLogs(pod, &corev1.PodLogOptions{
+		Container: container,
+		TailLines: &lines,
+	}).DoRaw(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	text := strings.TrimRight(string(raw), "\n")
+	if text == "" {
+		return []string{}, nil
+	}
+	return strings.Split(text, "\n"), nil
+}
@@ -0,0 +1,126 @@
+package application_test
+
+import (
+	"encoding/json"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/tektoncd/pipeline/pkg/apis/pipeline/v1beta1"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+// pipelineRun returns a pipeline run named r7fhz with the given status
+func pipelineRun(status string) *v1beta1.PipelineRun {
+	return pipelineRunWith("r7fhz", `{}`, status)
+}
+
+// pipelineRunWith returns a pipeline run with the given spec and status
+func pipelineRunWith(name, spec, status string) *v1beta1.PipelineRun {
+	pr := &v1beta1.PipelineRun{}
+	err := json.Unmarshal([]byte(`{"metadata": {"name": "`+name+`"}, "spec": `+spec+`, "status": `+status+`}`), pr)
+	Expect(err).ToNot(HaveOccurred())
+	return pr
+}
+
+var _ = Describe("StageStatusOf", func() {
+	It("reports a run tekton did not pick up yet as pending", func() {
+		status, pod, _ := application.StageStatusOf(pipelineRun(`{}`))
+		Expect(status.ID).To(Equal("r7fhz"))
+		Expect(status.Status).To(Equal(models.StagePending))
+		Expect(pod).To(BeEmpty())
+	})
+
+	It("reports a running and a succeeded run", func() {
+		status, _, _ := application.StageStatusOf(pipelineRun(
+			`{"conditions": [{"type": "Succeeded", "status": "Unknown", "reason": "Running"}]}`))
+		Expect(status.Status).To(Equal(models.StageRunning))
+
+		status, _, _ = application.StageStatusOf(pipelineRun(
+			`{"conditions": [{"type": "Succeeded", "status": "True", "reason": "Succeeded"}]}`))
+		Expect(status.Status).To(Equal(models.StageSucceeded))
+	})
+
+	It("reports the failed step of a failed run", func() {
+		status, pod, container := application.StageStatusOf(pipelineRun(`{
+			"conditions": [{"type": "Succeeded", "status": "False", "reason": "Failed", "message": "Tasks Completed: 2 (Failed: 1)"}],
+			"taskRuns": {
+				"r7fhz-clone-x8z2k": {
+					"pipelineTaskName": "clone",
+					"status": {
+						"conditions": [{"type": "Succeeded", "status": "True"}],
+						"podName": "r7fhz-clone-x8z2k-pod-4kx2m"
+					}
+				},
+				"r7fhz-stage-2l4pd": {
+					"pipelineTaskName": "stage",
+					"status": {
+						"conditions": [{"type": "Succeeded", "status": "False", "message": "\"step-build\" exited with code 51"}],
+						"podName": "r7fhz-stage-2l4pd-pod-9xk7t",
+						"steps": [
+							{"name": "prepare", "container": "step-prepare", "terminated": {"exitCode": 0}},
+							{"name": "build", "container": "step-build", "terminated": {"exitCode": 51}}
+						]
+					}
+				}
+			}
+		}`))
+
+		Expect(status.Status).To(Equal(models.StageFailed))
+		Expect(status.Task).To(Equal("stage"))
+		Expect(status.Step).To(Equal("build"))
+		Expect(status.ExitCode).To(Equal(int32(51)))
+		Expect(status.Message).To(ContainSubstring("exited with code 51"))
+		Expect(pod).To(Equal("r7fhz-stage-2l4pd-pod-9xk7t"))
+		Expect(container).To(Equal("step-build"))
+	})
+
+	It("reports a failed run without failed task", func() {
+		status, pod, _ := application.StageStatusOf(pipelineRun(
+			`{"conditions": [{"type": "Succeeded", "status": "False", "reason": "PipelineRunTimeout", "message": "timed out"}]}`))
+		Expect(status.Status).To(Equal(models.StageFailed))
+		Expect(status.Message).To(Equal("timed out"))
+		Expect(status.Task).To(BeEmpty())
+		Expect(pod).To(BeEmpty())
+	})
+})
+
+var _ = Describe("staging queue", func() {
+	queued := `{"status": "PipelineRunPending"}`
+	running := `{"conditions": [{"type": "Succeeded", "status": "Unknown", "reason": "Running"}]}`
+
+	It("reports queued and cancelled runs", func() {
+		status, _, _ := application.StageStatusOf(pipelineRunWith("r7fhz", queued,
+			`{"conditions": [{"type": "Succeeded", "status": "Unknown", "reason": "PipelineRunPending"}]}`))
+		Expect(status.Status).To(Equal(models.StageQueued))
+
+		status, _, _ = application.StageStatusOf(pipelineRunWith("r7fhz", `{"status": "PipelineRunCancelled"}`,
+			`{"conditions": [{"type": "Succeeded", "status": "False", "reason": "PipelineRunCancelled"}]}`))
+		Expect(status.Status).To(Equal(models.StageCancelled))
+	})
+
+	It("starts the oldest queued run, when none is running", func() {
+		runs := []v1beta1.PipelineRun{
+			*pipelineRunWith("first", queued, `{}`),
+			*pipelineRunWith("second", queued, `{}`),
+		}
+		Expect(application.NextQueuedStaging(runs).Name).To(Equal("first"))
+	})
+
+	It("starts no run while one is running", func() {
+		runs := []v1beta1.PipelineRun{
+			*pipelineRunWith("first", `{}`, running),
+			*pipelineRunWith("second", queued, `{}`),
+		}
+		Expect(application.NextQueuedStaging(runs)).To(BeNil())
+		Expect(application.NextQueuedStaging(nil)).To(BeNil())
+	})
+
+	It("knows the staging policies", func() {
+		Expect(application.ValidateStagingPolicy("queue")).To(Succeed())
+		Expect(application.ValidateStagingPolicy("supersede")).To(Succeed())
+		Expect(application.ValidateStagingPolicy("reject")).To(Succeed())
+		Expect(application.ValidateStagingPolicy("later")).To(MatchError(ContainSubstring("unknown staging policy 'later'")))
+	})
+})
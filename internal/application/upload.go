@@ -0,0 +1,127 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// UploadAnnotation is the annotation of the application resource holding the
+// JSON encoded digest and upload response of the last sources uploaded for
+// the app
+const UploadAnnotation = "epinio.suse.org/upload"
+
+// UploadRevisionAnnotation is the annotation of the application resource
+// holding the revision of the sources last pushed into its gitea repo by an
+// upload. Auto-deploy skips the push of these sources, as the client stages
+// them.
+const UploadRevisionAnnotation = "epinio.suse.org/upload-revision"
+
+var digestRegexp = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+type uploadRecord struct {
+	Digest   string                `json:"digest"`
+	Response models.UploadResponse `json:"response"`
+}
+
+// ValidateDigest checks that the digest is a hex encoded sha256 digest, as
+// used to identify uploaded sources
+func ValidateDigest(digest string) error {
+	if !digestRegexp.MatchString(digest) {
+		return errors.Errorf("bad digest '%s', expected a hex encoded sha256 digest", digest)
+	}
+	return nil
+}
+
+// StoredUpload returns the upload response of the last sources uploaded for
+// the app, if their digest is the given one, and nil else. The sources do not
+// have to be uploaded again then.
+func StoredUpload(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, digest string) (*models.UploadResponse, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := uploadOf(app)
+	if err != nil {
+		return nil, err
+	}
+	if record.Digest != digest || record.Response.Git == nil {
+		return nil, nil
+	}
+	return &record.Response, nil
+}
+
+// UploadRecord remembers the digest and upload response of the sources
+// uploaded for the app, see StoredUpload. Sources uploaded before the app
+// was created are not remembered.
+func UploadRecord(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, digest string, response models.UploadResponse) error {
+	err := updateAnnotation(ctx, cluster, appRef, UploadAnnotation, func(*unstructured.Unstructured) (string, error) {
+		data, err := json.Marshal(uploadRecord{Digest: digest, Response: response})
+		return string(data), err
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+// UploadedBuilder returns the builder detected from the sources last uploaded
+// for the app, if any. It stages the later pushes of the app's gitea repo,
+// which are not uploaded.
+func UploadedBuilder(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (string, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return "", err
+	}
+
+	record, err := uploadOf(app)
+	if err != nil {
+		return "", err
+	}
+	return record.Response.Builder, nil
+}
+
+// UploadRevision returns the revision of the sources last pushed into the
+// gitea repo of the app by an upload, if any
+func UploadRevision(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef) (string, error) {
+	app, err := Get(ctx, cluster, appRef)
+	if err != nil {
+		return "", err
+	}
+
+	return app.GetAnnotations()[UploadRevisionAnnotation], nil
+}
+
+// UploadRevisionSet remembers the revision of the sources an upload pushes
+// into the gitea repo of the app, before they are pushed. Sources uploaded
+// before the app was created are not remembered.
+func UploadRevisionSet(ctx context.Context, cluster *kubernetes.Cluster, appRef models.AppRef, revision string) error {
+	err := updateAnnotation(ctx, cluster, appRef, UploadRevisionAnnotation, func(*unstructured.Unstructured) (string, error) {
+		return revision, nil
+	})
+	if apierrors.IsNotFound(err) {
+		return nil
+	}
+	return err
+}
+
+func uploadOf(app *unstructured.Unstructured) (uploadRecord, error) {
+	record := uploadRecord{}
+
+	data, ok := app.GetAnnotations()[UploadAnnotation]
+	if !ok || data == "" {
+		return record, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &record); err != nil {
+		return record, errors.Wrap(err, "bad upload record")
+	}
+	return record, nil
+}
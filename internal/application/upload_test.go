@@ -0,0 +1,23 @@
+package application_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Upload", func() {
+	It("accepts hex encoded sha256 digests", func() {
+		sum := sha256.Sum256([]byte("sources"))
+		Expect(application.ValidateDigest(hex.EncodeToString(sum[:]))).To(Succeed())
+	})
+
+	It("rejects other digests", func() {
+		Expect(application.ValidateDigest("abc")).To(MatchError(ContainSubstring("bad digest 'abc'")))
+		Expect(application.ValidateDigest("../../etc/passwd")).ToNot(Succeed())
+	})
+})
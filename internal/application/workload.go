@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/internal/api/v1/models"
@@ -211,6 +212,113 @@ func (a *Workload) Unbind(ctx context.Context, service interfaces.Service) error
 	return service.DeleteBinding(ctx, a.app.Name, a.app.Org)
 }
 
+// Pods returns the pods of the application, sorted by name
+func (a *Workload) Pods(ctx context.Context) ([]corev1.Pod, error) {
+	pods, err := a.cluster.Kubectl.CoreV1().Pods(a.app.Org).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app.kubernetes.io/component=application,app.kubernetes.io/name=%s", a.app.Name),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := pods.Items
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// RunningInstances returns the pods of the running instances of the
+// application, sorted by name. An instance is referred to by its index.
+func (a *Workload) RunningInstances(ctx context.Context) ([]corev1.Pod, error) {
+	pods, err := a.Pods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	running := []corev1.Pod{}
+	for _, pod := range pods {
+		if isRunning(&pod) {
+			running = append(running, pod)
+		}
+	}
+
+	return running, nil
+}
+
+// Instances returns the status of the instances of the application, sorted
+// by name
+func (a *Workload) Instances(ctx context.Context) ([]models.Instance, error) {
+	pods, err := a.Pods(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return InstancesOf(pods), nil
+}
+
+// InstancesOf returns the status of the application instances running in the
+// pods. The running instances are numbered in the order of the pods, as
+// RunningInstances refers to them.
+func InstancesOf(pods []corev1.Pod) []models.Instance {
+	instances := []models.Instance{}
+	index := 0
+	for i := range pods {
+		instance := InstanceOf(&pods[i])
+		if isRunning(&pods[i]) {
+			n := index
+			instance.Index = &n
+			index++
+		}
+		instances = append(instances, instance)
+	}
+
+	return instances
+}
+
+func isRunning(pod *corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil
+}
+
+// InstanceOf returns the status of the application instance running in the
+// pod. The status of the container is the one of the app container, i.e. the
+// first.
+func InstanceOf(pod *corev1.Pod) models.Instance {
+	instance := models.Instance{
+		Name:    pod.Name,
+		Phase:   string(pod.Status.Phase),
+		Node:    pod.Spec.NodeName,
+		Created: pod.CreationTimestamp.Time,
+		StageID: pod.Labels[models.EpinioStageIDLabel],
+	}
+	if pod.DeletionTimestamp != nil {
+		instance.Phase = "Terminating"
+	}
+
+	if len(pod.Spec.Containers) == 0 {
+		return instance
+	}
+	name := pod.Spec.Containers[0].Name
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name != name {
+			continue
+		}
+
+		instance.Ready = status.Ready
+		instance.Restarts = status.RestartCount
+		if terminated := status.LastTerminationState.Terminated; terminated != nil {
+			instance.LastTermination = terminated.Reason
+		}
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+			instance.CrashLoop = true
+		}
+	}
+
+	return instance
+}
+
 func (a *Workload) deployment(ctx context.Context) (*appsv1.Deployment, error) {
 	return a.cluster.Kubectl.AppsV1().Deployments(a.app.Org).Get(
 		ctx, a.app.Name, metav1.GetOptions{},
@@ -309,9 +417,9 @@ func (a *Workload) Complete(ctx context.Context) (*models.App, error) {
 
 	routes, err := a.cluster.ListIngressRoutes(ctx, app.Organization, app.Name)
 	if err != nil {
-		app.Route = err.Error()
+		app.Routes = []string{err.Error()}
 	} else {
-		app.Route = routes[0]
+		app.Routes = routes
 	}
 
 	app.BoundServices = []string{}
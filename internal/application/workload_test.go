@@ -0,0 +1,82 @@
+package application_test
+
+import (
+	"github.com/epinio/epinio/internal/application"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("InstanceOf", func() {
+	var pod *corev1.Pod
+
+	BeforeEach(func() {
+		pod = &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   "app-6b9f7c-x2x4z",
+				Labels: map[string]string{"epinio.suse.org/stage-id": "r7fhz"},
+			},
+			Spec: corev1.PodSpec{
+				NodeName:   "node-1",
+				Containers: []corev1.Container{{Name: "app"}, {Name: "linkerd-proxy"}},
+			},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: "linkerd-proxy", Ready: true},
+					{Name: "app", Ready: true, RestartCount: 0},
+				},
+			},
+		}
+	})
+
+	It("reports a healthy instance", func() {
+		instance := application.InstanceOf(pod)
+		Expect(instance.Name).To(Equal("app-6b9f7c-x2x4z"))
+		Expect(instance.Phase).To(Equal("Running"))
+		Expect(instance.Ready).To(BeTrue())
+		Expect(instance.Node).To(Equal("node-1"))
+		Expect(instance.StageID).To(Equal("r7fhz"))
+		Expect(instance.CrashLoop).To(BeFalse())
+	})
+
+	It("reports the restarts and the crash loop of the app container", func() {
+		pod.Status.ContainerStatuses[1] = corev1.ContainerStatus{
+			Name:         "app",
+			RestartCount: 5,
+			State: corev1.ContainerState{
+				Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"},
+			},
+			LastTerminationState: corev1.ContainerState{
+				Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+			},
+		}
+
+		instance := application.InstanceOf(pod)
+		Expect(instance.Ready).To(BeFalse())
+		Expect(instance.Restarts).To(Equal(int32(5)))
+		Expect(instance.LastTermination).To(Equal("OOMKilled"))
+		Expect(instance.CrashLoop).To(BeTrue())
+	})
+})
+
+var _ = Describe("InstancesOf", func() {
+	It("numbers the running instances only", func() {
+		pods := []corev1.Pod{
+			{ObjectMeta: metav1.ObjectMeta{Name: "app-a"}, Status: corev1.PodStatus{Phase: corev1.PodPending}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "app-b"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "app-c", DeletionTimestamp: &metav1.Time{}}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+			{ObjectMeta: metav1.ObjectMeta{Name: "app-d"}, Status: corev1.PodStatus{Phase: corev1.PodRunning}},
+		}
+
+		instances := application.InstancesOf(pods)
+		Expect(instances).To(HaveLen(4))
+		Expect(instances[0].Index).To(BeNil())
+		Expect(*instances[1].Index).To(Equal(0))
+		Expect(instances[2].Index).To(BeNil())
+		Expect(instances[2].Phase).To(Equal("Terminating"))
+		Expect(*instances[3].Index).To(Equal(1))
+	})
+})
@@ -50,6 +50,7 @@ type CertParam struct {
 	Namespace string
 	Domain    string
 	Issuer    string
+	DNSName   string // The certified name, defaults to Name.Domain
 }
 
 // CreateCertificate creates a certificate resource, for the given
@@ -83,6 +84,22 @@ func CreateCertificate(
 	return nil
 }
 
+// DeleteCertificate removes the named certificate resource. A missing
+// certificate is not an error.
+func DeleteCertificate(ctx context.Context, cluster *kubernetes.Cluster, namespace, name string) error {
+	client, err := cluster.ClientCertificate()
+	if err != nil {
+		return err
+	}
+
+	err = client.Namespace(namespace).Delete(ctx, name, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return nil
+}
+
 func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 	// Notes:
 	// - spec.CommonName is length-limited.
@@ -99,7 +116,11 @@ func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 	//   full string as means of keeping the text unique across
 	//   apps.
 
-	cn := names.TruncateMD5(fmt.Sprintf("%s.%s", cert.Name, cert.Domain), 64)
+	dnsName := cert.DNSName
+	if dnsName == "" {
+		dnsName = fmt.Sprintf("%s.%s", cert.Name, cert.Domain)
+	}
+	cn := names.TruncateMD5(dnsName, 64)
 	data := fmt.Sprintf(`{
 		"apiVersion": "cert-manager.io/v1alpha2",
 		"kind": "Certificate",
@@ -110,14 +131,14 @@ func newCertificate(cert CertParam) (*unstructured.Unstructured, error) {
 			"commonName" : "%[2]s",
 			"secretName" : "%[1]s-tls",
 			"dnsNames": [
-				"%[1]s.%[3]s"
+				"%[3]s"
 			],
 			"issuerRef" : {
 				"name" : "%[4]s",
 				"kind" : "ClusterIssuer"
 			}
 		}
-        }`, cert.Name, cn, cert.Domain, cert.Issuer)
+        }`, cert.Name, cn, dnsName, cert.Issuer)
 
 	decoderUnstructured := yaml.NewDecodingSerializer(unstructured.UnstructuredJSONScheme)
 	obj := &unstructured.Unstructured{}
@@ -0,0 +1,267 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+)
+
+const (
+	// TLSSecretLabel marks the secrets holding user provided certificates
+	TLSSecretLabel = "epinio.suse.org/tls-certificate"
+	// TLSDomainAnnotation holds the domain a user provided certificate is
+	// stored for
+	TLSDomainAnnotation = "epinio.suse.org/domain"
+)
+
+// ErrTLSSecretNotKnown is returned when an org has no certificate for a domain
+var ErrTLSSecretNotKnown = errors.New("certificate does not exist")
+
+// TLSCertificate describes a user provided certificate, stored in a TLS
+// secret of an org. It is used for the routes of the domain, instead of a
+// certificate from cert-manager. A domain of the form `*.example.com` covers
+// the routes directly below example.com.
+type TLSCertificate struct {
+	Domain     string
+	SecretName string
+	DNSNames   []string
+	Issuer     string
+	NotAfter   time.Time
+}
+
+// Expired returns true if the certificate is not valid anymore
+func (c *TLSCertificate) Expired() bool {
+	return time.Now().After(c.NotAfter)
+}
+
+// Covers returns true if the certificate is usable for the route
+func (c *TLSCertificate) Covers(route string) bool {
+	return DomainCovers(c.Domain, route)
+}
+
+// DomainCovers returns true if the route is the domain, or, for a wildcard
+// domain, a name directly below it
+func DomainCovers(domain, route string) bool {
+	if !strings.HasPrefix(domain, "*.") {
+		return domain == route
+	}
+
+	parts := strings.SplitN(route, ".", 2)
+	return len(parts) == 2 && parts[0] != "" && parts[1] == domain[2:]
+}
+
+// ValidateTLSCertificate checks a PEM encoded certificate chain and key for
+// the domain. The key has to match the certificate, the certificate has to be
+// valid now, name the domain, and chain up to a trusted root. The roots are
+// the system roots, plus those given by caPEM, if any.
+func ValidateTLSCertificate(domain, certPEM, keyPEM, caPEM string, now time.Time) (*TLSCertificate, error) {
+	pair, err := tls.X509KeyPair([]byte(certPEM), []byte(keyPEM))
+	if err != nil {
+		return nil, errors.Wrap(err, "bad certificate or key")
+	}
+
+	chain := []*x509.Certificate{}
+	for _, der := range pair.Certificate {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return nil, errors.Wrap(err, "bad certificate")
+		}
+		chain = append(chain, cert)
+	}
+	leaf := chain[0]
+
+	if now.Before(leaf.NotBefore) {
+		return nil, errors.Errorf("certificate is not valid before %s", leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if now.After(leaf.NotAfter) {
+		return nil, errors.Errorf("certificate expired at %s", leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+
+	if !certificateNames(leaf, domain) {
+		return nil, errors.Errorf("certificate is not valid for '%s', it names %s",
+			domain, strings.Join(leaf.DNSNames, ", "))
+	}
+
+	roots, _ := x509.SystemCertPool()
+	if roots == nil {
+		roots = x509.NewCertPool()
+	}
+	if caPEM != "" {
+		if !roots.AppendCertsFromPEM([]byte(caPEM)) {
+			return nil, errors.New("bad ca certificate")
+		}
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		CurrentTime:   now,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "certificate chain does not verify")
+	}
+
+	return &TLSCertificate{
+		Domain:     domain,
+		SecretName: tlsSecretName(domain),
+		DNSNames:   leaf.DNSNames,
+		Issuer:     leaf.Issuer.String(),
+		NotAfter:   leaf.NotAfter,
+	}, nil
+}
+
+// certificateNames returns true if the certificate names the domain. A
+// wildcard domain has to be named literally.
+func certificateNames(cert *x509.Certificate, domain string) bool {
+	if strings.HasPrefix(domain, "*.") {
+		for _, name := range cert.DNSNames {
+			if strings.ToLower(name) == domain {
+				return true
+			}
+		}
+		return false
+	}
+
+	return cert.VerifyHostname(domain) == nil
+}
+
+// StoreTLSCertificate validates the certificate, see ValidateTLSCertificate,
+// and stores it in a TLS secret of the org. It replaces the certificate the
+// org has for the domain, if any, e.g. on renewal.
+func StoreTLSCertificate(ctx context.Context, cluster *kubernetes.Cluster, org, domain, certPEM, keyPEM, caPEM string) (*TLSCertificate, error) {
+	cert, err := ValidateTLSCertificate(domain, certPEM, keyPEM, caPEM, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cert.SecretName,
+			Namespace: org,
+			Labels: map[string]string{
+				TLSSecretLabel:                 "true",
+				"app.kubernetes.io/managed-by": "epinio",
+			},
+			Annotations: map[string]string{
+				TLSDomainAnnotation: domain,
+			},
+		},
+		Type: corev1.SecretTypeTLS,
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       []byte(certPEM),
+			corev1.TLSPrivateKeyKey: []byte(keyPEM),
+		},
+	}
+	if caPEM != "" {
+		secret.Data["ca.crt"] = []byte(caPEM)
+	}
+
+	secrets := cluster.Kubectl.CoreV1().Secrets(org)
+	_, err = secrets.Create(ctx, secret, metav1.CreateOptions{})
+	if apierrors.IsAlreadyExists(err) {
+		_, err = secrets.Update(ctx, secret, metav1.UpdateOptions{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return cert, nil
+}
+
+// ListTLSCertificates returns the user provided certificates of the org,
+// sorted by domain
+func ListTLSCertificates(ctx context.Context, cluster *kubernetes.Cluster, org string) ([]TLSCertificate, error) {
+	secrets, err := cluster.Kubectl.CoreV1().Secrets(org).List(ctx, metav1.ListOptions{
+		LabelSelector: TLSSecretLabel + "=true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []TLSCertificate{}
+	for _, secret := range secrets.Items {
+		cert, err := tlsCertificateFromSecret(&secret)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *cert)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Domain < result[j].Domain
+	})
+
+	return result, nil
+}
+
+// DeleteTLSCertificate removes the certificate the org has for the domain
+func DeleteTLSCertificate(ctx context.Context, cluster *kubernetes.Cluster, org, domain string) error {
+	err := cluster.Kubectl.CoreV1().Secrets(org).Delete(ctx, tlsSecretName(domain), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return ErrTLSSecretNotKnown
+	}
+	return err
+}
+
+// TLSCertificateFor returns the certificate to use for the route, or nil if
+// none of the certificates covers it. A certificate for the route itself is
+// preferred over a wildcard certificate. Expired certificates are ignored.
+func TLSCertificateFor(certs []TLSCertificate, route string) *TLSCertificate {
+	var match *TLSCertificate
+	for i := range certs {
+		cert := &certs[i]
+		if cert.Expired() || !cert.Covers(route) {
+			continue
+		}
+		if cert.Domain == route {
+			return cert
+		}
+		match = cert
+	}
+	return match
+}
+
+func tlsCertificateFromSecret(secret *corev1.Secret) (*TLSCertificate, error) {
+	cert := &TLSCertificate{
+		Domain:     secret.Annotations[TLSDomainAnnotation],
+		SecretName: secret.Name,
+	}
+
+	block, _ := pem.Decode(secret.Data[corev1.TLSCertKey])
+	if block == nil {
+		return nil, errors.Errorf("secret '%s' holds no certificate", secret.Name)
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrapf(err, "secret '%s' holds a bad certificate", secret.Name)
+	}
+
+	cert.DNSNames = leaf.DNSNames
+	cert.Issuer = leaf.Issuer.String()
+	cert.NotAfter = leaf.NotAfter
+
+	return cert, nil
+}
+
+// tlsSecretName returns the name of the secret holding the certificate for
+// the domain. The wildcard is spelled out, as it is not valid in a name.
+func tlsSecretName(domain string) string {
+	return "epinio-tls-" + strings.Replace(domain, "*", "wildcard", 1)
+}
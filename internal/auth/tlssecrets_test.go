@@ -0,0 +1,155 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"time"
+
+	"github.com/epinio/epinio/internal/auth"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCert creates a certificate for the names, signed by the parent. A
+// nil parent creates a self-signed CA.
+func newTestCert(parent *testCert, notAfter time.Time, names ...string) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		DNSNames:     names,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	signer, signerKey := template, key
+	if parent == nil {
+		template.Subject.CommonName = "test ca"
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	Expect(err).ToNot(HaveOccurred())
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	Expect(err).ToNot(HaveOccurred())
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+var _ = Describe("TLS certificates", func() {
+	var ca *testCert
+	inAYear := time.Now().Add(365 * 24 * time.Hour)
+
+	BeforeEach(func() {
+		ca = newTestCert(nil, inAYear)
+	})
+
+	Describe("ValidateTLSCertificate", func() {
+		It("accepts a certificate for the domain, signed by the given ca", func() {
+			leaf := newTestCert(ca, inAYear, "shop.example.com")
+
+			cert, err := auth.ValidateTLSCertificate("shop.example.com", leaf.certPEM, leaf.keyPEM, ca.certPEM, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.DNSNames).To(Equal([]string{"shop.example.com"}))
+			Expect(cert.SecretName).To(Equal("epinio-tls-shop.example.com"))
+		})
+
+		It("accepts a wildcard certificate for a wildcard domain", func() {
+			leaf := newTestCert(ca, inAYear, "*.example.com")
+
+			cert, err := auth.ValidateTLSCertificate("*.example.com", leaf.certPEM, leaf.keyPEM, ca.certPEM, time.Now())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cert.SecretName).To(Equal("epinio-tls-wildcard.example.com"))
+		})
+
+		It("rejects a certificate for another domain", func() {
+			leaf := newTestCert(ca, inAYear, "shop.example.com")
+
+			_, err := auth.ValidateTLSCertificate("blog.example.com", leaf.certPEM, leaf.keyPEM, ca.certPEM, time.Now())
+			Expect(err).To(MatchError(ContainSubstring("certificate is not valid for 'blog.example.com'")))
+		})
+
+		It("rejects an expired certificate", func() {
+			leaf := newTestCert(ca, time.Now().Add(-time.Minute), "shop.example.com")
+
+			_, err := auth.ValidateTLSCertificate("shop.example.com", leaf.certPEM, leaf.keyPEM, ca.certPEM, time.Now())
+			Expect(err).To(MatchError(ContainSubstring("certificate expired")))
+		})
+
+		It("rejects a certificate of an unknown ca", func() {
+			leaf := newTestCert(ca, inAYear, "shop.example.com")
+
+			_, err := auth.ValidateTLSCertificate("shop.example.com", leaf.certPEM, leaf.keyPEM, "", time.Now())
+			Expect(err).To(MatchError(ContainSubstring("certificate chain does not verify")))
+		})
+
+		It("rejects a key of another certificate", func() {
+			leaf := newTestCert(ca, inAYear, "shop.example.com")
+			other := newTestCert(ca, inAYear, "shop.example.com")
+
+			_, err := auth.ValidateTLSCertificate("shop.example.com", leaf.certPEM, other.keyPEM, ca.certPEM, time.Now())
+			Expect(err).To(MatchError(ContainSubstring("bad certificate or key")))
+		})
+	})
+
+	Describe("DomainCovers", func() {
+		It("covers the domain itself", func() {
+			Expect(auth.DomainCovers("shop.example.com", "shop.example.com")).To(BeTrue())
+			Expect(auth.DomainCovers("shop.example.com", "www.shop.example.com")).To(BeFalse())
+		})
+
+		It("covers the names directly below a wildcard domain", func() {
+			Expect(auth.DomainCovers("*.example.com", "shop.example.com")).To(BeTrue())
+			Expect(auth.DomainCovers("*.example.com", "example.com")).To(BeFalse())
+			Expect(auth.DomainCovers("*.example.com", "www.shop.example.com")).To(BeFalse())
+		})
+	})
+
+	Describe("TLSCertificateFor", func() {
+		certs := []auth.TLSCertificate{
+			{Domain: "*.example.com", NotAfter: inAYear},
+			{Domain: "shop.example.com", NotAfter: inAYear},
+			{Domain: "old.example.com", NotAfter: time.Now().Add(-time.Hour)},
+		}
+
+		It("prefers the certificate of the route over a wildcard", func() {
+			Expect(auth.TLSCertificateFor(certs, "shop.example.com").Domain).To(Equal("shop.example.com"))
+			Expect(auth.TLSCertificateFor(certs, "blog.example.com").Domain).To(Equal("*.example.com"))
+		})
+
+		It("ignores expired certificates", func() {
+			Expect(auth.TLSCertificateFor(certs, "old.example.com").Domain).To(Equal("*.example.com"))
+		})
+
+		It("returns nil for an uncovered route", func() {
+			Expect(auth.TLSCertificateFor(certs, "shop.example.org")).To(BeNil())
+		})
+	})
+})
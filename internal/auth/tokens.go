@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+)
+
+const (
+	// TokenLabel marks the secrets holding API tokens
+	TokenLabel = "epinio.suse.org/api-token"
+	// TokenPrefix starts every token value, making them easy to recognize,
+	// e.g. by secret scanners
+	TokenPrefix = "epn_"
+)
+
+var (
+	// ErrTokenNotKnown is returned for unknown, revoked and expired tokens
+	ErrTokenNotKnown = errors.New("token does not exist")
+	// ErrTokenAlreadyKnown is returned when the user has a token of the same name already
+	ErrTokenAlreadyKnown = errors.New("token already exists")
+)
+
+// Token is a named API token of a user. Only a hash of the token value is
+// stored in the cluster. The value itself is shown once, at creation.
+type Token struct {
+	Name     string
+	Username string
+	Created  time.Time
+	Expires  time.Time // zero for tokens which do not expire
+}
+
+// Expired returns true if the token cannot be used anymore
+func (t *Token) Expired() bool {
+	return !t.Expires.IsZero() && time.Now().After(t.Expires)
+}
+
+// CreateToken stores a new token for the user. A zero ttl creates a token
+// which does not expire. Returns the token value.
+func CreateToken(ctx context.Context, cluster *kubernetes.Cluster, username, name string, ttl time.Duration) (string, *Token, error) {
+	if name == "" {
+		return "", nil, errors.New("token name must not be empty")
+	}
+
+	tokens, err := ListTokens(ctx, cluster, username)
+	if err != nil {
+		return "", nil, err
+	}
+	for _, t := range tokens {
+		if t.Name == name {
+			return "", nil, ErrTokenAlreadyKnown
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", nil, err
+	}
+	value := TokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	token := &Token{
+		Name:     name,
+		Username: username,
+		Created:  time.Now().UTC().Truncate(time.Second),
+	}
+	if ttl > 0 {
+		token.Expires = token.Created.Add(ttl)
+	}
+
+	expires := ""
+	if !token.Expires.IsZero() {
+		expires = token.Expires.Format(time.RFC3339)
+	}
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      tokenSecretName(value),
+			Namespace: UserNamespace,
+			Labels: map[string]string{
+				TokenLabel:                     "true",
+				"app.kubernetes.io/managed-by": "epinio",
+			},
+		},
+		Data: map[string][]byte{
+			"name":     []byte(name),
+			"username": []byte(username),
+			"created":  []byte(token.Created.Format(time.RFC3339)),
+			"expires":  []byte(expires),
+		},
+	}
+
+	_, err = cluster.Kubectl.CoreV1().Secrets(UserNamespace).Create(ctx, secret, metav1.CreateOptions{})
+	if err != nil {
+		return "", nil, err
+	}
+
+	return value, token, nil
+}
+
+// ListTokens returns the tokens of the user, sorted by name
+func ListTokens(ctx context.Context, cluster *kubernetes.Cluster, username string) ([]Token, error) {
+	secrets, err := listTokenSecrets(ctx, cluster, username)
+	if err != nil {
+		return nil, err
+	}
+
+	result := []Token{}
+	for _, secret := range secrets {
+		token, err := tokenFromSecret(&secret)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *token)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, nil
+}
+
+// RevokeToken removes the named token of the user
+func RevokeToken(ctx context.Context, cluster *kubernetes.Cluster, username, name string) error {
+	secrets, err := listTokenSecrets(ctx, cluster, username)
+	if err != nil {
+		return err
+	}
+
+	for _, secret := range secrets {
+		if string(secret.Data["name"]) != name {
+			continue
+		}
+
+		err := cluster.Kubectl.CoreV1().Secrets(UserNamespace).Delete(ctx, secret.Name, metav1.DeleteOptions{})
+		if apierrors.IsNotFound(err) {
+			return ErrTokenNotKnown
+		}
+		return err
+	}
+
+	return ErrTokenNotKnown
+}
+
+// AuthenticateToken returns the user owning the token. It returns
+// ErrTokenNotKnown for unknown and expired tokens alike.
+func AuthenticateToken(ctx context.Context, cluster *kubernetes.Cluster, value string) (*User, error) {
+	if !strings.HasPrefix(value, TokenPrefix) {
+		return nil, ErrTokenNotKnown
+	}
+
+	secret, err := cluster.GetSecret(ctx, UserNamespace, tokenSecretName(value))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrTokenNotKnown
+		}
+		return nil, err
+	}
+
+	token, err := tokenFromSecret(secret)
+	if err != nil {
+		return nil, err
+	}
+	if token.Expired() {
+		return nil, ErrTokenNotKnown
+	}
+
+	user, err := LookupUser(ctx, cluster, token.Username)
+	if err == ErrUserNotKnown {
+		// The tokens of deleted users are void
+		return nil, ErrTokenNotKnown
+	}
+
+	return user, err
+}
+
+func listTokenSecrets(ctx context.Context, cluster *kubernetes.Cluster, username string) ([]corev1.Secret, error) {
+	secrets, err := cluster.Kubectl.CoreV1().Secrets(UserNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: TokenLabel + "=true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []corev1.Secret{}
+	for _, secret := range secrets.Items {
+		if string(secret.Data["username"]) == username {
+			result = append(result, secret)
+		}
+	}
+
+	return result, nil
+}
+
+func tokenFromSecret(secret *corev1.Secret) (*Token, error) {
+	token := &Token{
+		Name:     string(secret.Data["name"]),
+		Username: string(secret.Data["username"]),
+	}
+
+	var err error
+	if created := string(secret.Data["created"]); created != "" {
+		token.Created, err = time.Parse(time.RFC3339, created)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad creation time in secret '%s'", secret.Name)
+		}
+	}
+	if expires := string(secret.Data["expires"]); expires != "" {
+		token.Expires, err = time.Parse(time.RFC3339, expires)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad expiration time in secret '%s'", secret.Name)
+		}
+	}
+
+	return token, nil
+}
+
+// tokenSecretName maps the token value to the name of its secret. Only the
+// hash of the value is stored, and it allows finding the token without
+// scanning all of them.
+func tokenSecretName(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "epinio-token-" + hex.EncodeToString(sum[:])
+}
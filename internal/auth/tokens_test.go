@@ -0,0 +1,28 @@
+package auth_test
+
+import (
+	"time"
+
+	"github.com/epinio/epinio/internal/auth"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Token", func() {
+	Describe("Expired", func() {
+		It("never expires without expiration time", func() {
+			token := &auth.Token{Name: "ci"}
+			Expect(token.Expired()).To(BeFalse())
+		})
+
+		It("expires after the expiration time", func() {
+			token := &auth.Token{Name: "ci", Expires: time.Now().Add(-time.Minute)}
+			Expect(token.Expired()).To(BeTrue())
+		})
+
+		It("is valid before the expiration time", func() {
+			token := &auth.Token{Name: "ci", Expires: time.Now().Add(time.Hour)}
+			Expect(token.Expired()).To(BeFalse())
+		})
+	})
+})
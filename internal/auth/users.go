@@ -0,0 +1,266 @@
+// Package auth collects structures and functions around the
+// generation and processing of credentials.
+package auth
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"golang.org/x/crypto/bcrypt"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+
+	"github.com/epinio/epinio/helpers/kubernetes"
+)
+
+const (
+	// UserNamespace is the namespace holding the secrets of the API users
+	UserNamespace = "epinio"
+	// UserLabel marks the secrets holding API user credentials
+	UserLabel = "epinio.suse.org/api-user-credentials"
+
+	RoleAdmin        = "admin"
+	RoleOrgDeveloper = "org-developer"
+	RoleOrgViewer    = "org-viewer"
+)
+
+// ErrUserNotKnown is returned by the user functions when the named user does not exist
+var ErrUserNotKnown = errors.New("user does not exist")
+
+// User is an API user, as stored in the cluster. The user is either a global
+// admin, or has a role per organization.
+type User struct {
+	Username string
+	Admin    bool
+	Orgs     map[string]string // org name -> role
+}
+
+type CtxUserKey struct{}
+
+// CurrentUser returns the authenticated user from the context. The server
+// injects the user into each authenticated request.
+func CurrentUser(ctx context.Context) *User {
+	user, ok := ctx.Value(CtxUserKey{}).(*User)
+	if !ok {
+		return nil
+	}
+	return user
+}
+
+// ValidOrgRole returns true if the role can be granted to a user for an org
+func ValidOrgRole(role string) bool {
+	return role == RoleOrgDeveloper || role == RoleOrgViewer
+}
+
+// Allowed returns true if the user may perform a request with the given
+// method against the org. Admins may do anything. Developers may do anything
+// in their orgs. Viewers may only read from their orgs.
+func (u *User) Allowed(org, method string) bool {
+	if u.Admin {
+		return true
+	}
+
+	switch u.Orgs[org] {
+	case RoleOrgDeveloper:
+		return true
+	case RoleOrgViewer:
+		return method == http.MethodGet
+	}
+
+	return false
+}
+
+// ListUsers returns all the API users stored in the cluster
+func ListUsers(ctx context.Context, cluster *kubernetes.Cluster) ([]User, error) {
+	secrets, err := cluster.Kubectl.CoreV1().Secrets(UserNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: UserLabel + "=true",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := []User{}
+	for _, secret := range secrets.Items {
+		user, err := userFromSecret(&secret)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, *user)
+	}
+
+	return result, nil
+}
+
+// LookupUser returns the named API user
+func LookupUser(ctx context.Context, cluster *kubernetes.Cluster, username string) (*User, error) {
+	secret, err := cluster.GetSecret(ctx, UserNamespace, userSecretName(username))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrUserNotKnown
+		}
+		return nil, err
+	}
+
+	return userFromSecret(secret)
+}
+
+// Authenticate checks the credentials against the stored users and returns
+// the matching user. It returns ErrUserNotKnown for unknown users and bad
+// passwords alike.
+func Authenticate(ctx context.Context, cluster *kubernetes.Cluster, username, password string) (*User, error) {
+	secret, err := cluster.GetSecret(ctx, UserNamespace, userSecretName(username))
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return nil, ErrUserNotKnown
+		}
+		return nil, err
+	}
+
+	err = bcrypt.CompareHashAndPassword(secret.Data["password"], []byte(password))
+	if err != nil {
+		return nil, ErrUserNotKnown
+	}
+
+	return userFromSecret(secret)
+}
+
+// CreateUser stores a new API user with the given password in the cluster.
+// Users without password cannot log in with basic auth, only with ID tokens
+// of the OIDC issuer.
+func CreateUser(ctx context.Context, cluster *kubernetes.Cluster, username, password string, admin bool) error {
+	hash := ""
+	if password != "" {
+		var err error
+		hash, err = HashBcrypt(password)
+		if err != nil {
+			return err
+		}
+	}
+
+	secret, err := NewUserSecret(username, hash, admin)
+	if err != nil {
+		return err
+	}
+
+	_, err = cluster.Kubectl.CoreV1().Secrets(UserNamespace).Create(ctx, secret, metav1.CreateOptions{})
+	return err
+}
+
+// DeleteUser removes the named API user from the cluster, together with its
+// API tokens
+func DeleteUser(ctx context.Context, cluster *kubernetes.Cluster, username string) error {
+	err := cluster.Kubectl.CoreV1().Secrets(UserNamespace).Delete(ctx, userSecretName(username), metav1.DeleteOptions{})
+	if apierrors.IsNotFound(err) {
+		return ErrUserNotKnown
+	}
+	if err != nil {
+		return err
+	}
+
+	tokens, err := listTokenSecrets(ctx, cluster, username)
+	if err != nil {
+		return err
+	}
+	for _, token := range tokens {
+		err := cluster.Kubectl.CoreV1().Secrets(UserNamespace).Delete(ctx, token.Name, metav1.DeleteOptions{})
+		if err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SetOrgRole grants the role in the org to the named user. An empty role
+// revokes all access to the org.
+func SetOrgRole(ctx context.Context, cluster *kubernetes.Cluster, username, org, role string) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		secret, err := cluster.GetSecret(ctx, UserNamespace, userSecretName(username))
+		if err != nil {
+			if apierrors.IsNotFound(err) {
+				return ErrUserNotKnown
+			}
+			return err
+		}
+
+		user, err := userFromSecret(secret)
+		if err != nil {
+			return err
+		}
+
+		if role == "" {
+			delete(user.Orgs, org)
+		} else {
+			user.Orgs[org] = role
+		}
+
+		orgs, err := json.Marshal(user.Orgs)
+		if err != nil {
+			return err
+		}
+		secret.Data["orgs"] = orgs
+
+		_, err = cluster.Kubectl.CoreV1().Secrets(UserNamespace).Update(ctx, secret, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// NewUserSecret returns the secret holding the credentials of an API user.
+// The password has to be hashed already, see HashBcrypt.
+func NewUserSecret(username, passwordHash string, admin bool) (*corev1.Secret, error) {
+	if username == "" {
+		return nil, errors.New("user name must not be empty")
+	}
+
+	role := ""
+	if admin {
+		role = RoleAdmin
+	}
+
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      userSecretName(username),
+			Namespace: UserNamespace,
+			Labels: map[string]string{
+				UserLabel:                      "true",
+				"app.kubernetes.io/managed-by": "epinio",
+			},
+		},
+		Data: map[string][]byte{
+			"username": []byte(username),
+			"password": []byte(passwordHash),
+			"role":     []byte(role),
+			"orgs":     []byte("{}"),
+		},
+	}, nil
+}
+
+func userFromSecret(secret *corev1.Secret) (*User, error) {
+	user := &User{
+		Username: string(secret.Data["username"]),
+		Admin:    string(secret.Data["role"]) == RoleAdmin,
+		Orgs:     map[string]string{},
+	}
+
+	if orgs, ok := secret.Data["orgs"]; ok && len(orgs) > 0 {
+		if err := json.Unmarshal(orgs, &user.Orgs); err != nil {
+			return nil, errors.Wrapf(err, "bad org roles in secret '%s'", secret.Name)
+		}
+	}
+
+	return user, nil
+}
+
+// userSecretName maps the user name to the name of its secret. User names
+// may contain characters not allowed in resource names, like `@`. The hash
+// keeps the mapping unique and deterministic.
+func userSecretName(username string) string {
+	sum := md5.Sum([]byte(username))
+	return "epinio-user-" + hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,59 @@
+package auth_test
+
+import (
+	"net/http"
+
+	"github.com/epinio/epinio/internal/auth"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("User", func() {
+	Describe("Allowed", func() {
+		user := &auth.User{
+			Username: "contractor",
+			Orgs: map[string]string{
+				"dev":  auth.RoleOrgDeveloper,
+				"prod": auth.RoleOrgViewer,
+			},
+		}
+
+		It("allows everything to admins", func() {
+			admin := &auth.User{Username: "admin", Admin: true}
+			Expect(admin.Allowed("any", http.MethodDelete)).To(BeTrue())
+		})
+
+		It("allows everything in the orgs of a developer", func() {
+			Expect(user.Allowed("dev", http.MethodGet)).To(BeTrue())
+			Expect(user.Allowed("dev", http.MethodPost)).To(BeTrue())
+			Expect(user.Allowed("dev", http.MethodDelete)).To(BeTrue())
+		})
+
+		It("allows only reading in the orgs of a viewer", func() {
+			Expect(user.Allowed("prod", http.MethodGet)).To(BeTrue())
+			Expect(user.Allowed("prod", http.MethodPost)).To(BeFalse())
+			Expect(user.Allowed("prod", http.MethodPatch)).To(BeFalse())
+		})
+
+		It("denies access to other orgs", func() {
+			Expect(user.Allowed("other", http.MethodGet)).To(BeFalse())
+		})
+	})
+
+	Describe("NewUserSecret", func() {
+		It("stores the credentials and the role", func() {
+			secret, err := auth.NewUserSecret("jane@example.com", "hash", true)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(secret.Name).To(MatchRegexp(`^epinio-user-[0-9a-f]+$`))
+			Expect(secret.Labels).To(HaveKeyWithValue(auth.UserLabel, "true"))
+			Expect(string(secret.Data["username"])).To(Equal("jane@example.com"))
+			Expect(string(secret.Data["password"])).To(Equal("hash"))
+			Expect(string(secret.Data["role"])).To(Equal(auth.RoleAdmin))
+		})
+
+		It("rejects an empty user name", func() {
+			_, err := auth.NewUserSecret("", "hash", false)
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
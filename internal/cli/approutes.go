@@ -0,0 +1,95 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CmdAppRoute.AddCommand(CmdAppRouteList)
+	CmdAppRoute.AddCommand(CmdAppRouteAdd)
+	CmdAppRoute.AddCommand(CmdAppRouteRemove)
+}
+
+// CmdAppRoute implements the epinio `apps route` command
+var CmdAppRoute = &cobra.Command{
+	Use:           "route",
+	Aliases:       []string{"routes"},
+	Short:         "Application routes",
+	Long:          `Manage the hostnames an application is reachable at`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.ExactArgs(0),
+}
+
+// CmdAppRouteList implements the epinio `apps route list` command
+var CmdAppRouteList = &cobra.Command{
+	Use:               "list NAME",
+	Short:             "Lists the routes of the application",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRoutes(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing routes")
+		}
+
+		return nil
+	},
+}
+
+// CmdAppRouteAdd implements the epinio `apps route add` command
+var CmdAppRouteAdd = &cobra.Command{
+	Use:   "add NAME ROUTE",
+	Short: "Add a route to the application",
+	Long: `Make the application reachable at the hostname ROUTE, with a certificate of its own.
+For a custom domain the DNS has to resolve ROUTE to the cluster's ingress.`,
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRouteAdd(args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error adding route")
+		}
+
+		return nil
+	},
+}
+
+// CmdAppRouteRemove implements the epinio `apps route remove` command
+var CmdAppRouteRemove = &cobra.Command{
+	Use:               "remove NAME ROUTE",
+	Short:             "Remove a route from the application",
+	Args:              cobra.ExactArgs(2),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRouteRemove(args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error removing route")
+		}
+
+		return nil
+	},
+}
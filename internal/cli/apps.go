@@ -3,7 +3,7 @@ package cli
 import (
 	"context"
 
-	v1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/cli/clients"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
@@ -29,16 +29,25 @@ func init() {
 
 	updateFlags := CmdAppUpdate.Flags()
 	updateFlags.Int32P("instances", "i", 1, "The number of instances the application should have")
-	err := cobra.MarkFlagRequired(updateFlags, "instances")
-	if err != nil {
-		panic(err)
-	}
+	updateFlags.String("staging-policy", "", "What a push does while the application is staging: queue, supersede or reject")
+	updateFlags.String("builder", "", "How the application is staged: buildpacks, dockerfile, or auto to detect it from the sources")
+	buildpacksFlags(updateFlags)
+	settingsFlags(updateFlags)
 
+	CmdApp.AddCommand(CmdAppAutoscale)  // See autoscale.go for implementation
+	CmdApp.AddCommand(CmdAppAutoDeploy) // See autodeploy.go for implementation
 	CmdApp.AddCommand(CmdAppCreate)
-	CmdApp.AddCommand(CmdAppEnv) // See env.go for implementation
+	CmdApp.AddCommand(CmdAppEnv)    // See env.go for implementation
+	CmdApp.AddCommand(CmdAppEvents) // See events.go for implementation
+	CmdApp.AddCommand(CmdAppExec)   // See exec.go for implementation
 	CmdApp.AddCommand(CmdAppList)
 	CmdApp.AddCommand(CmdAppLogs)
+	CmdApp.AddCommand(CmdAppPortForward) // See portforward.go for implementation
+	CmdApp.AddCommand(CmdAppReleases)    // See releases.go for implementation
+	CmdApp.AddCommand(CmdAppRollback)    // See releases.go for implementation
+	CmdApp.AddCommand(CmdAppRoute)       // See approutes.go for implementation
 	CmdApp.AddCommand(CmdAppShow)
+	CmdApp.AddCommand(CmdAppStage) // See staging.go for implementation
 	CmdApp.AddCommand(CmdAppUpdate)
 	CmdApp.AddCommand(CmdDeleteApp)
 	CmdApp.AddCommand(CmdPush) // See push.go for implementation
@@ -187,7 +196,7 @@ var CmdAppLogs = &cobra.Command{
 var CmdAppUpdate = &cobra.Command{
 	Use:   "update NAME",
 	Short: "Update the named application",
-	Long:  "Update the running application's attributes (e.g. instances)",
+	Long:  "Update the application's attributes (e.g. instances, port, probes, resources, staging policy, builder, buildpacks)",
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
@@ -202,11 +211,34 @@ var CmdAppUpdate = &cobra.Command{
 		if err != nil {
 			return errors.Wrap(err, "trouble with instances")
 		}
-		if i == nil {
-			d := v1.DefaultInstances
-			i = &d
+		s, err := settings(cmd)
+		if err != nil {
+			return errors.Wrap(err, "trouble with settings")
+		}
+		policy, err := cmd.Flags().GetString("staging-policy")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --staging-policy")
+		}
+		builder, err := cmd.Flags().GetString("builder")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --builder")
 		}
-		err = client.AppUpdate(args[0], *i)
+		b, err := buildpacks(cmd)
+		if err != nil {
+			return errors.Wrap(err, "trouble with buildpacks")
+		}
+		if i == nil && s == nil && policy == "" && builder == "" && b == nil {
+			cmd.SilenceUsage = false
+			return errors.New("nothing to update, give the instances, a setting, the staging policy, the builder, or the buildpacks")
+		}
+
+		err = client.AppUpdate(args[0], models.UpdateAppRequest{
+			Instances:     i,
+			Settings:      s,
+			StagingPolicy: policy,
+			Builder:       builder,
+			Buildpacks:    b,
+		})
 		if err != nil {
 			return errors.Wrap(err, "error updating the app")
 		}
@@ -0,0 +1,60 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := CmdAppAutoDeploy.Flags()
+	flags.String("branch", models.DefaultAutoDeployBranch, "The branch of the application's repository to deploy")
+	flags.Bool("disable", false, "Stop deploying pushes automatically")
+}
+
+// CmdAppAutoDeploy implements the epinio `apps auto-deploy` command
+var CmdAppAutoDeploy = &cobra.Command{
+	Use:   "auto-deploy NAME",
+	Short: "Deploy pushes to the application's repository automatically",
+	Long: `Deploy pushes to the branch of the application's git repository in Epinio automatically.
+
+Epinio keeps the sources of an application pushed from a directory in a git repository
+of its own. In auto-deploy mode a push to the branch of this repository stages and deploys
+the pushed revision. Pushes of "epinio push" are staged and deployed by it, as before.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		disable, err := cmd.Flags().GetBool("disable")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --disable")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		if disable {
+			err = client.AppAutoDeployDisable(args[0])
+			if err != nil {
+				return errors.Wrap(err, "error disabling auto-deploy")
+			}
+			return nil
+		}
+
+		branch, err := cmd.Flags().GetString("branch")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --branch")
+		}
+
+		err = client.AppAutoDeploy(args[0], branch)
+		if err != nil {
+			return errors.Wrap(err, "error enabling auto-deploy")
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,73 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := CmdAppAutoscale.Flags()
+	flags.Int32("min", 1, "The minimum number of instances")
+	flags.Int32("max", 0, "The maximum number of instances")
+	flags.Int32("cpu-percent", 80, "The average cpu utilization to aim at, in percent of the requested cpu")
+	flags.Bool("disable", false, "Remove the autoscaling, keeping the current number of instances")
+}
+
+// CmdAppAutoscale implements the epinio `apps autoscale` command
+var CmdAppAutoscale = &cobra.Command{
+	Use:   "autoscale NAME",
+	Short: "Scale the application automatically",
+	Long: `Scale the application automatically between the minimum and maximum number of instances,
+aiming at the given average cpu utilization. The utilization is relative to the cpu requested by
+an instance, see the --cpu-request option of "epinio app update".`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		disable, err := cmd.Flags().GetBool("disable")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --disable")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		if disable {
+			err = client.AppAutoscaleDisable(args[0])
+			if err != nil {
+				return errors.Wrap(err, "error disabling autoscaling")
+			}
+			return nil
+		}
+
+		autoscale := models.Autoscale{}
+		autoscale.Min, err = cmd.Flags().GetInt32("min")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --min")
+		}
+		autoscale.Max, err = cmd.Flags().GetInt32("max")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --max")
+		}
+		autoscale.CPUPercent, err = cmd.Flags().GetInt32("cpu-percent")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --cpu-percent")
+		}
+		if !cmd.Flags().Changed("max") {
+			cmd.SilenceUsage = false
+			return errors.New("option --max is required")
+		}
+
+		err = client.AppAutoscale(args[0], autoscale)
+		if err != nil {
+			return errors.Wrap(err, "error autoscaling the app")
+		}
+
+		return nil
+	},
+}
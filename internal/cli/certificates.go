@@ -0,0 +1,123 @@
+package cli
+
+import (
+	"io/ioutil"
+
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdCertificate implements the epinio certificate command
+var CmdCertificate = &cobra.Command{
+	Use:     "certificate",
+	Aliases: []string{"certificates"},
+	Short:   "Epinio TLS certificates",
+	Long: `Manage the TLS certificates of the targeted org.
+
+The routes of a domain with a certificate use it, instead of a certificate
+from the cluster issuer of the installation. A domain like *.example.com
+covers the routes directly below example.com.`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	flags := CmdCertificateUpload.Flags()
+	flags.String("cert", "", "file holding the PEM encoded certificate, followed by its intermediates, if any")
+	flags.String("key", "", "file holding the PEM encoded private key of the certificate")
+	flags.String("ca", "", "file holding the PEM encoded root certificate, if it is not trusted by the system")
+	_ = CmdCertificateUpload.MarkFlagRequired("cert")
+	_ = CmdCertificateUpload.MarkFlagRequired("key")
+
+	CmdCertificate.AddCommand(CmdCertificateList)
+	CmdCertificate.AddCommand(CmdCertificateUpload)
+	CmdCertificate.AddCommand(CmdCertificateDelete)
+}
+
+// CmdCertificateList implements the epinio `certificate list` command
+var CmdCertificateList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the certificates of the targeted org",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Certificates()
+		if err != nil {
+			return errors.Wrap(err, "error listing certificates")
+		}
+
+		return nil
+	},
+}
+
+// CmdCertificateUpload implements the epinio `certificate upload` command
+var CmdCertificateUpload = &cobra.Command{
+	Use:   "upload DOMAIN",
+	Short: "Uploads a certificate for the routes of DOMAIN",
+	Long: `Upload a certificate for the routes of DOMAIN, in the targeted org.
+
+The certificate replaces the one the org has for DOMAIN, if any. It has to
+be valid, name DOMAIN, and chain up to a trusted root.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		pems := map[string]string{}
+		for _, option := range []string{"cert", "key", "ca"} {
+			path, err := cmd.Flags().GetString(option)
+			if err != nil {
+				return errors.Wrapf(err, "could not read option --%s", option)
+			}
+			if path == "" {
+				continue
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return errors.Wrapf(err, "could not read the file of option --%s", option)
+			}
+			pems[option] = string(data)
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.UploadCertificate(args[0], pems["cert"], pems["key"], pems["ca"])
+		if err != nil {
+			return errors.Wrap(err, "error uploading certificate")
+		}
+
+		return nil
+	},
+}
+
+// CmdCertificateDelete implements the epinio `certificate delete` command
+var CmdCertificateDelete = &cobra.Command{
+	Use:   "delete DOMAIN",
+	Short: "Deletes the certificate for the routes of DOMAIN",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.DeleteCertificate(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error deleting certificate")
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,89 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// AppRoutes lists the routes of the named app, in the targeted org
+func (c *EpinioClient) AppRoutes(appName string) error {
+	log := c.Log.WithName("AppRoutes").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Listing routes")
+
+	jsonResponse, err := c.get(api.Routes.Path("AppRoutes", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+
+	var routes []string
+	if err := json.Unmarshal(jsonResponse, &routes); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Route")
+	for _, route := range routes {
+		msg = msg.WithTableRow(fmt.Sprintf("https://%s", route))
+	}
+	msg.Msg("Routes:")
+
+	return nil
+}
+
+// AppRouteAdd adds a route to the named app, in the targeted org
+func (c *EpinioClient) AppRouteAdd(appName, route string) error {
+	log := c.Log.WithName("AppRouteAdd").WithValues("Organization", c.Config.Org, "Application", appName, "Route", route)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Route", route).
+		Msg("Adding route")
+
+	js, err := json.Marshal(models.RouteRequest{Route: route})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("AppRouteAdd", c.Config.Org, appName), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Route added")
+
+	return nil
+}
+
+// AppRouteRemove removes a route from the named app, in the targeted org
+func (c *EpinioClient) AppRouteRemove(appName, route string) error {
+	log := c.Log.WithName("AppRouteRemove").WithValues("Organization", c.Config.Org, "Application", appName, "Route", route)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Route", route).
+		Msg("Removing route")
+
+	_, err := c.delete(api.Routes.Path("AppRouteRemove", c.Config.Org, appName, url.PathEscape(route)))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Route removed")
+
+	return nil
+}
@@ -0,0 +1,58 @@
+package clients
+
+import (
+	"encoding/json"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// AppAutoDeploy turns on the auto-deploy mode of the named app, in the
+// targeted org. Pushes to the branch of its gitea repo are deployed.
+func (c *EpinioClient) AppAutoDeploy(appName, branch string) error {
+	log := c.Log.WithName("AppAutoDeploy").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Branch", branch).
+		Msg("Enable auto-deploy")
+
+	data, err := json.Marshal(models.AutoDeploy{Branch: branch})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("AppAutoDeploy", c.Config.Org, appName), string(data))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Pushes to the branch are deployed automatically")
+
+	return nil
+}
+
+// AppAutoDeployDisable turns off the auto-deploy mode of the named app, in
+// the targeted org
+func (c *EpinioClient) AppAutoDeployDisable(appName string) error {
+	log := c.Log.WithName("AppAutoDeployDisable").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Disable auto-deploy")
+
+	_, err := c.delete(api.Routes.Path("AppAutoDeployDelete", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Auto-deploy disabled")
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// AppAutoscale scales the named app automatically, in the targeted org
+func (c *EpinioClient) AppAutoscale(appName string, autoscale models.Autoscale) error {
+	log := c.Log.WithName("AppAutoscale").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Instances", fmt.Sprintf("%d - %d", autoscale.Min, autoscale.Max)).
+		WithStringValue("CPU", strconv.Itoa(int(autoscale.CPUPercent))+"%").
+		Msg("Autoscale application")
+
+	data, err := json.Marshal(autoscale)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("AppAutoscale", c.Config.Org, appName), string(data))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Application is autoscaled")
+
+	return nil
+}
+
+// AppAutoscaleDisable removes the autoscaling of the named app, in the targeted org
+func (c *EpinioClient) AppAutoscaleDisable(appName string) error {
+	log := c.Log.WithName("AppAutoscaleDisable").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Disable autoscaling")
+
+	_, err := c.delete(api.Routes.Path("AppAutoscaleDelete", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Autoscaling disabled")
+
+	return nil
+}
+
+// autoscaleString returns a description of the autoscaling for display
+func autoscaleString(autoscale *models.Autoscale) string {
+	if autoscale == nil {
+		return "off"
+	}
+	return fmt.Sprintf("%d - %d instances, at %d%% cpu", autoscale.Min, autoscale.Max, autoscale.CPUPercent)
+}
+
+// scaleString returns the current and desired scale of an autoscaled app for display
+func scaleString(autoscale *models.Autoscale) string {
+	cpu := "unknown"
+	if autoscale.CurrentCPUPercent != nil {
+		cpu = strconv.Itoa(int(*autoscale.CurrentCPUPercent)) + "%"
+	}
+	return fmt.Sprintf("%d instances, target %d, at %s cpu", autoscale.CurrentInstances, autoscale.DesiredInstances, cpu)
+}
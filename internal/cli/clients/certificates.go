@@ -0,0 +1,109 @@
+package clients
+
+import (
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// Certificates lists the TLS certificates of the targeted org
+func (c *EpinioClient) Certificates() error {
+	log := c.Log.WithName("Certificates").WithValues("Organization", c.Config.Org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		Msg("Listing certificates")
+
+	jsonResponse, err := c.get(api.Routes.Path("Certificates", c.Config.Org))
+	if err != nil {
+		return err
+	}
+
+	var certs models.CertificateResponseList
+	if err := json.Unmarshal(jsonResponse, &certs); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Domain", "Names", "Issuer", "Expires")
+
+	for _, cert := range certs {
+		expires := cert.NotAfter.Local().Format(time.RFC3339)
+		if cert.NotAfter.Before(time.Now()) {
+			expires += " (expired)"
+		}
+		msg = msg.WithTableRow(cert.Domain, strings.Join(cert.Names, ", "), cert.Issuer, expires)
+	}
+
+	msg.Msg("Epinio Certificates:")
+
+	return nil
+}
+
+// UploadCertificate stores the PEM encoded certificate and key for the
+// routes of the domain, in the targeted org
+func (c *EpinioClient) UploadCertificate(domain, cert, key, ca string) error {
+	log := c.Log.WithName("UploadCertificate").WithValues("Organization", c.Config.Org, "Domain", domain)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Domain", domain).
+		Msg("Uploading certificate...")
+
+	js, err := json.Marshal(models.CertificateCreateRequest{
+		Domain:      domain,
+		Certificate: cert,
+		Key:         key,
+		CA:          ca,
+	})
+	if err != nil {
+		return err
+	}
+
+	jsonResponse, err := c.post(api.Routes.Path("CertificateCreate", c.Config.Org), string(js))
+	if err != nil {
+		return err
+	}
+
+	var response models.CertificateResponse
+	if err := json.Unmarshal(jsonResponse, &response); err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithStringValue("Names", strings.Join(response.Names, ", ")).
+		WithStringValue("Issuer", response.Issuer).
+		WithStringValue("Expires", response.NotAfter.Local().Format(time.RFC3339)).
+		Msg("Certificate uploaded.")
+
+	return nil
+}
+
+// DeleteCertificate removes the certificate for the routes of the domain,
+// in the targeted org
+func (c *EpinioClient) DeleteCertificate(domain string) error {
+	log := c.Log.WithName("DeleteCertificate").WithValues("Organization", c.Config.Org, "Domain", domain)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Domain", domain).
+		Msg("Deleting certificate...")
+
+	_, err := c.delete(api.Routes.Path("CertificateDelete", c.Config.Org, url.PathEscape(domain)))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Certificate deleted.")
+
+	return nil
+}
@@ -2,18 +2,15 @@
 package clients
 
 import (
-	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"io/ioutil"
-	"mime/multipart"
 	"net/http"
 	"os"
-	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -29,13 +26,13 @@ import (
 	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/cli/config"
 	"github.com/epinio/epinio/internal/cli/logprinter"
-	"github.com/epinio/epinio/internal/domain"
 	"github.com/epinio/epinio/internal/duration"
 	"github.com/epinio/epinio/internal/services"
 
 	"github.com/go-logr/logr"
 	"github.com/gorilla/websocket"
 	"github.com/pkg/errors"
+	kubeduration "k8s.io/apimachinery/pkg/util/duration"
 	"k8s.io/apimachinery/pkg/util/validation"
 )
 
@@ -51,10 +48,17 @@ type EpinioClient struct {
 }
 
 type PushParams struct {
-	Instances *int32
-	Services  []string
-	Docker    string
-	GitRev    string
+	Instances      *int32
+	Services       []string
+	Docker         string
+	GitRev         string
+	GitURL         string // External repository, instead of the source
+	GitBranch      string
+	GitCredentials string
+	Routes         []string
+	Environment    models.EnvVariableList
+	Settings       *models.AppSettings
+	ClearCache     bool
 }
 
 func NewEpinioClient(ctx context.Context) (*EpinioClient, error) {
@@ -63,18 +67,31 @@ func NewEpinioClient(ctx context.Context) (*EpinioClient, error) {
 		return nil, err
 	}
 
+	// After `epinio login` the configuration holds the API URL, and the
+	// client works without access to the cluster. The commands still
+	// talking to the cluster directly fail then, see requireCluster.
 	cluster, err := kubernetes.GetCluster(ctx)
 	if err != nil {
-		return nil, err
+		if configConfig.API == "" {
+			return nil, err
+		}
+		cluster = nil
 	}
 
 	uiUI := termui.NewUI()
-	epClient, err := GetEpinioAPIClient(ctx)
-	if err != nil {
-		return nil, err
+
+	var serverURL, wsServerURL string
+	if configConfig.API != "" {
+		serverURL = strings.TrimSuffix(configConfig.API, "/")
+		wsServerURL = websocketURL(serverURL)
+	} else {
+		epClient, err := GetEpinioAPIClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		serverURL = epClient.URL
+		wsServerURL = epClient.WsURL
 	}
-	serverURL := epClient.URL
-	wsServerURL := epClient.WsURL
 
 	logger := tracelog.NewClientLogger()
 	epinioClient := &EpinioClient{
@@ -110,20 +127,20 @@ func (c *EpinioClient) EnvList(ctx context.Context, appName string) error {
 		return err
 	}
 
-	msg := c.ui.Success().WithTable("Variable", "Value")
+	msg := c.ui.Success().WithTable("Variable", "Value", "Scope")
 
 	sort.Sort(eVariables)
 	for _, ev := range eVariables {
-		msg = msg.WithTableRow(ev.Name, ev.Value)
+		msg = msg.WithTableRow(ev.Name, ev.Value, envScope(ev))
 	}
 
 	msg.Msg("Ok")
 	return nil
 }
 
-// EnvUnset adds or modifies the specified environment variable in the
-// named application, with the given value. A workload is restarted.
-func (c *EpinioClient) EnvSet(ctx context.Context, appName, envName, envValue string) error {
+// EnvSet adds or modifies the specified environment variable in the
+// named application, with the given value and scope. A workload is restarted.
+func (c *EpinioClient) EnvSet(ctx context.Context, appName, envName, envValue, scope string) error {
 	log := c.Log.WithName("Env")
 	log.Info("start")
 	defer log.Info("return")
@@ -133,12 +150,14 @@ func (c *EpinioClient) EnvSet(ctx context.Context, appName, envName, envValue st
 		WithStringValue("Application", appName).
 		WithStringValue("Variable", envName).
 		WithStringValue("Value", envValue).
+		WithStringValue("Scope", scope).
 		Msg("Extend or modify application environment")
 
 	request := models.EnvVariableList{
 		models.EnvVariable{
 			Name:  envName,
 			Value: envValue,
+			Scope: scope,
 		},
 	}
 
@@ -181,11 +200,20 @@ func (c *EpinioClient) EnvShow(ctx context.Context, appName, envName string) err
 
 	c.ui.Success().
 		WithStringValue("Value", eVariable.Value).
+		WithStringValue("Scope", envScope(eVariable)).
 		Msg("OK")
 
 	return nil
 }
 
+// envScope returns the scope of the variable for display
+func envScope(ev models.EnvVariable) string {
+	if ev.Scope == "" {
+		return models.EnvScopeBoth
+	}
+	return ev.Scope
+}
+
 // EnvUnset removes the specified environment variable from the named
 // application. A workload is restarted.
 func (c *EpinioClient) EnvUnset(ctx context.Context, appName, envName string) error {
@@ -320,6 +348,10 @@ func (c *EpinioClient) ServicePlanMatching(ctx context.Context, serviceClassName
 	// `Index`/Listing endpoint, either with parameter for
 	// matching, or local matching.
 
+	if c.requireCluster() != nil {
+		return result
+	}
+
 	serviceClass, err := services.ClassLookup(ctx, c.Cluster, serviceClassName)
 	if err != nil {
 		return result
@@ -354,6 +386,10 @@ func (c *EpinioClient) ServiceClassMatching(ctx context.Context, prefix string)
 	// `Index`/Listing endpoint, either with parameter for
 	// matching, or local matching.
 
+	if c.requireCluster() != nil {
+		return result
+	}
+
 	serviceClasses, err := services.ListClasses(ctx, c.Cluster)
 	if err != nil {
 		details.Info("Error", err)
@@ -453,6 +489,10 @@ func (c *EpinioClient) ServiceMatching(ctx context.Context, prefix string) []str
 	// `Index`/Listing endpoint, either with parameter for
 	// matching, or local matching.
 
+	if c.requireCluster() != nil {
+		return result
+	}
+
 	orgServices, err := services.List(ctx, c.Cluster, c.Config.Org)
 	if err != nil {
 		return result
@@ -775,6 +815,10 @@ func (c *EpinioClient) Info() error {
 	log.Info("start")
 	defer log.Info("return")
 
+	if err := c.requireCluster(); err != nil {
+		return err
+	}
+
 	platform := c.Cluster.GetPlatform()
 	kubeVersion, err := c.Cluster.GetVersion()
 	if err != nil {
@@ -818,6 +862,10 @@ func (c *EpinioClient) AppsMatching(ctx context.Context, prefix string) []string
 	// `Index`/Listing endpoint, either with parameter for
 	// matching, or local matching.
 
+	if c.requireCluster() != nil {
+		return result
+	}
+
 	apps, err := application.List(ctx, c.Cluster, c.Config.Org)
 	if err != nil {
 		return result
@@ -865,7 +913,7 @@ func (c *EpinioClient) Apps() error {
 		msg = msg.WithTableRow(
 			app.Name,
 			app.Status,
-			app.Route,
+			strings.Join(app.Routes, ", "),
 			strings.Join(app.BoundServices, ", "))
 	}
 
@@ -897,18 +945,123 @@ func (c *EpinioClient) AppShow(appName string) error {
 		return err
 	}
 
-	c.ui.Success().
+	msg := c.ui.Success().
 		WithTable("Key", "Value").
 		WithTableRow("Status", app.Status).
 		WithTableRow("StageId", app.StageID).
-		WithTableRow("Routes", app.Route).
+		WithTableRow("Routes", strings.Join(app.Routes, ", ")).
 		WithTableRow("Services", strings.Join(app.BoundServices, ", ")).
-		WithTableRow("Environment", `See it by running the command "epinio app env list `+appName+`"`).
-		Msg("Details:")
+		WithTableRow("Environment", `See it by running the command "epinio app env list `+appName+`"`)
+	if app.Settings != nil {
+		msg = msg.
+			WithTableRow("Port", strconv.Itoa(int(app.Settings.ContainerPort()))).
+			WithTableRow("Liveness Probe", probeString(app.Settings.Liveness)).
+			WithTableRow("Readiness Probe", probeString(app.Settings.Readiness)).
+			WithTableRow("Memory", resourceString(app.Settings.Resources.MemoryRequest, app.Settings.Resources.MemoryLimit)).
+			WithTableRow("CPU", resourceString(app.Settings.Resources.CPURequest, app.Settings.Resources.CPULimit))
+	}
+	if app.StagingPolicy != "" {
+		msg = msg.WithTableRow("Staging Policy", app.StagingPolicy)
+	}
+	if app.Builder != "" {
+		msg = msg.WithTableRow("Builder", app.Builder)
+	}
+	if app.Buildpacks != nil {
+		msg = msg.
+			WithTableRow("Builder Image", app.Buildpacks.BuilderImage).
+			WithTableRow("Buildpacks", buildpacksString(app.Buildpacks.Buildpacks))
+	}
+	msg = msg.WithTableRow("Autoscale", autoscaleString(app.Autoscale))
+	if app.Autoscale != nil {
+		msg = msg.WithTableRow("Scale", scaleString(app.Autoscale))
+	}
+	if app.AutoDeploy != nil {
+		msg = msg.WithTableRow("Auto-Deploy", "pushes to "+app.AutoDeploy.Branch)
+	}
+	msg.Msg("Details:")
+
+	if len(app.Instances) > 0 {
+		// The index is the one taken by exec and port-forward, only
+		// running instances have one
+		msg = c.ui.Normal().WithTable("#", "Instance", "Ready", "Phase", "Restarts", "Last Termination", "Node", "Age", "Stage")
+		for _, instance := range app.Instances {
+			index := "-"
+			if instance.Index != nil {
+				index = strconv.Itoa(*instance.Index)
+			}
+			phase := instance.Phase
+			if instance.CrashLoop {
+				phase += " (crash loop)"
+			}
+			msg = msg.WithTableRow(index, instance.Name, strconv.FormatBool(instance.Ready), phase,
+				strconv.Itoa(int(instance.Restarts)), instance.LastTermination, instance.Node,
+				kubeduration.HumanDuration(time.Since(instance.Created)), instance.StageID)
+		}
+		msg.Msg("Instances:")
+
+		for _, instance := range app.Instances {
+			if !instance.CrashLoop {
+				continue
+			}
+			reason := ""
+			if instance.LastTermination != "" {
+				reason = fmt.Sprintf(", the last time with %s", instance.LastTermination)
+			}
+			c.ui.Exclamation().Msg(fmt.Sprintf("Instance %s is crash looping. It restarted %d times%s. See `epinio app logs %s`.",
+				instance.Name, instance.Restarts, reason, appName))
+		}
+	}
 
 	return nil
 }
 
+// buildpacksString returns a description of the buildpacks for display
+func buildpacksString(buildpacks []string) string {
+	if len(buildpacks) == 0 {
+		return "all of the builder"
+	}
+	return strings.Join(buildpacks, ", ")
+}
+
+// probeString returns a description of the probe for display
+func probeString(probe *models.Probe) string {
+	if probe == nil {
+		return "none"
+	}
+
+	result := probe.Type
+	if probe.Path != "" {
+		result += " " + probe.Path
+	}
+	timings := []struct {
+		name  string
+		value int32
+	}{
+		{"delay", probe.InitialDelaySeconds},
+		{"period", probe.PeriodSeconds},
+		{"timeout", probe.TimeoutSeconds},
+		{"failures", probe.FailureThreshold},
+	}
+	for _, t := range timings {
+		if t.value != 0 {
+			result += fmt.Sprintf(", %s=%d", t.name, t.value)
+		}
+	}
+
+	return result
+}
+
+// resourceString returns a description of a resource request and limit for display
+func resourceString(request, limit string) string {
+	if request == "" {
+		request = "-"
+	}
+	if limit == "" {
+		limit = "-"
+	}
+	return fmt.Sprintf("request %s, limit %s", request, limit)
+}
+
 // AppStageID returns the stage id of the named app, in the targeted org
 func (c *EpinioClient) AppStageID(appName string) (string, error) {
 	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
@@ -931,8 +1084,9 @@ func (c *EpinioClient) AppStageID(appName string) (string, error) {
 	return app.StageID, nil
 }
 
-// AppUpdate updates the specified running application's attributes (e.g. instances)
-func (c *EpinioClient) AppUpdate(appName string, instances int32) error {
+// AppUpdate updates the specified application's attributes (e.g. instances,
+// port). Empty fields of the update leave the attribute unchanged.
+func (c *EpinioClient) AppUpdate(appName string, update models.UpdateAppRequest) error {
 	log := c.Log.WithName("Apps").WithValues("Organization", c.Config.Org, "Application", appName)
 	log.Info("start")
 	defer log.Info("return")
@@ -945,9 +1099,7 @@ func (c *EpinioClient) AppUpdate(appName string, instances int32) error {
 
 	details.Info("update application")
 
-	data, err := json.Marshal(models.UpdateAppRequest{
-		Instances: instances,
-	})
+	data, err := json.Marshal(update)
 	if err != nil {
 		return err
 	}
@@ -1005,7 +1157,7 @@ func (c *EpinioClient) AppLogs(appName, stageID string, follow bool, interrupt c
 	urlArgs = append(urlArgs, fmt.Sprintf("stage_id=%s", stageID))
 
 	headers := http.Header{
-		"Authorization": {"Basic " + base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", c.Config.User, c.Config.Password)))},
+		"Authorization": {c.authorization()},
 	}
 
 	var endpoint string
@@ -1148,6 +1300,65 @@ func (c *EpinioClient) DeleteOrg(org string) error {
 	return nil
 }
 
+// ShowOrg shows the details of the named org
+func (c *EpinioClient) ShowOrg(org string) error {
+	log := c.Log.WithName("ShowOrg").WithValues("Organization", org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", org).
+		Msg("Showing organization...")
+
+	jsonResponse, err := c.get(api.Routes.Path("OrgShow", org))
+	if err != nil {
+		return err
+	}
+	var details models.Org
+	if err := json.Unmarshal(jsonResponse, &details); err != nil {
+		return err
+	}
+
+	builderImage := details.Buildpacks.BuilderImage
+	if builderImage == "" {
+		builderImage = "default"
+	}
+
+	c.ui.Success().
+		WithTable("Key", "Value").
+		WithTableRow("Name", details.Name).
+		WithTableRow("Builder Image", builderImage).
+		WithTableRow("Buildpacks", buildpacksString(details.Buildpacks.Buildpacks)).
+		Msg("Details:")
+
+	return nil
+}
+
+// UpdateOrg changes the buildpacks settings the apps of the named org default
+// to
+func (c *EpinioClient) UpdateOrg(org string, buildpacks *models.Buildpacks) error {
+	log := c.Log.WithName("UpdateOrg").WithValues("Organization", org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", org).
+		Msg("Updating organization...")
+
+	data, err := json.Marshal(models.OrgUpdateRequest{Buildpacks: buildpacks})
+	if err != nil {
+		return err
+	}
+	_, err = c.patch(api.Routes.Path("OrgUpdate", org), string(data))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Organization updated.")
+
+	return nil
+}
+
 // Delete removes the named application from the cluster
 func (c *EpinioClient) Delete(ctx context.Context, appname string) error {
 	log := c.Log.WithName("Delete").WithValues("Application", appname)
@@ -1264,6 +1475,14 @@ func (c *EpinioClient) Orgs() error {
 // * deploy
 // * wait for app
 func (c *EpinioClient) Push(ctx context.Context, name, source string, params PushParams) error {
+	name, params, err := withManifest(name, source, params)
+	if err != nil {
+		return err
+	}
+	if name == "" {
+		return errors.New("app name missing, neither given as argument nor found in the manifest")
+	}
+
 	appRef := models.AppRef{Name: name, Org: c.Config.Org}
 	log := c.Log.
 		WithName("Push").
@@ -1275,8 +1494,13 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 	details := log.V(1) // NOTE: Increment of level, not absolute. Visible via TRACE_LEVEL=2
 
 	sourceToShow := source
+	if params.GitURL != "" {
+		sourceToShow = params.GitURL
+	}
 	if params.GitRev != "" {
 		sourceToShow = fmt.Sprintf("%s @ %s", sourceToShow, params.GitRev)
+	} else if params.GitBranch != "" {
+		sourceToShow = fmt.Sprintf("%s @ %s", sourceToShow, params.GitBranch)
 	}
 
 	msg := c.ui.Note().
@@ -1303,11 +1527,6 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 		return fmt.Errorf("%s: %s", "app name incorrect", strings.Join(errorMsgs, "\n"))
 	}
 
-	route, err := appDefaultRoute(ctx, appRef.Name)
-	if err != nil {
-		return errors.Wrap(err, "unable to determine default app route")
-	}
-
 	c.ui.Normal().Msg("Create the application resource ...")
 
 	request := models.ApplicationCreateRequest{Name: appRef.Name}
@@ -1329,21 +1548,64 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 		return err
 	}
 
-	var gitRef *models.GitRef
-	if params.GitRev == "" && params.Docker == "" {
-		c.ui.Normal().Msg("Collecting the application sources ...")
+	if len(params.Environment) > 0 {
+		c.ui.Normal().Msg("Setting the application environment ...")
 
-		tmpDir, tarball, err := collectSources(log, source)
-		defer func() {
-			if tmpDir != "" {
-				_ = os.RemoveAll(tmpDir)
-			}
-		}()
+		js, err := json.Marshal(params.Environment)
 		if err != nil {
 			return err
 		}
 
-		c.ui.Normal().Msg("Uploading application code ...")
+		_, err = c.post(api.Routes.Path("EnvSet", appRef.Org, appRef.Name), string(js))
+		if err != nil {
+			return err
+		}
+	}
+
+	var gitRef *models.GitRef
+	builder := ""
+	git := params.GitRev != "" || params.GitURL != ""
+	if !git && params.Docker == "" {
+		info, err := os.Stat(source)
+		if err != nil {
+			return err
+		}
+
+		tarball := source
+		if info.IsDir() {
+			c.ui.Normal().Msg("Collecting the application sources ...")
+
+			files, ignoreFile, err := sourceFiles(log, source)
+			if err != nil {
+				return err
+			}
+
+			tmpDir, collected, err := collectSources(source, files)
+			defer func() {
+				if tmpDir != "" {
+					_ = os.RemoveAll(tmpDir)
+				}
+			}()
+			if err != nil {
+				return err
+			}
+			tarball = collected
+
+			info, err = os.Stat(tarball)
+			if err != nil {
+				return err
+			}
+			msg := c.ui.Normal()
+			if ignoreFile != "" {
+				msg = msg.WithStringValue("Ignore File", ignoreFile)
+			}
+			msg.Msg(fmt.Sprintf("Uploading application code, %d files and directories, %s ...",
+				len(files), byteSize(info.Size())))
+		} else {
+			// An archive, e.g. a zip file or a Java archive. The
+			// server unpacks it.
+			c.ui.Normal().Msg(fmt.Sprintf("Uploading application archive, %s ...", byteSize(info.Size())))
+		}
 
 		details.Info("upload code")
 		upload, err := c.uploadCode(appRef, tarball)
@@ -1353,10 +1615,17 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 		log.V(3).Info("upload response", "response", upload)
 
 		gitRef = upload.Git
-	} else if params.GitRev != "" {
+		builder = upload.Builder
+	} else if git {
+		// Staged straight from the repository, nothing to upload
 		gitRef = &models.GitRef{
-			URL:      source,
-			Revision: params.GitRev,
+			URL:         source,
+			Revision:    params.GitRev,
+			Branch:      params.GitBranch,
+			Credentials: params.GitCredentials,
+		}
+		if params.GitURL != "" {
+			gitRef.URL = params.GitURL
 		}
 	}
 
@@ -1365,9 +1634,10 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 	if params.Docker == "" {
 		c.ui.Normal().Msg("Staging application ...")
 		req := models.StageRequest{
-			App:   appRef,
-			Git:   gitRef,
-			Route: route,
+			App:        appRef,
+			Git:        gitRef,
+			Builder:    builder,
+			ClearCache: params.ClearCache,
 		}
 		details.Info("staging code", "Git", gitRef.Revision)
 		stageResponse, err = c.stageCode(req)
@@ -1388,8 +1658,9 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 	deployRequest := models.DeployRequest{
 		App:       appRef,
 		Instances: params.Instances,
-		Route:     route,
+		Routes:    params.Routes,
 		Git:       gitRef,
+		Settings:  params.Settings,
 	}
 	// If docker param is specified, then we just take it into ImageURL
 	// If not, we take the one from the staging response
@@ -1400,15 +1671,18 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 		deployRequest.Stage = models.StageRef{ID: stageID}
 	}
 
-	_, err = c.deployCode(deployRequest)
+	deployResponse, err := c.deployCode(deployRequest)
 	if err != nil {
 		return err
 	}
 
-	details.Info("wait for application resources")
-	err = c.waitForApp(ctx, appRef)
-	if err != nil {
-		return errors.Wrap(err, "waiting for app failed")
+	// An app scaled to zero has no instance to wait for
+	if params.Instances == nil || *params.Instances > 0 {
+		details.Info("wait for application resources")
+		err = c.waitForApp(ctx, appRef, stageID)
+		if err != nil {
+			return errors.Wrap(err, "waiting for app failed")
+		}
 	}
 
 	// TODO : This services work should be moved into the stage
@@ -1454,23 +1728,17 @@ func (c *EpinioClient) Push(ctx context.Context, name, source string, params Pus
 		msg.Msg(text)
 	}
 
-	c.ui.Success().
+	msg = c.ui.Success().
 		WithStringValue("Name", appRef.Name).
-		WithStringValue("Organization", appRef.Org).
-		WithStringValue("Route", fmt.Sprintf("https://%s", route)).
-		Msg("App is online.")
+		WithStringValue("Organization", appRef.Org)
+	for _, route := range deployResponse.Routes {
+		msg = msg.WithStringValue("Route", fmt.Sprintf("https://%s", route))
+	}
+	msg.Msg("App is online.")
 
 	return nil
 }
 
-func appDefaultRoute(ctx context.Context, name string) (string, error) {
-	mainDomain, err := domain.MainDomain(ctx)
-	if err != nil {
-		return "", err
-	}
-	return fmt.Sprintf("%s.%s", name, mainDomain), nil
-}
-
 // Target targets an org in gitea
 func (c *EpinioClient) Target(org string) error {
 	log := c.Log.WithName("Target").WithValues("Organization", org)
@@ -1515,6 +1783,10 @@ func (c *EpinioClient) ServicesToApps(ctx context.Context, org string) (map[stri
 
 	var appsOf = map[string]models.AppList{}
 
+	if err := c.requireCluster(); err != nil {
+		return nil, err
+	}
+
 	apps, err := application.List(ctx, c.Cluster, c.Config.Org)
 	if err != nil {
 		return nil, err
@@ -1555,63 +1827,6 @@ func (c *EpinioClient) delete(endpoint string) ([]byte, error) {
 	return c.curl(endpoint, "DELETE", "")
 }
 
-// upload the given path as param "file" in a multipart form
-func (c *EpinioClient) upload(endpoint string, path string) ([]byte, error) {
-	uri := fmt.Sprintf("%s/%s", c.serverURL, endpoint)
-
-	// open the tarball
-	file, err := os.Open(path)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to open tarball")
-	}
-	defer file.Close()
-
-	// create multipart form
-	body := &bytes.Buffer{}
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", filepath.Base(file.Name()))
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to create multiform part")
-	}
-
-	_, err = io.Copy(part, file)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to write to multiform part")
-	}
-
-	err = writer.Close()
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to close multiform")
-	}
-
-	// make the request
-	request, err := http.NewRequest("POST", uri, body)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to build request")
-	}
-
-	request.SetBasicAuth(c.Config.User, c.Config.Password)
-	request.Header.Add("Content-Type", writer.FormDataContentType())
-
-	response, err := (&http.Client{}).Do(request)
-	if err != nil {
-		return nil, errors.Wrap(err, "failed to POST to upload")
-	}
-	defer response.Body.Close()
-
-	bodyBytes, _ := ioutil.ReadAll(response.Body)
-	if response.StatusCode == http.StatusCreated {
-		return bodyBytes, nil
-	}
-
-	if response.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("server status code: %s\n%s", http.StatusText(response.StatusCode), string(bodyBytes))
-	}
-
-	// object was not created, but status was ok?
-	return bodyBytes, nil
-}
-
 func (c *EpinioClient) curl(endpoint, method, requestBody string) ([]byte, error) {
 	uri := fmt.Sprintf("%s/%s", c.serverURL, endpoint)
 	c.Log.Info(fmt.Sprintf("%s %s", method, uri))
@@ -1621,7 +1836,7 @@ func (c *EpinioClient) curl(endpoint, method, requestBody string) ([]byte, error
 		return []byte{}, err
 	}
 
-	request.SetBasicAuth(c.Config.User, c.Config.Password)
+	request.Header.Set("Authorization", c.authorization())
 
 	response, err := (&http.Client{}).Do(request)
 	if err != nil {
@@ -1654,7 +1869,7 @@ func (c *EpinioClient) curlWithCustomErrorHandling(endpoint, method, requestBody
 		return []byte{}, err
 	}
 
-	request.SetBasicAuth(c.Config.User, c.Config.Password)
+	request.Header.Set("Authorization", c.authorization())
 
 	response, err := (&http.Client{}).Do(request)
 	if err != nil {
@@ -1679,6 +1894,32 @@ func (c *EpinioClient) curlWithCustomErrorHandling(endpoint, method, requestBody
 	return bodyBytes, nil
 }
 
+// requireCluster returns an error if the client has no access to the cluster
+func (c *EpinioClient) requireCluster() error {
+	if c.Cluster == nil {
+		return errors.New("this command requires access to the kubernetes cluster")
+	}
+	return nil
+}
+
+// websocketURL returns the websocket URL for the API URL
+func websocketURL(apiURL string) string {
+	if strings.HasPrefix(apiURL, "http://") {
+		return "ws://" + strings.TrimPrefix(apiURL, "http://")
+	}
+	return "wss://" + strings.TrimPrefix(apiURL, "https://")
+}
+
+// authorization returns the value of the `Authorization` header for API
+// requests. An API token in the configuration takes precedence over the user
+// name and password.
+func (c *EpinioClient) authorization() string {
+	if c.Config.Token != "" {
+		return "Bearer " + c.Config.Token
+	}
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Config.User+":"+c.Config.Password))
+}
+
 func uniqueStrings(stringSlice []string) []string {
 	keys := make(map[string]bool)
 	list := []string{}
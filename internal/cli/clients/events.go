@@ -0,0 +1,74 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// eventPollInterval is the time between two queries for new events, when
+// following them
+const eventPollInterval = 2 * time.Second
+
+// AppEvents prints the kubernetes events about the named app and its
+// staging, in the targeted org. When following, it queries the server for new
+// events until interrupted. A repeated event is printed again when its count
+// changes.
+func (c *EpinioClient) AppEvents(appName string, follow bool) error {
+	log := c.Log.WithName("AppEvents").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Showing application events")
+
+	seen := map[string]int32{}
+	for {
+		jsonResponse, err := c.get(api.Routes.Path("AppEvents", c.Config.Org, appName))
+		if err != nil {
+			return err
+		}
+
+		var events models.EventList
+		if err := json.Unmarshal(jsonResponse, &events); err != nil {
+			return err
+		}
+
+		for _, event := range events {
+			if count, ok := seen[event.UID]; ok && count == event.Count {
+				continue
+			}
+			seen[event.UID] = event.Count
+			c.printEvent(event)
+		}
+
+		if !follow {
+			if len(events) == 0 {
+				c.ui.Normal().Msg("No events")
+			}
+			return nil
+		}
+		time.Sleep(eventPollInterval)
+	}
+}
+
+// printEvent prints the event on a line, warnings highlighted
+func (c *EpinioClient) printEvent(event models.Event) {
+	line := fmt.Sprintf("%s  %-8s %-20s %s: %s",
+		event.Time.Local().Format("2006-01-02 15:04:05"),
+		event.Type, event.Reason, event.Object, event.Message)
+	if event.Count > 1 {
+		line = fmt.Sprintf("%s (x%d)", line, event.Count)
+	}
+
+	if event.Type == "Warning" {
+		c.ui.Exclamation().Compact().Msg(line)
+		return
+	}
+	c.ui.Normal().Compact().Msg(line)
+}
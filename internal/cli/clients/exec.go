@@ -0,0 +1,161 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// AppExec runs the command in the numbered instance of the named app, in the
+// targeted org. An empty command runs a shell. The command runs in a terminal
+// when the standard input is one. Returns the exit code of the command.
+func (c *EpinioClient) AppExec(appName string, instance int, command []string) (int, error) {
+	log := c.Log.WithName("AppExec").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	stdin := int(os.Stdin.Fd())
+	tty := term.IsTerminal(stdin)
+
+	query := url.Values{}
+	query.Set("instance", strconv.Itoa(instance))
+	query.Set("tty", strconv.FormatBool(tty))
+	for _, arg := range command {
+		query.Add("command", arg)
+	}
+
+	conn, err := c.dialWebsocket(api.Routes.Path("AppExec", c.Config.Org, appName), query)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var writeLock sync.Mutex
+	send := func(channel byte, data []byte) error {
+		writeLock.Lock()
+		defer writeLock.Unlock()
+		return conn.WriteMessage(websocket.BinaryMessage, append([]byte{channel}, data...))
+	}
+
+	if tty {
+		state, err := term.MakeRaw(stdin)
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to set up the terminal")
+		}
+		defer func() {
+			_ = term.Restore(stdin, state)
+		}()
+
+		done := make(chan struct{})
+		defer close(done)
+		go sendTerminalSize(send, done)
+	}
+
+	go func() {
+		buffer := make([]byte, 4096)
+		for {
+			n, err := os.Stdin.Read(buffer)
+			if n > 0 {
+				if send(models.ExecStdin, buffer[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				// End of input
+				_ = send(models.ExecStdin, nil)
+				return
+			}
+		}
+	}()
+
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			return 0, errors.Wrap(err, "connection closed before the command ended")
+		}
+
+		if kind == websocket.TextMessage {
+			var result models.ExecResult
+			if err := json.Unmarshal(data, &result); err != nil {
+				return 0, err
+			}
+			if result.Error != "" {
+				return result.ExitCode, errors.New(result.Error)
+			}
+			return result.ExitCode, nil
+		}
+
+		if len(data) == 0 {
+			continue
+		}
+		switch data[0] {
+		case models.ExecStdout:
+			_, _ = os.Stdout.Write(data[1:])
+		case models.ExecStderr:
+			_, _ = os.Stderr.Write(data[1:])
+		}
+	}
+}
+
+// sendTerminalSize sends the size of the terminal, and again whenever it
+// changes, until done is closed. The size is polled, as not all platforms
+// signal changes.
+func sendTerminalSize(send func(byte, []byte) error, done chan struct{}) {
+	stdout := int(os.Stdout.Fd())
+	var last models.TerminalSize
+
+	for {
+		width, height, err := term.GetSize(stdout)
+		if err == nil {
+			size := models.TerminalSize{Width: uint16(width), Height: uint16(height)}
+			if size != last {
+				js, err := json.Marshal(size)
+				if err != nil || send(models.ExecResize, js) != nil {
+					return
+				}
+				last = size
+			}
+		}
+
+		select {
+		case <-done:
+			return
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
+
+// dialWebsocket opens a websocket connection to the API endpoint. An API
+// error is returned like those of the other requests.
+func (c *EpinioClient) dialWebsocket(endpoint string, query url.Values) (*websocket.Conn, error) {
+	uri := fmt.Sprintf("%s/%s?%s", c.wsServerURL, endpoint, query.Encode())
+	c.Log.Info(fmt.Sprintf("GET %s", uri))
+
+	headers := http.Header{
+		"Authorization": {c.authorization()},
+	}
+
+	conn, response, err := websocket.DefaultDialer.Dial(uri, headers)
+	if err != nil {
+		if response == nil {
+			return nil, errors.Wrap(err, "failed to connect to websockets endpoint")
+		}
+		defer response.Body.Close()
+		bodyBytes, _ := ioutil.ReadAll(response.Body)
+		return nil, errors.New(fmt.Sprintf("%s: %s", http.StatusText(response.StatusCode), string(bodyBytes)))
+	}
+
+	return conn, nil
+}
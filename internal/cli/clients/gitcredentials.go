@@ -0,0 +1,94 @@
+package clients
+
+import (
+	"encoding/json"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// GitCredentials lists the git credentials of the targeted org
+func (c *EpinioClient) GitCredentials() error {
+	log := c.Log.WithName("GitCredentials").WithValues("Organization", c.Config.Org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		Msg("Listing git credentials")
+
+	jsonResponse, err := c.get(api.Routes.Path("GitCredentials", c.Config.Org))
+	if err != nil {
+		return err
+	}
+
+	var credentials models.GitCredentialsList
+	if err := json.Unmarshal(jsonResponse, &credentials); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Name", "URL", "Username")
+
+	for _, creds := range credentials {
+		msg = msg.WithTableRow(creds.Name, creds.URL, creds.Username)
+	}
+
+	msg.Msg("Epinio Git Credentials:")
+
+	return nil
+}
+
+// CreateGitCredentials stores the named credentials for the git server at
+// the URL, in the targeted org
+func (c *EpinioClient) CreateGitCredentials(name, url, username, password string) error {
+	log := c.Log.WithName("CreateGitCredentials").WithValues("Organization", c.Config.Org, "Name", name)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Name", name).
+		WithStringValue("URL", url).
+		WithStringValue("Username", username).
+		Msg("Creating git credentials...")
+
+	js, err := json.Marshal(models.GitCredentialsCreateRequest{
+		Name:     name,
+		URL:      url,
+		Username: username,
+		Password: password,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("GitCredentialsCreate", c.Config.Org), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Git credentials created.")
+
+	return nil
+}
+
+// DeleteGitCredentials removes the named git credentials of the targeted org
+func (c *EpinioClient) DeleteGitCredentials(name string) error {
+	log := c.Log.WithName("DeleteGitCredentials").WithValues("Organization", c.Config.Org, "Name", name)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Name", name).
+		Msg("Deleting git credentials...")
+
+	_, err := c.delete(api.Routes.Path("GitCredentialsDelete", c.Config.Org, name))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Git credentials deleted.")
+
+	return nil
+}
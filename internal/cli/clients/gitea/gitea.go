@@ -3,10 +3,12 @@ package gitea
 
 import (
 	"context"
+	"net/http"
 
 	giteaSDK "code.gitea.io/sdk/gitea"
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/auth"
 	"github.com/pkg/errors"
 )
@@ -107,3 +109,50 @@ func (c *Client) DeleteOrg(org string) error {
 
 	return err
 }
+
+// RepoExists returns true if the gitea repo of the app exists
+func (c *Client) RepoExists(app models.AppRef) (bool, error) {
+	_, resp, err := c.Client.GetRepo(app.Org, app.Name)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// CreateHook adds a webhook to the gitea repo of the app, posting pushes to
+// the branch to the URL. Gitea signs the payloads with the secret. It
+// returns the ID of the hook.
+func (c *Client) CreateHook(app models.AppRef, url, secret, branch string) (int64, error) {
+	hook, _, err := c.Client.CreateRepoHook(app.Org, app.Name, giteaSDK.CreateHookOption{
+		Type: "gitea",
+		Config: map[string]string{
+			"url":          url,
+			"content_type": "json",
+			"secret":       secret,
+		},
+		Events:       []string{"push"},
+		BranchFilter: branch,
+		Active:       true,
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to create webhook")
+	}
+
+	return hook.ID, nil
+}
+
+// DeleteHook removes the webhook from the gitea repo of the app. A missing
+// hook, or repo, is not an error.
+func (c *Client) DeleteHook(app models.AppRef, id int64) error {
+	resp, err := c.Client.DeleteRepoHook(app.Org, app.Name, id)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to delete webhook")
+	}
+	return nil
+}
@@ -17,7 +17,9 @@ import (
 // Upload puts the app data into the gitea repo and creates the webhook and
 // accompanying app data.
 // The results are added to the struct App.
-func (c *Client) Upload(app models.AppRef, tmpDir string) (models.GitRef, error) {
+// The revision of the app data is passed to pushing before it is pushed, so
+// that the push can be recognized, e.g. by auto-deploy.
+func (c *Client) Upload(app models.AppRef, tmpDir string, pushing func(revision string) error) (models.GitRef, error) {
 	g := models.GitRef{}
 	org := app.Org
 	name := app.Name
@@ -34,7 +36,7 @@ func (c *Client) Upload(app models.AppRef, tmpDir string) (models.GitRef, error)
 	u.User = url.UserPassword(c.Auth.Username, c.Auth.Password)
 	u.Path = path.Join(u.Path, app.Org, app.Name)
 
-	rev, err := c.gitPush(u.String(), tmpDir)
+	rev, err := c.gitPush(u.String(), tmpDir, pushing)
 	if err != nil {
 		return g, errors.Wrap(err, "failed to get latest app commit")
 	}
@@ -72,7 +74,7 @@ func (c *Client) createRepo(org string, name string) error {
 }
 
 // gitPush the app data
-func (c *Client) gitPush(remote string, tmpDir string) (string, error) {
+func (c *Client) gitPush(remote string, tmpDir string, pushing func(revision string) error) (string, error) {
 	cmd := exec.Command("/bin/sh", "-c", fmt.Sprintf(`
 cd "%s" 
 git init
@@ -82,9 +84,8 @@ git remote add epinio "%s"
 git fetch --all
 git reset --soft epinio/main
 git add --all
-git commit -m "pushed at %s"
-git push epinio %s:main
-`, tmpDir, remote, time.Now().Format("20060102150405"), "`git branch --show-current`"))
+git commit -m "pushed at %s" || git diff --cached --quiet
+`, tmpDir, remote, time.Now().Format("20060102150405")))
 
 	_, err := cmd.CombinedOutput()
 	if err != nil {
@@ -103,5 +104,20 @@ git rev-parse HEAD
 	}
 
 	rev := strings.TrimSuffix(string(out), "\n")
+
+	if err := pushing(rev); err != nil {
+		return "", err
+	}
+
+	cmd = exec.Command("/bin/sh", "-c", fmt.Sprintf(`
+cd "%s"
+git push epinio %s:main
+`, tmpDir, "`git branch --show-current`"))
+
+	_, err = cmd.CombinedOutput()
+	if err != nil {
+		return "", errors.Wrap(err, "push script failed")
+	}
+
 	return rev, nil
 }
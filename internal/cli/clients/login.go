@@ -0,0 +1,262 @@
+package clients
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/epinio/epinio/helpers/termui"
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/auth"
+	"github.com/epinio/epinio/internal/cli/config"
+	"github.com/epinio/epinio/internal/oidc"
+	"github.com/pkg/errors"
+	"golang.org/x/term"
+)
+
+// LoginParams configures the login flow
+type LoginParams struct {
+	Username string // use the password grant instead of the device-code flow
+	Password string // prompted for when empty
+	Issuer   string // overrides the issuer advertised by the server
+	ClientID string // overrides the client id advertised by the server
+	TrustCA  bool   // trust the server's certificate without asking
+}
+
+// Login authenticates the user against the OIDC issuer of the Epinio API at
+// the address, and saves the API address, the ID token and the server's
+// certificates in the configuration. The client then talks to the API
+// without access to the cluster.
+func Login(ctx context.Context, address string, params LoginParams) error {
+	ui := termui.NewUI()
+
+	address = strings.TrimSuffix(address, "/")
+	if !strings.Contains(address, "://") {
+		address = "https://" + address
+	}
+
+	ui.Note().
+		WithStringValue("API", address).
+		Msg("Logging in...")
+
+	certs, err := serverCerts(ui, address, params.TrustCA)
+	if err != nil {
+		return err
+	}
+	if certs != "" {
+		auth.ExtendLocalTrust(certs)
+	}
+
+	issuer, clientID, err := authConfig(address)
+	if err != nil {
+		return err
+	}
+	if params.Issuer != "" {
+		issuer = params.Issuer
+	}
+	if params.ClientID != "" {
+		clientID = params.ClientID
+	}
+	if issuer == "" {
+		return errors.New("the server has no OIDC issuer configured, use --issuer to specify one")
+	}
+
+	discovery, err := oidc.Discover(ctx, http.DefaultClient, issuer)
+	if err != nil {
+		return err
+	}
+	client := &oidc.Client{
+		Discovery: discovery,
+		ClientID:  clientID,
+		HTTP:      http.DefaultClient,
+	}
+
+	var token *oidc.Token
+	if params.Username != "" {
+		password := params.Password
+		if password == "" {
+			password, err = askPassword()
+			if err != nil {
+				return err
+			}
+		}
+
+		token, err = client.PasswordGrant(ctx, params.Username, password)
+		if err != nil {
+			return errors.Wrap(err, "login failed")
+		}
+	} else {
+		authorization, err := client.StartDeviceAuthorization(ctx)
+		if err != nil {
+			return err
+		}
+
+		msg := ui.Normal().
+			WithStringValue("Code", authorization.UserCode).
+			WithStringValue("URL", authorization.VerificationURI)
+		if authorization.VerificationURIComplete != "" {
+			msg = msg.WithStringValue("Direct URL", authorization.VerificationURIComplete)
+		}
+		msg.Msg("To log in, open the URL in a browser and enter the code")
+
+		s := ui.Progress("Waiting for confirmation")
+		token, err = client.PollDeviceToken(ctx, authorization)
+		s.Stop()
+		if err != nil {
+			return errors.Wrap(err, "login failed")
+		}
+	}
+
+	claims, err := oidc.UnverifiedClaims(token.IDToken)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	cfg.API = address
+	cfg.Token = token.IDToken
+	cfg.Certs = certs
+	if err := cfg.Save(); err != nil {
+		return errors.Wrap(err, "failed to save configuration")
+	}
+
+	ui.Success().
+		WithStringValue("User", claims.Username()).
+		WithStringValue("API", address).
+		Msg("Logged in. Run `epinio login` again when the token expires.")
+
+	return nil
+}
+
+// authConfig returns the OIDC issuer and client id advertised by the server
+func authConfig(address string) (string, string, error) {
+	response, err := http.Get(fmt.Sprintf("%s/%s", address, api.Routes.Path("AuthConfig")))
+	if err != nil {
+		return "", "", errors.Wrap(err, "failed to contact the server")
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("%s: %s", http.StatusText(response.StatusCode), string(body))
+	}
+
+	var config models.AuthConfigResponse
+	if err := json.Unmarshal(body, &config); err != nil {
+		return "", "", err
+	}
+
+	return config.OIDCIssuer, config.OIDCClientID, nil
+}
+
+// serverCerts returns the PEM encoded certificate chain of the server when
+// it is not trusted by the system already. The user is asked to confirm the
+// trust, unless trust is set.
+func serverCerts(ui *termui.UI, address string, trust bool) (string, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "https" {
+		return "", nil
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+
+	conn, err := tls.Dial("tcp", host, &tls.Config{
+		InsecureSkipVerify: true, // the chain is verified below
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to contact the server")
+	}
+	defer conn.Close()
+
+	chain := conn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return "", errors.New("the server sent no certificate")
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = chain[0].Verify(x509.VerifyOptions{
+		DNSName:       u.Hostname(),
+		Intermediates: intermediates,
+	})
+	if err == nil {
+		return "", nil
+	}
+
+	root := chain[len(chain)-1]
+	if !trust {
+		fingerprint := sha256.Sum256(root.Raw)
+		ui.Exclamation().
+			WithStringValue("Subject", root.Subject.String()).
+			WithStringValue("Issuer", root.Issuer.String()).
+			WithStringValue("SHA256 Fingerprint", fmt.Sprintf("% X", fingerprint)).
+			Msg("The server's certificate is not trusted.")
+
+		if !askTrust() {
+			return "", errors.New("Cancelled by user")
+		}
+	}
+
+	var certs strings.Builder
+	for _, cert := range chain {
+		err := pem.Encode(&certs, &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	return certs.String(), nil
+}
+
+func askTrust() bool {
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Print("Trust this certificate? (y/n): ")
+		s, err := reader.ReadString('\n')
+		if err != nil {
+			return false
+		}
+		switch strings.ToLower(strings.TrimSpace(s)) {
+		case "y":
+			return true
+		case "n":
+			return false
+		}
+	}
+}
+
+func askPassword() (string, error) {
+	fmt.Print("Password: ")
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Println()
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read the password")
+	}
+	return string(password), nil
+}
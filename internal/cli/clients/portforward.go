@@ -0,0 +1,129 @@
+package clients
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"sync"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+// PortMapping pairs a local port with a port of an application instance. A
+// zero local port picks a free one.
+type PortMapping struct {
+	Local  int
+	Remote int
+}
+
+// AppPortForward forwards the local ports on the address to the ports of the
+// numbered instance of the named app, in the targeted org. Each connection to
+// a local port is tunnelled through the API server. It runs until the
+// process is interrupted.
+func (c *EpinioClient) AppPortForward(appName string, instance int, address string, ports []PortMapping) error {
+	log := c.Log.WithName("AppPortForward").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	// Fail early for an unknown app
+	if _, err := c.get(api.Routes.Path("AppShow", c.Config.Org, appName)); err != nil {
+		return err
+	}
+
+	listeners := []net.Listener{}
+	defer func() {
+		for _, listener := range listeners {
+			listener.Close()
+		}
+	}()
+
+	msg := c.ui.Success().WithTable("Local", "Remote")
+	for _, mapping := range ports {
+		listener, err := net.Listen("tcp", net.JoinHostPort(address, strconv.Itoa(mapping.Local)))
+		if err != nil {
+			return errors.Wrap(err, "failed to listen")
+		}
+		listeners = append(listeners, listener)
+		msg = msg.WithTableRow(listener.Addr().String(), strconv.Itoa(mapping.Remote))
+	}
+	msg.Msg(fmt.Sprintf("Forwarding to instance %d of %s. Press Ctrl-C to stop.", instance, appName))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(ports))
+	for i, listener := range listeners {
+		wg.Add(1)
+		go func(listener net.Listener, remote int) {
+			defer wg.Done()
+			for {
+				local, err := listener.Accept()
+				if err != nil {
+					errs <- err
+					return
+				}
+				go c.forward(local, appName, instance, remote)
+			}
+		}(listener, ports[i].Remote)
+	}
+
+	// Stop with the first listener failing
+	err := <-errs
+	for _, listener := range listeners {
+		listener.Close()
+	}
+	wg.Wait()
+
+	return errors.Wrap(err, "failed to accept connections")
+}
+
+// forward tunnels the local connection to the remote port of the app
+// instance, through a websocket of the API server
+func (c *EpinioClient) forward(local net.Conn, appName string, instance, remote int) {
+	defer local.Close()
+
+	query := url.Values{}
+	query.Set("instance", strconv.Itoa(instance))
+	query.Set("port", strconv.Itoa(remote))
+
+	conn, err := c.dialWebsocket(api.Routes.Path("AppPortForward", c.Config.Org, appName), query)
+	if err != nil {
+		c.ui.Problem().Msg(fmt.Sprintf("Forwarding a connection to port %d failed: %s", remote, err.Error()))
+		return
+	}
+	defer conn.Close()
+
+	go func() {
+		buffer := make([]byte, 32*1024)
+		for {
+			n, err := local.Read(buffer)
+			if n > 0 {
+				if conn.WriteMessage(websocket.BinaryMessage, buffer[:n]) != nil {
+					return
+				}
+			}
+			if err != nil {
+				_ = conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""))
+				return
+			}
+		}
+	}()
+
+	for {
+		kind, data, err := conn.ReadMessage()
+		if err != nil {
+			if closeErr, ok := err.(*websocket.CloseError); ok && closeErr.Code != websocket.CloseNormalClosure {
+				c.ui.Problem().Msg(fmt.Sprintf("Connection to port %d failed: %s", remote, closeErr.Text))
+			}
+			return
+		}
+		if kind != websocket.BinaryMessage {
+			continue
+		}
+		if _, err := local.Write(data); err != nil {
+			return
+		}
+	}
+}
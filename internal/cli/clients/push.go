@@ -1,48 +1,199 @@
 package clients
 
 import (
+	"archive/tar"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"os"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/epinio/epinio/deployments"
+	"github.com/avast/retry-go"
 	api "github.com/epinio/epinio/internal/api/v1"
 	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/duration"
+	"github.com/epinio/epinio/internal/ignore"
+	"github.com/epinio/epinio/internal/manifest"
 	"github.com/go-logr/logr"
-	"github.com/mholt/archiver/v3"
 	"github.com/pkg/errors"
-	tekton "github.com/tektoncd/pipeline/pkg/client/clientset/versioned"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/wait"
 )
 
-func collectSources(log logr.Logger, source string) (string, string, error) {
-	files, err := ioutil.ReadDir(source)
-	if err != nil {
-		return "", "", errors.Wrap(err, "cannot read the apps source files")
-	}
-	sources := []string{}
-	for _, f := range files {
-		// The FileInfo entries returned by ReadDir provide
-		// only the base name of the file or directory they
-		// are for. We have to add back the path of the
-		// application directory to get the proper paths to
-		// the files and directories to assemble in the
-		// tarball.
-		// Ignore git config files in the app sources to prevent conflicts with the gitea git repo
-		if f.Name() == ".git" || f.Name() == ".gitignore" || f.Name() == ".gitmodules" || f.Name() == ".gitconfig" || f.Name() == ".git-credentials" {
-			log.V(3).Info(fmt.Sprintf("Skipping upload of file/dir '%s'.", f.Name()))
+// withManifest merges the application manifest found in the source directory,
+// if any, with the name and parameters given by the user. The latter take
+// precedence over the values found in the manifest.
+func withManifest(name, source string, params PushParams) (string, PushParams, error) {
+	if source == "" || params.GitRev != "" {
+		// Docker image or git repository. There is no local
+		// directory to look for a manifest in.
+		return name, params, nil
+	}
+	if info, err := os.Stat(source); err == nil && !info.IsDir() {
+		// Archive. The manifest is not looked for inside of it.
+		return name, params, nil
+	}
+
+	m, err := manifest.Load(source)
+	if err != nil {
+		return name, params, err
+	}
+	if m == nil {
+		return name, params, nil
+	}
+
+	if name == "" {
+		name = m.Name
+	}
+	if params.Instances == nil {
+		params.Instances = m.Instances
+	}
+	if len(params.Services) == 0 {
+		params.Services = m.Services
+	}
+	if len(params.Routes) == 0 {
+		params.Routes = m.Routes
+	}
+	if params.Docker == "" {
+		params.Docker = m.Staging.DockerImageURL
+	}
+
+	// Settings given by the caller override the manifest's settings.
+	if m.AppSettings != (models.AppSettings{}) {
+		settings := m.AppSettings
+		if params.Settings != nil {
+			settings = application.MergeSettings(settings, *params.Settings)
+
+			// Keep the removal of probes and resources for the server
+			if params.Settings.Liveness != nil && settings.Liveness == nil {
+				settings.Liveness = params.Settings.Liveness
+			}
+			if params.Settings.Readiness != nil && settings.Readiness == nil {
+				settings.Readiness = params.Settings.Readiness
+			}
+			resources := []struct{ update, merged *string }{
+				{&params.Settings.Resources.MemoryRequest, &settings.Resources.MemoryRequest},
+				{&params.Settings.Resources.MemoryLimit, &settings.Resources.MemoryLimit},
+				{&params.Settings.Resources.CPURequest, &settings.Resources.CPURequest},
+				{&params.Settings.Resources.CPULimit, &settings.Resources.CPULimit},
+			}
+			for _, r := range resources {
+				if *r.update == models.ResourceNone {
+					*r.merged = models.ResourceNone
+				}
+			}
+		}
+		params.Settings = &settings
+	}
+
+	// Variables given by the caller override the manifest's
+	// variables of the same name.
+	environment := m.EnvVariables()
+	for _, ev := range params.Environment {
+		found := false
+		for i := range environment {
+			if environment[i].Name == ev.Name {
+				environment[i].Value = ev.Value
+				found = true
+				break
+			}
+		}
+		if !found {
+			environment = append(environment, ev)
+		}
+	}
+	params.Environment = environment
+
+	return name, params, nil
+}
+
+// PushFiles lists the files of the application sources which push uploads,
+// without pushing them
+func (c *EpinioClient) PushFiles(ctx context.Context, source string) error {
+	log := c.Log.WithName("PushFiles").WithValues("Sources", source)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Sources", source).
+		Msg("Show the files to upload")
+
+	info, err := os.Stat(source)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		// An archive is uploaded as is
+		c.ui.Success().
+			WithTable("File").
+			WithTableRow(path.Base(source)).
+			WithStringValue("Total", fmt.Sprintf("1 archive, %s", byteSize(info.Size()))).
+			Msg("OK")
+		return nil
+	}
+
+	files, ignoreFile, err := sourceFiles(log, source)
+	if err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("File")
+	size := int64(0)
+	count := 0
+	for _, file := range files {
+		info, err := os.Lstat(path.Join(source, file))
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
 			continue
 		}
-		sources = append(sources, path.Join(source, f.Name()))
+		msg = msg.WithTableRow(file)
+		size += info.Size()
+		count++
+	}
+
+	if ignoreFile != "" {
+		msg = msg.WithStringValue("Ignore File", ignoreFile)
 	}
-	log.V(3).Info("found app data files", "files", sources)
+	msg.WithStringValue("Total", fmt.Sprintf("%d files, %s", count, byteSize(size))).
+		Msg("OK")
 
+	return nil
+}
+
+// sourceFiles returns the relative paths of the files and directories of the
+// app sources to upload, and the name of the ignore file selecting them, if
+// any. See package ignore.
+func sourceFiles(log logr.Logger, source string) ([]string, string, error) {
+	matcher, ignoreFile, err := ignore.Load(source)
+	if err != nil {
+		return nil, "", err
+	}
+	log.V(3).Info("ignore file", "name", ignoreFile)
+
+	files, err := matcher.Files(source)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "cannot read the apps source files")
+	}
+	log.V(3).Info("found app data files", "files", files)
+
+	return files, ignoreFile, nil
+}
+
+// collectSources assembles the given files of the app sources into a
+// tarball. It returns the temp directory holding the tarball, and the
+// tarball.
+func collectSources(source string, files []string) (string, string, error) {
 	// create a tmpDir - tarball dir and POST
 	tmpDir, err := ioutil.TempDir("", "epinio-app")
 	if err != nil {
@@ -50,16 +201,138 @@ func collectSources(log logr.Logger, source string) (string, string, error) {
 	}
 
 	tarball := path.Join(tmpDir, "blob.tar")
-	err = archiver.Archive(sources, tarball)
+	out, err := os.Create(tarball)
 	if err != nil {
 		return tmpDir, "", errors.Wrap(err, "can't create archive")
 	}
+	defer out.Close()
 
-	return tmpDir, tarball, nil
+	tw := tar.NewWriter(out)
+	for _, file := range files {
+		if err := tarFile(tw, source, file); err != nil {
+			return tmpDir, "", errors.Wrap(err, "can't create archive")
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return tmpDir, "", errors.Wrap(err, "can't create archive")
+	}
+
+	return tmpDir, tarball, out.Close()
 }
 
+// tarFile writes the file at the relative path of the sources into the
+// tarball, under that path
+func tarFile(tw *tar.Writer, source, file string) error {
+	full := path.Join(source, file)
+
+	info, err := os.Lstat(full)
+	if err != nil {
+		return err
+	}
+
+	link := ""
+	if info.Mode()&os.ModeSymlink != 0 {
+		link, err = os.Readlink(full)
+		if err != nil {
+			return err
+		}
+	}
+
+	header, err := tar.FileInfoHeader(info, link)
+	if err != nil {
+		return err
+	}
+	header.Name = file
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return nil
+	}
+
+	f, err := os.Open(full)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// byteSize formats the size in bytes for display
+func byteSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
+// uploadChunkSize is the size of the chunks of an upload of app sources
+const uploadChunkSize = 8 * 1024 * 1024
+
+// uploadCode uploads the tarball of the app sources in chunks. A failed chunk
+// is retried from where the server stopped receiving, and sources the server
+// holds already are not uploaded again.
 func (c *EpinioClient) uploadCode(app models.AppRef, tarball string) (*models.UploadResponse, error) {
-	b, err := c.upload(api.Routes.Path("AppUpload", app.Org, app.Name), tarball)
+	digest, size, err := fileDigest(tarball)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read archive")
+	}
+
+	status, err := c.uploadStatus(app, digest)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't upload archive")
+	}
+	if status.Stored != nil {
+		c.ui.Normal().Msg("Application code unchanged, skipping the upload")
+		return status.Stored, nil
+	}
+
+	f, err := os.Open(tarball)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't read archive")
+	}
+	defer f.Close()
+
+	offset := status.Offset
+	err = retry.Do(
+		func() error {
+			for offset < size {
+				offset, err = c.uploadChunk(app, digest, f, offset, size)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		retry.OnRetry(func(n uint, err error) {
+			c.ui.Note().Msgf("Retrying upload (%d/%d) after %s", n, duration.RetryMax, err.Error())
+
+			// Resume where the server stopped receiving
+			if status, err := c.uploadStatus(app, digest); err == nil {
+				offset = status.Offset
+			}
+		}),
+		retry.Delay(time.Second),
+		retry.Attempts(duration.RetryMax),
+		retry.LastErrorOnly(true),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "can't upload archive")
+	}
+
+	b, err := c.post(api.Routes.Path("AppUploadFinish", app.Org, app.Name, digest), "")
 	if err != nil {
 		return nil, errors.Wrap(err, "can't upload archive")
 	}
@@ -73,6 +346,88 @@ func (c *EpinioClient) uploadCode(app models.AppRef, tarball string) (*models.Up
 	return upload, nil
 }
 
+func (c *EpinioClient) uploadStatus(app models.AppRef, digest string) (*models.UploadStatus, error) {
+	b, err := c.get(api.Routes.Path("AppUploadStatus", app.Org, app.Name, digest))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.UploadStatus{}
+	if err := json.Unmarshal(b, status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// uploadChunk sends the chunk of the file at the offset, and returns the
+// offset of the next chunk. Errors of the request itself are not recoverable.
+func (c *EpinioClient) uploadChunk(app models.AppRef, digest string, f *os.File, offset, size int64) (int64, error) {
+	length := size - offset
+	if length > uploadChunkSize {
+		length = uploadChunkSize
+	}
+
+	uri := fmt.Sprintf("%s/%s", c.serverURL, api.Routes.Path("AppUploadChunk", app.Org, app.Name, digest))
+	c.Log.V(1).Info("upload chunk", "uri", uri, "offset", offset, "length", length)
+
+	request, err := http.NewRequest("PATCH", uri, io.NewSectionReader(f, offset, length))
+	if err != nil {
+		return offset, retry.Unrecoverable(err)
+	}
+
+	request.ContentLength = length
+	request.Header.Set("Authorization", c.authorization())
+	request.Header.Set(models.UploadOffsetHeader, strconv.FormatInt(offset, 10))
+	request.Header.Set(models.UploadLengthHeader, strconv.FormatInt(size, 10))
+
+	response, err := (&http.Client{}).Do(request)
+	if err != nil {
+		return offset, err
+	}
+	defer response.Body.Close()
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return offset, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		err := errors.New(fmt.Sprintf("%s: %s", http.StatusText(response.StatusCode), string(bodyBytes)))
+
+		// A conflict is an offset mismatch, resolved by resuming from
+		// the offset of the server. Server errors may be transient.
+		if response.StatusCode != http.StatusConflict && response.StatusCode < http.StatusInternalServerError {
+			return offset, retry.Unrecoverable(err)
+		}
+		return offset, err
+	}
+
+	status := models.UploadStatus{}
+	if err := json.Unmarshal(bodyBytes, &status); err != nil {
+		return offset, err
+	}
+
+	return status.Offset, nil
+}
+
+// fileDigest returns the hex encoded sha256 digest and the size of the file
+func fileDigest(file string) (string, int64, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer f.Close()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, f)
+	if err != nil {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), size, nil
+}
+
 func (c *EpinioClient) stageCode(req models.StageRequest) (*models.StageResponse, error) {
 	out, err := json.Marshal(req)
 	if err != nil {
@@ -120,7 +475,7 @@ func (c *EpinioClient) stageLogs(ctx context.Context, details logr.Logger, appRe
 	return err
 }
 
-func (c *EpinioClient) deployCode(req models.DeployRequest) ([]byte, error) {
+func (c *EpinioClient) deployCode(req models.DeployRequest) (*models.DeployResponse, error) {
 	out, err := json.Marshal(req)
 	if err != nil {
 		return nil, errors.Wrap(err, "can't marshal deploy request")
@@ -131,50 +486,115 @@ func (c *EpinioClient) deployCode(req models.DeployRequest) ([]byte, error) {
 		return nil, errors.Wrap(err, "can't deploy app")
 	}
 
-	return b, nil
+	// returns the routes of the app
+	deploy := &models.DeployResponse{}
+	if err := json.Unmarshal(b, deploy); err != nil {
+		return nil, err
+	}
+
+	return deploy, nil
 }
 
+// waitForPipelineRun waits for the staging run to finish. It fails as soon
+// as the server reports the run as failed, with the failed step, its exit
+// code and the last lines of its log. The time a run waits in the staging
+// queue of the app does not count against the timeout.
 func (c *EpinioClient) waitForPipelineRun(ctx context.Context, app models.AppRef, id string) error {
 	c.ui.ProgressNote().KeeplineUnder(1).Msg("Running staging")
 
-	cs, err := tekton.NewForConfig(c.Cluster.RestConfig)
-	if err != nil {
-		return err
-	}
-	client := cs.TektonV1beta1().PipelineRuns(deployments.TektonStagingNamespace)
+	deadline := time.Now().Add(duration.ToAppBuilt())
+	position := 0
 
-	return wait.PollImmediate(time.Second, duration.ToAppBuilt(),
+	return wait.PollImmediateInfinite(time.Second,
 		func() (bool, error) {
-			l, err := client.List(ctx, metav1.ListOptions{LabelSelector: models.EpinioStageIDLabel + "=" + id})
+			status, err := c.stageStatus(app.Org, id)
 			if err != nil {
 				return false, err
 			}
-			if len(l.Items) == 0 {
+
+			switch status.Status {
+			case models.StageQueued:
+				deadline = time.Now().Add(duration.ToAppBuilt())
+				if status.Position != position {
+					position = status.Position
+					c.ui.Normal().Msgf("Staging is queued behind other staging of the application, at position %d", position)
+				}
 				return false, nil
+			case models.StageSucceeded:
+				return true, nil
+			case models.StageFailed:
+				return false, stageFailure(status)
+			case models.StageCancelled:
+				return false, errors.New("staging was cancelled, e.g. superseded by another push")
 			}
-			for _, pr := range l.Items {
-				// any failed conditions, throw an error so we can exit early
-				for _, c := range pr.Status.Conditions {
-					if c.IsFalse() {
-						return false, errors.New(c.Message)
-					}
-				}
-				// it worked
-				if pr.Status.CompletionTime != nil {
-					return true, nil
-				}
+
+			if time.Now().After(deadline) {
+				return false, wait.ErrWaitTimeout
 			}
-			// pr exists, but still running
 			return false, nil
 		})
 }
 
-func (c *EpinioClient) waitForApp(ctx context.Context, app models.AppRef) error {
+func (c *EpinioClient) stageStatus(org, id string) (*models.StageStatus, error) {
+	b, err := c.get(api.Routes.Path("StagingStatus", org, id))
+	if err != nil {
+		return nil, err
+	}
+
+	status := &models.StageStatus{}
+	if err := json.Unmarshal(b, status); err != nil {
+		return nil, err
+	}
+
+	return status, nil
+}
+
+// stageFailure returns the error describing the failed staging run
+func stageFailure(status *models.StageStatus) error {
+	reason := "staging failed"
+	if status.Step != "" {
+		reason = fmt.Sprintf("staging failed in step '%s' of task '%s', with exit code %d",
+			status.Step, status.Task, status.ExitCode)
+	} else if status.Task != "" {
+		reason = fmt.Sprintf("staging failed in task '%s'", status.Task)
+	}
+	if status.Message != "" {
+		reason = fmt.Sprintf("%s: %s", reason, status.Message)
+	}
+	if len(status.Logs) > 0 {
+		reason = fmt.Sprintf("%s\nLast lines of the step log:\n  %s", reason, strings.Join(status.Logs, "\n  "))
+	}
+
+	return errors.New(reason)
+}
+
+// waitForApp waits for an instance of the deployed stage of the app to be
+// ready. Without stage, i.e. for an image, any ready instance will do. The
+// status is polled from the server, the cluster is not accessed.
+func (c *EpinioClient) waitForApp(ctx context.Context, app models.AppRef, stageID string) error {
 	c.ui.ProgressNote().KeeplineUnder(1).Msg("Creating application resources")
 
-	err := c.Cluster.WaitForDeploymentCompleted(
-		ctx,
-		c.ui, app.Org, app.Name, duration.ToAppBuilt())
+	s := c.ui.Progressf("Waiting for application %s in %s to be ready", app.Name, app.Org)
+	defer s.Stop()
+
+	err := wait.PollImmediate(time.Second, duration.ToAppBuilt(), func() (bool, error) {
+		b, err := c.get(api.Routes.Path("AppShow", app.Org, app.Name))
+		if err != nil {
+			return false, err
+		}
+
+		var show models.App
+		if err := json.Unmarshal(b, &show); err != nil {
+			return false, err
+		}
+
+		for _, instance := range show.Instances {
+			if instance.Ready && (stageID == "" || instance.StageID == stageID) {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
 	if err != nil {
 		return errors.Wrap(err, "waiting for app to come online failed")
 	}
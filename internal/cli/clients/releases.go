@@ -0,0 +1,98 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// AppReleases lists the releases of the named app, in the targeted org
+func (c *EpinioClient) AppReleases(appName string) error {
+	log := c.Log.WithName("AppReleases").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Listing releases")
+
+	jsonResponse, err := c.get(api.Routes.Path("AppReleases", c.Config.Org, appName))
+	if err != nil {
+		return err
+	}
+
+	var releases models.ReleaseList
+	if err := json.Unmarshal(jsonResponse, &releases); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Release", "Created", "User", "Revision", "Image", "Note")
+
+	for _, release := range releases {
+		revision := ""
+		if release.Git != nil {
+			revision = release.Git.Revision
+			if revision == "" && release.Git.Branch != "" {
+				revision = "head of " + release.Git.Branch
+			}
+		}
+		note := ""
+		if release.RollbackOf != 0 {
+			note = fmt.Sprintf("rollback to %d", release.RollbackOf)
+		}
+		msg = msg.WithTableRow(
+			strconv.Itoa(release.ID),
+			release.Created.Local().Format(time.RFC3339),
+			release.User,
+			revision,
+			release.ImageURL,
+			note)
+	}
+
+	msg.Msg("Releases:")
+
+	return nil
+}
+
+// AppRollback deploys an earlier release of the named app again. A zero
+// release selects the release before the current one.
+func (c *EpinioClient) AppRollback(appName string, release int) error {
+	log := c.Log.WithName("AppRollback").WithValues("Organization", c.Config.Org, "Application", appName, "Release", release)
+	log.Info("start")
+	defer log.Info("return")
+
+	msg := c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName)
+	if release != 0 {
+		msg = msg.WithStringValue("Release", strconv.Itoa(release))
+	}
+	msg.Msg("Rolling back application")
+
+	out, err := json.Marshal(models.RollbackRequest{Release: release})
+	if err != nil {
+		return err
+	}
+
+	jsonResponse, err := c.post(api.Routes.Path("AppRollback", c.Config.Org, appName), string(out))
+	if err != nil {
+		return err
+	}
+
+	var current models.Release
+	if err := json.Unmarshal(jsonResponse, &current); err != nil {
+		return err
+	}
+
+	c.ui.Success().
+		WithStringValue("Release", strconv.Itoa(current.ID)).
+		WithStringValue("Image", current.ImageURL).
+		Msg(fmt.Sprintf("Rolled back to release %d", current.RollbackOf))
+
+	return nil
+}
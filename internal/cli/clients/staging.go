@@ -0,0 +1,94 @@
+package clients
+
+import (
+	"encoding/json"
+	"strconv"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+)
+
+// AppStaging lists the unfinished staging runs of the named app, in the
+// targeted org
+func (c *EpinioClient) AppStaging(appName string) error {
+	log := c.Log.WithName("AppStaging").WithValues("Organization", c.Config.Org, "Application", appName)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		Msg("Listing stagings")
+
+	queue, err := c.stagingQueue(appName)
+	if err != nil {
+		return err
+	}
+
+	if len(queue) == 0 {
+		c.ui.Normal().Msg("The application is not staging")
+		return nil
+	}
+
+	msg := c.ui.Success().WithTable("Stage ID", "Status", "Queue Position")
+	for _, status := range queue {
+		position := ""
+		if status.Position > 0 {
+			position = strconv.Itoa(status.Position)
+		}
+		msg = msg.WithTableRow(status.ID, status.Status, position)
+	}
+	msg.Msg("Stagings:")
+
+	return nil
+}
+
+// AppStageCancel cancels the staging run with the given ID of the named app,
+// in the targeted org. Without ID it cancels the current staging of the app.
+func (c *EpinioClient) AppStageCancel(appName, stageID string) error {
+	log := c.Log.WithName("AppStageCancel").WithValues("Organization", c.Config.Org, "Application", appName, "StageID", stageID)
+	log.Info("start")
+	defer log.Info("return")
+
+	if stageID == "" {
+		queue, err := c.stagingQueue(appName)
+		if err != nil {
+			return err
+		}
+		if len(queue) == 0 {
+			return errors.New("the application is not staging")
+		}
+		stageID = queue[0].ID
+	}
+
+	c.ui.Note().
+		WithStringValue("Organization", c.Config.Org).
+		WithStringValue("Application", appName).
+		WithStringValue("Stage ID", stageID).
+		Msg("Cancelling staging")
+
+	if _, err := c.delete(api.Routes.Path("StagingCancel", c.Config.Org, stageID)); err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Staging cancelled.")
+
+	return nil
+}
+
+// stagingQueue returns the unfinished staging runs of the named app, oldest
+// first
+func (c *EpinioClient) stagingQueue(appName string) ([]models.StageStatus, error) {
+	jsonResponse, err := c.get(api.Routes.Path("AppStaging", c.Config.Org, appName))
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []models.StageStatus
+	if err := json.Unmarshal(jsonResponse, &queue); err != nil {
+		return nil, err
+	}
+
+	return queue, nil
+}
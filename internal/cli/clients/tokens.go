@@ -0,0 +1,115 @@
+package clients
+
+import (
+	"encoding/json"
+	"time"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// Tokens lists the API tokens of the current user
+func (c *EpinioClient) Tokens() error {
+	log := c.Log.WithName("Tokens")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Listing tokens")
+
+	jsonResponse, err := c.get(api.Routes.Path("Tokens"))
+	if err != nil {
+		return err
+	}
+
+	var tokens models.TokenResponseList
+	if err := json.Unmarshal(jsonResponse, &tokens); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Name", "Created", "Expires")
+
+	for _, token := range tokens {
+		expires := "never"
+		if token.Expires != nil {
+			expires = token.Expires.Local().Format(time.RFC3339)
+			if token.Expires.Before(time.Now()) {
+				expires += " (expired)"
+			}
+		}
+		msg = msg.WithTableRow(token.Name, token.Created.Local().Format(time.RFC3339), expires)
+	}
+
+	msg.Msg("Epinio API Tokens:")
+
+	return nil
+}
+
+// CreateToken creates a new API token for the current user. With save set
+// the token is stored in the configuration, and used by all further commands.
+func (c *EpinioClient) CreateToken(name, expiresIn string, save bool) error {
+	log := c.Log.WithName("CreateToken").WithValues("Token", name)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", name).
+		Msg("Creating token...")
+
+	js, err := json.Marshal(models.TokenCreateRequest{
+		Name:      name,
+		ExpiresIn: expiresIn,
+	})
+	if err != nil {
+		return err
+	}
+
+	jsonResponse, err := c.post(api.Routes.Path("TokenCreate"), string(js))
+	if err != nil {
+		return err
+	}
+
+	var token models.TokenResponse
+	if err := json.Unmarshal(jsonResponse, &token); err != nil {
+		return err
+	}
+
+	if save {
+		c.Config.Token = token.Token
+		if err := c.Config.Save(); err != nil {
+			return err
+		}
+	}
+
+	expires := "never"
+	if token.Expires != nil {
+		expires = token.Expires.Local().Format(time.RFC3339)
+	}
+
+	c.ui.Success().
+		WithStringValue("Token", token.Token).
+		WithStringValue("Expires", expires).
+		WithBoolValue("Saved", save).
+		Msg("Token created. Keep it safe, it cannot be shown again.")
+
+	return nil
+}
+
+// RevokeToken revokes the named API token of the current user
+func (c *EpinioClient) RevokeToken(name string) error {
+	log := c.Log.WithName("RevokeToken").WithValues("Token", name)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", name).
+		Msg("Revoking token...")
+
+	_, err := c.delete(api.Routes.Path("TokenDelete", name))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Token revoked.")
+
+	return nil
+}
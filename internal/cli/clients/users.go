@@ -0,0 +1,146 @@
+package clients
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	api "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/api/v1/models"
+)
+
+// Users lists the API users and their roles
+func (c *EpinioClient) Users() error {
+	log := c.Log.WithName("Users")
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().Msg("Listing users")
+
+	jsonResponse, err := c.get(api.Routes.Path("Users"))
+	if err != nil {
+		return err
+	}
+
+	var users models.UserResponseList
+	if err := json.Unmarshal(jsonResponse, &users); err != nil {
+		return err
+	}
+
+	msg := c.ui.Success().WithTable("Name", "Admin", "Organizations")
+
+	for _, user := range users {
+		orgs := []string{}
+		for org, role := range user.Orgs {
+			orgs = append(orgs, fmt.Sprintf("%s (%s)", org, role))
+		}
+		sort.Strings(orgs)
+
+		msg = msg.WithTableRow(user.Username, fmt.Sprintf("%t", user.Admin), strings.Join(orgs, ", "))
+	}
+
+	msg.Msg("Epinio Users:")
+
+	return nil
+}
+
+// CreateUser creates a new API user
+func (c *EpinioClient) CreateUser(username, password string, admin bool) error {
+	log := c.Log.WithName("CreateUser").WithValues("User", username)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		WithBoolValue("Admin", admin).
+		Msg("Creating user...")
+
+	request := models.UserCreateRequest{
+		Username: username,
+		Password: password,
+		Admin:    admin,
+	}
+
+	js, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("UserCreate"), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("User created.")
+
+	return nil
+}
+
+// DeleteUser deletes an API user
+func (c *EpinioClient) DeleteUser(username string) error {
+	log := c.Log.WithName("DeleteUser").WithValues("User", username)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		Msg("Deleting user...")
+
+	_, err := c.delete(api.Routes.Path("UserDelete", username))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("User deleted.")
+
+	return nil
+}
+
+// GrantRole grants a role in the org to the user
+func (c *EpinioClient) GrantRole(username, org, role string) error {
+	log := c.Log.WithName("GrantRole").WithValues("User", username, "Organization", org, "Role", role)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		WithStringValue("Organization", org).
+		WithStringValue("Role", role).
+		Msg("Granting role...")
+
+	js, err := json.Marshal(models.UserRoleRequest{Role: role})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.post(api.Routes.Path("UserRoleSet", username, org), string(js))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Role granted.")
+
+	return nil
+}
+
+// RevokeRole revokes the user's access to the org
+func (c *EpinioClient) RevokeRole(username, org string) error {
+	log := c.Log.WithName("RevokeRole").WithValues("User", username, "Organization", org)
+	log.Info("start")
+	defer log.Info("return")
+
+	c.ui.Note().
+		WithStringValue("Name", username).
+		WithStringValue("Organization", org).
+		Msg("Revoking role...")
+
+	_, err := c.delete(api.Routes.Path("UserRoleUnset", username, org))
+	if err != nil {
+		return err
+	}
+
+	c.ui.Success().Msg("Role revoked.")
+
+	return nil
+}
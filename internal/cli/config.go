@@ -92,12 +92,18 @@ var CmdConfigShow = &cobra.Command{
 			certInfo = color.BlueString("Present")
 		}
 
+		tokenInfo := color.CyanString("None defined")
+		if theConfig.Token != "" {
+			tokenInfo = color.BlueString("Present")
+		}
+
 		ui.Success().
 			WithTable("Key", "Value").
 			WithTableRow("Colorized Output", color.MagentaString("%t", theConfig.Colors)).
 			WithTableRow("Current Organization", color.CyanString(theConfig.Org)).
 			WithTableRow("API User Name", color.BlueString(theConfig.User)).
 			WithTableRow("API Password", color.BlueString(theConfig.Password)).
+			WithTableRow("API Token", tokenInfo).
 			WithTableRow("Certificates", certInfo).
 			Msg("Ok")
 
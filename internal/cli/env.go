@@ -25,6 +25,8 @@ func init() {
 	CmdAppEnv.AddCommand(CmdEnvSet)
 	CmdAppEnv.AddCommand(CmdEnvShow)
 	CmdAppEnv.AddCommand(CmdEnvUnset)
+
+	CmdEnvSet.Flags().String("scope", "", "where the variable is passed to: build, runtime or both (default both, or the current scope)")
 }
 
 // CmdEnvList implements the `epinio apps env list` command
@@ -68,8 +70,13 @@ var CmdEnvList = &cobra.Command{
 var CmdEnvSet = &cobra.Command{
 	Use:   "set APPNAME NAME VALUE",
 	Short: "Extend application environment",
-	Long:  "Add or change environment variable of named application",
-	Args:  cobra.ExactArgs(3),
+	Long: `Add or change environment variable of named application.
+
+Variables are passed to both the staging and the running app by default. Use
+--scope build to pass a variable only to the staging, e.g. for build tooling
+credentials, and --scope runtime to keep it out of the staging, e.g. for
+production secrets. Changing a variable without --scope keeps its scope.`,
+	Args: cobra.ExactArgs(3),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
@@ -79,7 +86,12 @@ var CmdEnvSet = &cobra.Command{
 			return errors.Wrap(err, "error initializing cli")
 		}
 
-		err = client.EnvSet(cmd.Context(), args[0], args[1], args[2])
+		scope, err := cmd.Flags().GetString("scope")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --scope")
+		}
+
+		err = client.EnvSet(cmd.Context(), args[0], args[1], args[2], scope)
 		if err != nil {
 			return errors.Wrap(err, "error setting into app environment")
 		}
@@ -0,0 +1,44 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CmdAppEvents.Flags().Bool("follow", false, "keep watching for new events")
+}
+
+// CmdAppEvents implements the epinio `apps events` command
+var CmdAppEvents = &cobra.Command{
+	Use:   "events NAME",
+	Short: "Shows the kubernetes events of the application",
+	Long: `Show the kubernetes events about the application, oldest first.
+
+These are the events of its deployment, replica sets, instances, ingress and
+route certificates, and of the pipeline runs, task runs and pods staging it.
+They help to find out why a push or an instance failed.`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		follow, err := cmd.Flags().GetBool("follow")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --follow")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppEvents(args[0], follow)
+		if err != nil {
+			return errors.Wrap(err, "error showing events")
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,55 @@
+package cli
+
+import (
+	"os"
+
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CmdAppExec.Flags().IntP("instance", "n", 0, "index of the running instance to run the command in, as shown by epinio app show")
+}
+
+// CmdAppExec implements the epinio `apps exec` command
+var CmdAppExec = &cobra.Command{
+	Use:   "exec NAME [-- COMMAND [ARG...]]",
+	Short: "Run a command in an application instance",
+	Long: `Run COMMAND in a running instance of the application, a shell by default.
+
+The command runs in a terminal when the input is one, for interactive use.
+Otherwise input and output can be redirected. The exit status of epinio is
+the one of the command.`,
+	Example: `  epinio app exec myapp
+  epinio app exec myapp --instance 1 -- ls -l /workspace`,
+	Args:              cobra.MinimumNArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		if dash := cmd.ArgsLenAtDash(); dash > 1 {
+			return errors.New("expected the command after `--`")
+		}
+
+		instance, err := cmd.Flags().GetInt("instance")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --instance")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		exitCode, err := client.AppExec(args[0], instance, args[1:])
+		if err != nil {
+			return errors.Wrap(err, "error running command")
+		}
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+
+		return nil
+	},
+}
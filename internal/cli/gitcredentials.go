@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdGitCredentials implements the epinio git-credentials command
+var CmdGitCredentials = &cobra.Command{
+	Use:   "git-credentials",
+	Short: "Epinio git credentials",
+	Long: `Manage the git credentials of the targeted org.
+
+Apps pushed with --git-url and --git-credentials are cloned from their
+external git repository with the named credentials, see "epinio push".`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	flags := CmdGitCredentialsCreate.Flags()
+	flags.String("username", "", "user name at the git server")
+	flags.String("password", "", "password, or access token, of the user at the git server")
+	_ = CmdGitCredentialsCreate.MarkFlagRequired("username")
+	_ = CmdGitCredentialsCreate.MarkFlagRequired("password")
+
+	CmdGitCredentials.AddCommand(CmdGitCredentialsList)
+	CmdGitCredentials.AddCommand(CmdGitCredentialsCreate)
+	CmdGitCredentials.AddCommand(CmdGitCredentialsDelete)
+}
+
+// CmdGitCredentialsList implements the epinio `git-credentials list` command
+var CmdGitCredentialsList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the git credentials of the targeted org",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.GitCredentials()
+		if err != nil {
+			return errors.Wrap(err, "error listing git credentials")
+		}
+
+		return nil
+	},
+}
+
+// CmdGitCredentialsCreate implements the epinio `git-credentials create` command
+var CmdGitCredentialsCreate = &cobra.Command{
+	Use:   "create NAME URL",
+	Short: "Creates git credentials NAME for the git server at URL",
+	Long: `Create git credentials NAME for the git server at URL, in the targeted org.
+
+URL is the http(s) URL of the git server, e.g. https://github.com. The
+credentials are used for all repositories below it.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		username, err := cmd.Flags().GetString("username")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --username")
+		}
+
+		password, err := cmd.Flags().GetString("password")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --password")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.CreateGitCredentials(args[0], args[1], username, password)
+		if err != nil {
+			return errors.Wrap(err, "error creating git credentials")
+		}
+
+		return nil
+	},
+}
+
+// CmdGitCredentialsDelete implements the epinio `git-credentials delete` command
+var CmdGitCredentialsDelete = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Deletes the git credentials NAME",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.DeleteGitCredentials(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error deleting git credentials")
+		}
+
+		return nil
+	},
+}
@@ -87,6 +87,34 @@ var NeededOptions = kubernetes.InstallationOptions{
 		Default:     true,
 		Value:       true,
 	},
+	{
+		Name:        "build-cache-size",
+		Description: "The size of the build cache of each application, e.g. 2Gi. 0 disables the cache.",
+		Type:        kubernetes.StringType,
+		Default:     "2Gi",
+		Value:       "2Gi",
+	},
+	{
+		Name:        "upload-max-size",
+		Description: "The maximum size of uploaded application sources, e.g. 1Gi. 0 disables the limit.",
+		Type:        kubernetes.StringType,
+		Default:     "1Gi",
+		Value:       "1Gi",
+	},
+	{
+		Name:        "oidc-issuer",
+		Description: "The URL of an OpenID Connect issuer whose ID tokens the API accepts. Enables `epinio login`.",
+		Type:        kubernetes.StringType,
+		Default:     "",
+		Value:       "",
+	},
+	{
+		Name:        "oidc-client-id",
+		Description: "The client id of epinio at the OpenID Connect issuer",
+		Type:        kubernetes.StringType,
+		Default:     "epinio",
+		Value:       "epinio",
+	},
 }
 
 var TraefikOptions = kubernetes.InstallationOptions{
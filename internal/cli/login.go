@@ -1 +1,61 @@
 package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := CmdLogin.Flags()
+	flags.StringP("username", "u", "", "log in with user name and password, instead of the device-code flow")
+	flags.StringP("password", "p", "", "the password, for use with --username. Prompted for when not given")
+	flags.String("issuer", "", "the OIDC issuer, overriding the issuer advertised by the API server")
+	flags.String("client-id", "", "the OIDC client id, overriding the client id advertised by the API server")
+	flags.Bool("trust-ca", false, "trust the API server's certificate without asking")
+}
+
+// CmdLogin implements the epinio login command
+var CmdLogin = &cobra.Command{
+	Use:   "login API-URL",
+	Short: "Log in to an Epinio API server",
+	Long: `Log in to an Epinio API server, via the OpenID Connect issuer it trusts.
+
+By default the device-code flow is used: The command shows a URL and a code
+to confirm in a browser. With --username the password grant is used instead.
+
+The API URL, the resulting token and the server's certificates are saved in
+the configuration. Further commands then talk to the API server, without
+access to the kubernetes cluster.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		var params clients.LoginParams
+		var err error
+
+		flags := cmd.Flags()
+		if params.Username, err = flags.GetString("username"); err != nil {
+			return errors.Wrap(err, "could not read option --username")
+		}
+		if params.Password, err = flags.GetString("password"); err != nil {
+			return errors.Wrap(err, "could not read option --password")
+		}
+		if params.Issuer, err = flags.GetString("issuer"); err != nil {
+			return errors.Wrap(err, "could not read option --issuer")
+		}
+		if params.ClientID, err = flags.GetString("client-id"); err != nil {
+			return errors.Wrap(err, "could not read option --client-id")
+		}
+		if params.TrustCA, err = flags.GetBool("trust-ca"); err != nil {
+			return errors.Wrap(err, "could not read option --trust-ca")
+		}
+
+		err = clients.Login(cmd.Context(), args[0], params)
+		if err != nil {
+			return errors.Wrap(err, "error logging in")
+		}
+
+		return nil
+	},
+}
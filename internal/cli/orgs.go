@@ -30,8 +30,12 @@ func init() {
 	flags := CmdOrgDelete.Flags()
 	flags.BoolVarP(&force, "force", "f", false, "force org deletion")
 
+	buildpacksFlags(CmdOrgUpdate.Flags())
+
 	CmdOrg.AddCommand(CmdOrgCreate)
 	CmdOrg.AddCommand(CmdOrgList)
+	CmdOrg.AddCommand(CmdOrgShow)
+	CmdOrg.AddCommand(CmdOrgUpdate)
 	CmdOrg.AddCommand(CmdOrgDelete)
 }
 
@@ -78,6 +82,63 @@ var CmdOrgCreate = &cobra.Command{
 	},
 }
 
+// CmdOrgShow implements the epinio `orgs show` command
+var CmdOrgShow = &cobra.Command{
+	Use:   "show NAME",
+	Short: "Shows the details of an organization",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.ShowOrg(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error showing org")
+		}
+
+		return nil
+	},
+}
+
+// CmdOrgUpdate implements the epinio `orgs update` command
+var CmdOrgUpdate = &cobra.Command{
+	Use:   "update NAME",
+	Short: "Updates an organization",
+	Long: `Update the defaults of the applications of the organization.
+
+The builder image and the buildpacks given are used by the applications which
+do not pin their own, see "epinio app update". The value default resets them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		b, err := buildpacks(cmd)
+		if err != nil {
+			return errors.Wrap(err, "trouble with buildpacks")
+		}
+		if b == nil {
+			cmd.SilenceUsage = false
+			return errors.New("nothing to update, give the builder image, or the buildpacks")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.UpdateOrg(args[0], b)
+		if err != nil {
+			return errors.Wrap(err, "error updating org")
+		}
+
+		return nil
+	},
+}
+
 // CmdOrgDelete implements the epinio `orgs delete` command
 var CmdOrgDelete = &cobra.Command{
 	Use:   "delete NAME",
@@ -0,0 +1,96 @@
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	flags := CmdAppPortForward.Flags()
+	flags.IntP("instance", "n", 0, "index of the running instance to forward to, as shown by epinio app show")
+	flags.String("address", "127.0.0.1", "local address to listen on")
+}
+
+// CmdAppPortForward implements the epinio `apps port-forward` command
+var CmdAppPortForward = &cobra.Command{
+	Use:   "port-forward NAME [LOCAL:]REMOTE...",
+	Short: "Forward local ports to an application instance",
+	Long: `Forward local ports to the ports of a running instance of the application,
+bypassing its routes. The connections are tunnelled through the Epinio API
+server, no access to the cluster is needed.
+
+A port without LOCAL part is forwarded from the same local port. A LOCAL port
+of 0 picks a free one.`,
+	Example: `  epinio app port-forward myapp 8080
+  epinio app port-forward myapp 9000:8080 0:9090 --instance 1`,
+	Args:              cobra.MinimumNArgs(2),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		instance, err := cmd.Flags().GetInt("instance")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --instance")
+		}
+		address, err := cmd.Flags().GetString("address")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --address")
+		}
+
+		ports := []clients.PortMapping{}
+		for _, arg := range args[1:] {
+			mapping, err := ParsePortMapping(arg)
+			if err != nil {
+				return err
+			}
+			ports = append(ports, mapping)
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppPortForward(args[0], instance, address, ports)
+		if err != nil {
+			return errors.Wrap(err, "error forwarding ports")
+		}
+
+		return nil
+	},
+}
+
+// ParsePortMapping parses a `[LOCAL:]REMOTE` port mapping
+func ParsePortMapping(spec string) (clients.PortMapping, error) {
+	local, remote := spec, spec
+	if parts := strings.SplitN(spec, ":", 2); len(parts) == 2 {
+		local, remote = parts[0], parts[1]
+	}
+
+	localPort, err := parsePort(local, 0)
+	if err != nil {
+		return clients.PortMapping{}, errors.Wrapf(err, "bad local port in '%s'", spec)
+	}
+	remotePort, err := parsePort(remote, 1)
+	if err != nil {
+		return clients.PortMapping{}, errors.Wrapf(err, "bad remote port in '%s'", spec)
+	}
+
+	return clients.PortMapping{Local: localPort, Remote: remotePort}, nil
+}
+
+func parsePort(value string, min int) (int, error) {
+	port, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, errors.New("not a number")
+	}
+	if port < min || port > 65535 {
+		return 0, fmt.Errorf("%d out of range", port)
+	}
+	return port, nil
+}
@@ -0,0 +1,34 @@
+package cli_test
+
+import (
+	"github.com/epinio/epinio/internal/cli"
+	"github.com/epinio/epinio/internal/cli/clients"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParsePortMapping", func() {
+	It("forwards a single port from the same local port", func() {
+		mapping, err := cli.ParsePortMapping("8080")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mapping).To(Equal(clients.PortMapping{Local: 8080, Remote: 8080}))
+	})
+
+	It("parses a local and a remote port", func() {
+		mapping, err := cli.ParsePortMapping("0:9090")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mapping).To(Equal(clients.PortMapping{Local: 0, Remote: 9090}))
+	})
+
+	It("rejects a bad port", func() {
+		_, err := cli.ParsePortMapping("9000:http")
+		Expect(err).To(MatchError("bad remote port in '9000:http': not a number"))
+
+		_, err = cli.ParsePortMapping("70000:80")
+		Expect(err).To(MatchError("bad local port in '70000:80': 70000 out of range"))
+
+		_, err = cli.ParsePortMapping("0")
+		Expect(err).To(MatchError("bad remote port in '0': 0 out of range"))
+	})
+})
@@ -17,8 +17,14 @@ func init() {
 	CmdPush.Flags().Int32P("instances", "i", v1.DefaultInstances,
 		"The number of desired instances for the application, default only applies to new deployments")
 	CmdPush.Flags().String("git", "", "git revision of sources. PATH becomes repository location")
+	CmdPush.Flags().String("git-url", "", "url of an external git repository to stage the sources from")
+	CmdPush.Flags().String("branch", "", "git branch to stage the head of, instead of a revision")
+	CmdPush.Flags().String("git-credentials", "", "git credentials of the org to clone the repository with")
 	CmdPush.Flags().String("docker-image-url", "", "docker image url for the app workload image")
 	CmdPush.Flags().StringSliceP("bind", "b", []string{}, "services to bind immediately")
+	CmdPush.Flags().Bool("clear-cache", false, "build without the build cache of the application, and replace it")
+	CmdPush.Flags().Bool("show-files", false, "only list the files to upload, and do not push")
+	settingsFlags(CmdPush.Flags())
 	CmdPush.RegisterFlagCompletionFunc("bind",
 		func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			// `cmd`, `args` are ignored.
@@ -80,9 +86,30 @@ func instances(cmd *cobra.Command) (*int32, error) {
 
 // CmdPush implements the epinio push command
 var CmdPush = &cobra.Command{
-	Use:   "push NAME [URL|PATH_TO_APPLICATION_SOURCES]",
+	Use:   "push [NAME] [URL|PATH_TO_APPLICATION_SOURCES|PATH_TO_ARCHIVE]",
 	Short: "Push an application from the specified directory, or the current working directory",
-	Args:  cobra.RangeArgs(1, 2),
+	Long: `Push an application from the specified directory, or the current working directory.
+
+When the application sources contain a manifest file, "epinio.yml", it provides
+the name, instances, environment, services, routes and staging configuration of
+the application. Arguments and options given on the command line override the
+values found in the manifest.
+
+Files matching the patterns of the ".epinioignore" file in the sources are not
+uploaded. Sources without it use their ".cfignore", or their ".gitignore". The
+patterns follow the gitignore syntax. Use --show-files to list the files to
+upload, without pushing.
+
+Instead of a directory the sources can be an archive, i.e. a tar, tar.gz or
+zip archive, or a built Java archive, i.e. a jar or war file. The server
+unpacks it for staging.
+
+With --git-url the sources are staged straight from an external git
+repository, without an upload. Staging clones the revision given by --git,
+or else the head of the --branch, or of the default branch. Repositories
+which are not public are cloned with the git credentials of the org named by
+--git-credentials, see "epinio git-credentials".`,
+	Args: cobra.RangeArgs(0, 2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		cmd.SilenceUsage = true
 
@@ -96,23 +123,52 @@ var CmdPush = &cobra.Command{
 			return errors.Wrap(err, "could not read option --git")
 		}
 
+		gitURL, err := cmd.Flags().GetString("git-url")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --git-url")
+		}
+
+		gitBranch, err := cmd.Flags().GetString("branch")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --branch")
+		}
+
+		gitCredentials, err := cmd.Flags().GetString("git-credentials")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --git-credentials")
+		}
+
 		dockerImageURL, err := cmd.Flags().GetString("docker-image-url")
 		if err != nil {
 			return errors.Wrap(err, "could not read option --docker-image-url")
 		}
 
-		if gitRevision != "" && dockerImageURL != "" {
-			return errors.Wrap(err, "cannot use both, git and docker image url")
+		git := gitRevision != "" || gitURL != ""
+		if git && dockerImageURL != "" {
+			return errors.New("cannot use both, git and docker image url")
+		}
+		if !git && (gitBranch != "" || gitCredentials != "") {
+			return errors.New("--branch and --git-credentials require a git repository, see --git-url")
 		}
 
 		// Syntax:
 		// 1. push NAME
 		// 2. push NAME PATH
 		// 3. push NAME URL --git REV
-		// 4. push NAME --docker-image-url URL
+		// 4. push NAME --git-url URL [--git REV | --branch BRANCH] [--git-credentials CREDS]
+		// 5. push NAME --docker-image-url URL
+		// 6. push (NAME from the manifest in the working directory)
 
-		var path string
-		if len(args) == 1 {
+		var name, path string
+		if len(args) > 0 {
+			name = args[0]
+		}
+		if gitURL != "" {
+			if len(args) > 1 {
+				cmd.SilenceUsage = false
+				return errors.New("cannot use both, git repository url and PATH")
+			}
+		} else if len(args) < 2 {
 			if gitRevision != "" {
 				// Missing argument is user error. Show usage
 				cmd.SilenceUsage = false
@@ -127,11 +183,11 @@ var CmdPush = &cobra.Command{
 			path = args[1]
 		}
 
-		if dockerImageURL != "" {
+		if dockerImageURL != "" || gitURL != "" {
 			path = ""
 		}
 
-		if gitRevision == "" && dockerImageURL == "" {
+		if !git && dockerImageURL == "" {
 			if _, err := os.Stat(path); err != nil {
 				// Path issue is user error. Show usage
 				cmd.SilenceUsage = false
@@ -139,14 +195,33 @@ var CmdPush = &cobra.Command{
 			}
 		}
 
+		showFiles, err := cmd.Flags().GetBool("show-files")
+		if err != nil {
+			return errors.Wrap(err, "failed to read option --show-files")
+		}
+		if showFiles {
+			if git || dockerImageURL != "" {
+				return errors.New("--show-files requires local application sources")
+			}
+
+			err = client.PushFiles(cmd.Context(), path)
+			if err != nil {
+				return errors.Wrap(err, "error listing the files to upload")
+			}
+			return nil
+		}
+
 		i, err := instances(cmd)
 		if err != nil {
 			return errors.Wrap(err, "trouble with instances")
 		}
 		params := clients.PushParams{
-			Instances: i,
-			GitRev:    gitRevision,
-			Docker:    dockerImageURL,
+			Instances:      i,
+			GitRev:         gitRevision,
+			GitURL:         gitURL,
+			GitBranch:      gitBranch,
+			GitCredentials: gitCredentials,
+			Docker:         dockerImageURL,
 		}
 
 		services, err := cmd.Flags().GetStringSlice("bind")
@@ -155,7 +230,17 @@ var CmdPush = &cobra.Command{
 		}
 		params.Services = services
 
-		err = client.Push(cmd.Context(), args[0], path, params)
+		params.Settings, err = settings(cmd)
+		if err != nil {
+			return errors.Wrap(err, "trouble with settings")
+		}
+
+		params.ClearCache, err = cmd.Flags().GetBool("clear-cache")
+		if err != nil {
+			return errors.Wrap(err, "failed to read option --clear-cache")
+		}
+
+		err = client.Push(cmd.Context(), name, path, params)
 		if err != nil {
 			return errors.Wrap(err, "error pushing app to server")
 		}
@@ -0,0 +1,85 @@
+package cli
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdAppReleases implements the epinio `apps releases` command
+var CmdAppReleases = &cobra.Command{
+	Use:               "releases NAME",
+	Short:             "Lists the releases of the application",
+	Long:              "Lists the releases of the application, i.e. its recorded deployments, oldest first",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppReleases(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing releases")
+		}
+
+		return nil
+	},
+}
+
+// CmdAppRollback implements the epinio `apps rollback` command
+var CmdAppRollback = &cobra.Command{
+	Use:   "rollback NAME [RELEASE]",
+	Short: "Roll the application back to an earlier release",
+	Long: `Deploy the image and environment of an earlier release of the application again, without staging.
+Without RELEASE the application goes back to the release before the current one.`,
+	Args:              cobra.RangeArgs(1, 2),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		release := 0
+		if len(args) > 1 {
+			var err error
+			release, err = strconv.Atoi(args[1])
+			if err != nil || release < 1 {
+				return errors.Errorf("bad release '%s', expected a release number", args[1])
+			}
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppRollback(args[0], release)
+		if err != nil {
+			return errors.Wrap(err, "error rolling back the app")
+		}
+
+		return nil
+	},
+}
+
+// matchingAppsFinder completes the first argument with the names of the
+// applications in the targeted org
+func matchingAppsFinder(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	app, err := clients.NewEpinioClient(context.Background())
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+
+	matches := app.AppsMatching(context.Background(), toComplete)
+
+	return matches, cobra.ShellCompDirectiveNoFileComp
+}
@@ -87,6 +87,11 @@ func init() {
 	rootCmd.AddCommand(CmdDisable)
 	rootCmd.AddCommand(CmdService)
 	rootCmd.AddCommand(CmdServer)
+	rootCmd.AddCommand(CmdUser)
+	rootCmd.AddCommand(CmdToken)
+	rootCmd.AddCommand(CmdCertificate)
+	rootCmd.AddCommand(CmdGitCredentials)
+	rootCmd.AddCommand(CmdLogin)
 	rootCmd.AddCommand(cmdVersion)
 }
 
@@ -16,9 +16,11 @@ import (
 	"sync"
 
 	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/termui"
 	"github.com/epinio/epinio/helpers/tracelog"
 	apiv1 "github.com/epinio/epinio/internal/api/v1"
+	"github.com/epinio/epinio/internal/application"
 	"github.com/epinio/epinio/internal/filesystem"
 	"github.com/epinio/epinio/internal/web"
 	"github.com/go-logr/logr"
@@ -40,6 +42,22 @@ func init() {
 	flags.Bool("use-internal-registry-node-port", true, "(USE_INTERNAL_REGISTRY_NODE_PORT) Use the internal registry via a node port")
 	viper.BindPFlag("use-internal-registry-node-port", flags.Lookup("use-internal-registry-node-port"))
 	viper.BindEnv("use-internal-registry-node-port", "USE_INTERNAL_REGISTRY_NODE_PORT")
+
+	flags.String("build-cache-size", "2Gi", "(BUILD_CACHE_SIZE) The size of the build cache of each application. 0 disables the cache")
+	viper.BindPFlag("build-cache-size", flags.Lookup("build-cache-size"))
+	viper.BindEnv("build-cache-size", "BUILD_CACHE_SIZE")
+
+	flags.String("upload-max-size", "1Gi", "(UPLOAD_MAX_SIZE) The maximum size of uploaded application sources. 0 disables the limit")
+	viper.BindPFlag("upload-max-size", flags.Lookup("upload-max-size"))
+	viper.BindEnv("upload-max-size", "UPLOAD_MAX_SIZE")
+
+	flags.String("oidc-issuer", "", "(OIDC_ISSUER) The OpenID Connect issuer whose ID tokens are accepted. Leave empty to disable")
+	viper.BindPFlag("oidc-issuer", flags.Lookup("oidc-issuer"))
+	viper.BindEnv("oidc-issuer", "OIDC_ISSUER")
+
+	flags.String("oidc-client-id", "epinio", "(OIDC_CLIENT_ID) The client id the accepted ID tokens are issued for")
+	viper.BindPFlag("oidc-client-id", flags.Lookup("oidc-client-id"))
+	viper.BindEnv("oidc-client-id", "OIDC_CLIENT_ID")
 }
 
 // CmdServer implements the epinio server command
@@ -58,6 +76,17 @@ var CmdServer = &cobra.Command{
 			return errors.Wrap(err, "failed to start server")
 		}
 		ui.Normal().Msg("listening on localhost on port " + listeningPort)
+
+		if err := apiv1.UploadsExpire(); err != nil {
+			logger.Error(err, "failed to remove the expired uploads")
+		}
+
+		cluster, err := kubernetes.GetCluster(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "failed to get access to a kube client")
+		}
+		go application.RunStagingQueue(cmd.Context(), cluster, logger.WithName("StagingQueue"))
+
 		httpServerWg.Wait()
 
 		return nil
@@ -74,7 +103,11 @@ func startEpinioServer(wg *sync.WaitGroup, port int, ui *termui.UI, logger logr.
 	listeningPort := elements[len(elements)-1]
 
 	http.Handle("/api/v1/", logRequestHandler(apiv1.Router(), logger))
-	http.Handle("/", logRequestHandler(web.Router(), logger))
+	http.Handle("/", logRequestHandler(apiv1.Authenticate(web.Router()), logger))
+	// Unauthenticated health check, for the probes of the server deployment
+	http.HandleFunc("/ready", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
 	// Static files
 	var assetsDir http.FileSystem
 	if os.Getenv("LOCAL_FILESYSTEM") == "true" {
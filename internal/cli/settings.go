@@ -0,0 +1,176 @@
+package cli
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// settingsFlags adds the options for the runtime settings of an app
+func settingsFlags(flags *pflag.FlagSet) {
+	flags.Int32("port", 0, "The port the application listens on (default 8080)")
+	flags.String("liveness", "", "The liveness probe: http:PATH, tcp or none, optionally followed by ,delay=N,period=N,timeout=N,failures=N")
+	flags.String("readiness", "", "The readiness probe, in the format of --liveness")
+	flags.String("memory-request", "", "The memory requested by each instance, e.g. 256Mi, or none")
+	flags.String("memory-limit", "", "The memory limit of each instance, e.g. 1Gi, or none")
+	flags.String("cpu-request", "", "The cpu requested by each instance, e.g. 250m, or none")
+	flags.String("cpu-limit", "", "The cpu limit of each instance, e.g. 1, or none")
+}
+
+// settings returns the runtime settings given by the user, or nil when the
+// user gave none.
+func settings(cmd *cobra.Command) (*models.AppSettings, error) {
+	flags := cmd.Flags()
+	result := &models.AppSettings{}
+	changed := false
+
+	if flags.Changed("port") {
+		port, err := flags.GetInt32("port")
+		if err != nil {
+			return nil, errors.Wrap(err, "could not read option --port")
+		}
+		if port < 1 || port > 65535 {
+			return nil, errors.Errorf("port %d out of range", port)
+		}
+		result.Port = port
+		changed = true
+	}
+
+	probes := []struct {
+		name  string
+		probe **models.Probe
+	}{
+		{"liveness", &result.Liveness},
+		{"readiness", &result.Readiness},
+	}
+	for _, p := range probes {
+		if !flags.Changed(p.name) {
+			continue
+		}
+		spec, err := flags.GetString(p.name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read option --%s", p.name)
+		}
+		probe, err := ParseProbe(spec)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad option --%s", p.name)
+		}
+		*p.probe = probe
+		changed = true
+	}
+
+	resources := []struct {
+		name  string
+		value *string
+	}{
+		{"memory-request", &result.Resources.MemoryRequest},
+		{"memory-limit", &result.Resources.MemoryLimit},
+		{"cpu-request", &result.Resources.CPURequest},
+		{"cpu-limit", &result.Resources.CPULimit},
+	}
+	for _, r := range resources {
+		if !flags.Changed(r.name) {
+			continue
+		}
+		value, err := flags.GetString(r.name)
+		if err != nil {
+			return nil, errors.Wrapf(err, "could not read option --%s", r.name)
+		}
+		*r.value = value
+		changed = true
+	}
+
+	if !changed {
+		return nil, nil
+	}
+	return result, nil
+}
+
+// buildpacksFlags adds the options for the buildpacks settings of an app or org
+func buildpacksFlags(flags *pflag.FlagSet) {
+	flags.String("builder-image", "", "The buildpacks builder image, or default")
+	flags.StringArray("buildpack", []string{}, "A buildpack to detect the application with, as ID or ID@VERSION, repeated in order, or default")
+}
+
+// buildpacks returns the buildpacks settings given by the user, or nil when
+// the user gave none.
+func buildpacks(cmd *cobra.Command) (*models.Buildpacks, error) {
+	flags := cmd.Flags()
+	if !flags.Changed("builder-image") && !flags.Changed("buildpack") {
+		return nil, nil
+	}
+
+	image, err := flags.GetString("builder-image")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read option --builder-image")
+	}
+	buildpacks, err := flags.GetStringArray("buildpack")
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read option --buildpack")
+	}
+
+	return &models.Buildpacks{BuilderImage: image, Buildpacks: buildpacks}, nil
+}
+
+// ParseProbe parses a probe specification of the form TYPE[:PATH][,KEY=N...],
+// e.g. "http:/healthz,delay=10" or "tcp". The type "none" removes a probe.
+func ParseProbe(spec string) (*models.Probe, error) {
+	parts := strings.Split(spec, ",")
+
+	probe := &models.Probe{}
+	kind := parts[0]
+	if i := strings.Index(kind, ":"); i >= 0 {
+		kind, probe.Path = kind[:i], kind[i+1:]
+	}
+	probe.Type = kind
+
+	switch probe.Type {
+	case "http":
+		if !strings.HasPrefix(probe.Path, "/") {
+			return nil, errors.New("http probe needs an absolute path, e.g. http:/healthz")
+		}
+	case "tcp", "none":
+		if probe.Path != "" {
+			return nil, errors.Errorf("%s probe takes no path", probe.Type)
+		}
+	default:
+		return nil, errors.Errorf("unknown probe type '%s', expected http, tcp or none", probe.Type)
+	}
+
+	if probe.Type == "none" {
+		if len(parts) > 1 {
+			return nil, errors.New("probe none takes no settings")
+		}
+		return probe, nil
+	}
+
+	for _, setting := range parts[1:] {
+		kv := strings.SplitN(setting, "=", 2)
+		if len(kv) != 2 {
+			return nil, errors.Errorf("bad probe setting '%s', expected KEY=N", setting)
+		}
+		n, err := strconv.ParseInt(kv[1], 10, 32)
+		if err != nil || n < 0 {
+			return nil, errors.Errorf("bad probe setting '%s', expected a number of seconds or failures", setting)
+		}
+
+		switch kv[0] {
+		case "delay":
+			probe.InitialDelaySeconds = int32(n)
+		case "period":
+			probe.PeriodSeconds = int32(n)
+		case "timeout":
+			probe.TimeoutSeconds = int32(n)
+		case "failures":
+			probe.FailureThreshold = int32(n)
+		default:
+			return nil, errors.Errorf("unknown probe setting '%s', expected delay, period, timeout or failures", kv[0])
+		}
+	}
+
+	return probe, nil
+}
@@ -0,0 +1,43 @@
+package cli_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/cli"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ParseProbe", func() {
+	It("parses an http probe with settings", func() {
+		probe, err := cli.ParseProbe("http:/healthz,delay=10,period=5,timeout=2,failures=3")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(*probe).To(Equal(models.Probe{
+			Type:                "http",
+			Path:                "/healthz",
+			InitialDelaySeconds: 10,
+			PeriodSeconds:       5,
+			TimeoutSeconds:      2,
+			FailureThreshold:    3,
+		}))
+	})
+
+	It("parses a tcp probe", func() {
+		probe, err := cli.ParseProbe("tcp")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(probe.Type).To(Equal("tcp"))
+	})
+
+	It("parses the removal of a probe", func() {
+		probe, err := cli.ParseProbe("none")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(probe.Type).To(Equal("none"))
+	})
+
+	It("rejects bad specifications", func() {
+		for _, spec := range []string{"", "grpc", "http", "http:healthz", "tcp:/x", "tcp,delay", "tcp,delay=-1", "tcp,retries=1", "none,delay=1"} {
+			_, err := cli.ParseProbe(spec)
+			Expect(err).To(HaveOccurred(), spec)
+		}
+	})
+})
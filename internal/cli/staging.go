@@ -0,0 +1,82 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	CmdAppStageCancel.Flags().String("stage-id", "", "the staging run to cancel, instead of the current one")
+	CmdAppStage.AddCommand(CmdAppStageList)
+	CmdAppStage.AddCommand(CmdAppStageCancel)
+}
+
+// CmdAppStage implements the epinio `apps stage` command
+var CmdAppStage = &cobra.Command{
+	Use:   "stage",
+	Short: "Application staging",
+	Long: `Inspect the staging of an application.
+
+A push while the application is staging is handled by the staging policy of
+the application, see "epinio app update --staging-policy". By default it is
+queued, and staged after the stagings before it.`,
+	SilenceErrors: true,
+	SilenceUsage:  true,
+	Args:          cobra.ExactArgs(0),
+}
+
+// CmdAppStageList implements the epinio `apps stage list` command
+var CmdAppStageList = &cobra.Command{
+	Use:               "list NAME",
+	Short:             "Lists the current and the queued stagings of the application",
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStaging(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error listing stagings")
+		}
+
+		return nil
+	},
+}
+
+// CmdAppStageCancel implements the epinio `apps stage cancel` command
+var CmdAppStageCancel = &cobra.Command{
+	Use:   "cancel NAME",
+	Short: "Cancels a staging of the application",
+	Long: `Cancel a staging of the application, by default the current one.
+
+The next queued staging of the application, if any, starts afterwards. Use
+--stage-id to cancel a queued staging instead, see "epinio app stage list".`,
+	Args:              cobra.ExactArgs(1),
+	ValidArgsFunction: matchingAppsFinder,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		stageID, err := cmd.Flags().GetString("stage-id")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --stage-id")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.AppStageCancel(args[0], stageID)
+		if err != nil {
+			return errors.Wrap(err, "error cancelling staging")
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,107 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdToken implements the epinio token command
+var CmdToken = &cobra.Command{
+	Use:     "token",
+	Aliases: []string{"tokens"},
+	Short:   "Epinio API tokens",
+	Long: `Manage the API tokens of the current user.
+
+Tokens are an alternative to the user's password, e.g. for CI pipelines.
+A token is used instead of user and password when it is set in the
+configuration, or in the environment variable EPINIO_TOKEN.`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	flags := CmdTokenCreate.Flags()
+	flags.String("expires-in", "", "lifetime of the token, e.g. 720h. Tokens without lifetime do not expire")
+	flags.Bool("save", false, "save the token in the configuration, for use by further commands")
+
+	CmdToken.AddCommand(CmdTokenList)
+	CmdToken.AddCommand(CmdTokenCreate)
+	CmdToken.AddCommand(CmdTokenRevoke)
+}
+
+// CmdTokenList implements the epinio `token list` command
+var CmdTokenList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists the tokens of the current user",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Tokens()
+		if err != nil {
+			return errors.Wrap(err, "error listing tokens")
+		}
+
+		return nil
+	},
+}
+
+// CmdTokenCreate implements the epinio `token create` command
+var CmdTokenCreate = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Creates a token for the current user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		expiresIn, err := cmd.Flags().GetString("expires-in")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --expires-in")
+		}
+		save, err := cmd.Flags().GetBool("save")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --save")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.CreateToken(args[0], expiresIn, save)
+		if err != nil {
+			return errors.Wrap(err, "error creating token")
+		}
+
+		return nil
+	},
+}
+
+// CmdTokenRevoke implements the epinio `token revoke` command
+var CmdTokenRevoke = &cobra.Command{
+	Use:   "revoke NAME",
+	Short: "Revokes a token of the current user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.RevokeToken(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error revoking token")
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"github.com/epinio/epinio/internal/cli/clients"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// CmdUser implements the epinio user command
+var CmdUser = &cobra.Command{
+	Use:           "user",
+	Aliases:       []string{"users"},
+	Short:         "Epinio API users",
+	Long:          `Manage the users of the epinio API, and their roles in organizations. Requires admin rights.`,
+	Args:          cobra.ExactArgs(0),
+	SilenceErrors: true,
+	SilenceUsage:  true,
+}
+
+func init() {
+	flags := CmdUserCreate.Flags()
+	flags.String("password", "", "password of the new user. Users without password log in via OIDC, see `epinio login`")
+	flags.Bool("admin", false, "make the new user an admin")
+
+	CmdUser.AddCommand(CmdUserList)
+	CmdUser.AddCommand(CmdUserCreate)
+	CmdUser.AddCommand(CmdUserDelete)
+	CmdUser.AddCommand(CmdUserGrant)
+	CmdUser.AddCommand(CmdUserRevoke)
+}
+
+// CmdUserList implements the epinio `user list` command
+var CmdUserList = &cobra.Command{
+	Use:   "list",
+	Short: "Lists all users",
+	Args:  cobra.ExactArgs(0),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.Users()
+		if err != nil {
+			return errors.Wrap(err, "error listing users")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserCreate implements the epinio `user create` command
+var CmdUserCreate = &cobra.Command{
+	Use:   "create NAME",
+	Short: "Creates a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		password, err := cmd.Flags().GetString("password")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --password")
+		}
+		admin, err := cmd.Flags().GetBool("admin")
+		if err != nil {
+			return errors.Wrap(err, "could not read option --admin")
+		}
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.CreateUser(args[0], password, admin)
+		if err != nil {
+			return errors.Wrap(err, "error creating user")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserDelete implements the epinio `user delete` command
+var CmdUserDelete = &cobra.Command{
+	Use:   "delete NAME",
+	Short: "Deletes a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.DeleteUser(args[0])
+		if err != nil {
+			return errors.Wrap(err, "error deleting user")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserGrant implements the epinio `user grant` command
+var CmdUserGrant = &cobra.Command{
+	Use:   "grant NAME ORG ROLE",
+	Short: "Grants a role in an organization to a user",
+	Long:  "Grants a role in an organization to a user. The role is either `org-developer` or `org-viewer`.",
+	Args:  cobra.ExactArgs(3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.GrantRole(args[0], args[1], args[2])
+		if err != nil {
+			return errors.Wrap(err, "error granting role")
+		}
+
+		return nil
+	},
+}
+
+// CmdUserRevoke implements the epinio `user revoke` command
+var CmdUserRevoke = &cobra.Command{
+	Use:   "revoke NAME ORG",
+	Short: "Revokes a user's access to an organization",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+
+		client, err := clients.NewEpinioClient(cmd.Context())
+		if err != nil {
+			return errors.Wrap(err, "error initializing cli")
+		}
+
+		err = client.RevokeRole(args[0], args[1])
+		if err != nil {
+			return errors.Wrap(err, "error revoking role")
+		}
+
+		return nil
+	},
+}
@@ -0,0 +1,243 @@
+// Package ignore handles the ignore file of the application sources,
+// `.epinioignore`. It lists gitignore style patterns of the files `epinio
+// push` does not upload. Sources without it fall back to their `.cfignore`,
+// and then to their `.gitignore`.
+package ignore
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// FileName is the name of the ignore file, expected at the toplevel
+	// of the application sources
+	FileName = ".epinioignore"
+)
+
+// FileNames are the names of the ignore files, in order of preference. Only
+// the first one found in the sources is used.
+var FileNames = []string{FileName, ".cfignore", ".gitignore"}
+
+// Defaults are the patterns of the files which are never uploaded. The git
+// files would conflict with the git repository holding the sources on the
+// server.
+var Defaults = []string{
+	".git",
+	".gitignore",
+	".gitmodules",
+	".gitconfig",
+	".git-credentials",
+	FileName,
+	".cfignore",
+}
+
+// Matcher decides which files of the sources are ignored
+type Matcher struct {
+	patterns []pattern
+}
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Load reads the ignore file found in the specified directory, see
+// FileNames, and returns a matcher for its patterns and the Defaults. It
+// also returns the name of the file, if any.
+func Load(dir string) (*Matcher, string, error) {
+	for _, name := range FileNames {
+		file := path.Join(dir, name)
+
+		content, err := ioutil.ReadFile(file)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, "", errors.Wrapf(err, "failed to read ignore file '%s'", file)
+		}
+
+		m, err := Parse(string(content))
+		return m, name, err
+	}
+
+	m, err := Parse("")
+	return m, "", err
+}
+
+// Parse returns a matcher for the patterns in the given content, one per
+// line, and the Defaults. Empty lines and lines starting with `#` are
+// skipped. As with gitignore, a pattern starting with `!` includes files
+// again, a pattern ending with `/` only matches directories, and a pattern
+// containing a `/` is relative to the toplevel of the sources. The last
+// matching pattern decides.
+func Parse(content string) (*Matcher, error) {
+	m := &Matcher{}
+
+	lines := append(append([]string{}, Defaults...), strings.Split(content, "\n")...)
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if !strings.HasSuffix(line, `\ `) {
+			line = strings.TrimRight(line, " \t")
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		p, err := compile(line)
+		if err != nil {
+			return nil, errors.Wrapf(err, "bad ignore pattern '%s'", line)
+		}
+		m.patterns = append(m.patterns, p)
+	}
+
+	return m, nil
+}
+
+// Ignored returns true if the file or directory at the relative path, with
+// `/` separators, is ignored
+func (m *Matcher) Ignored(rel string, isDir bool) bool {
+	ignored := false
+	for _, p := range m.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.re.MatchString(rel) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// Files returns the relative paths of the files and directories in dir
+// which are not ignored, parents before their children. The contents of
+// ignored directories are skipped.
+func (m *Matcher) Files(dir string) ([]string, error) {
+	files := []string{}
+
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+
+		if m.Ignored(rel, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		files = append(files, rel)
+		return nil
+	})
+
+	return files, err
+}
+
+// compile translates a gitignore style pattern into a regular expression
+// matching the relative paths it applies to
+func compile(line string) (pattern, error) {
+	p := pattern{}
+
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// A pattern without a slash matches at any depth. Others are
+	// relative to the toplevel.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+
+	var re strings.Builder
+	re.WriteString("^")
+	if !anchored {
+		re.WriteString("(?:.*/)?")
+	}
+
+	segments := strings.Split(line, "/")
+	for i, segment := range segments {
+		last := i == len(segments)-1
+
+		if segment == "**" {
+			if last {
+				re.WriteString(".*")
+			} else {
+				re.WriteString("(?:.*/)?")
+			}
+			continue
+		}
+
+		if err := glob(&re, segment); err != nil {
+			return p, err
+		}
+		if !last {
+			re.WriteString("/")
+		}
+	}
+	re.WriteString("$")
+
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return p, err
+	}
+	p.re = compiled
+
+	return p, nil
+}
+
+// glob translates the wildcards of a single path segment
+func glob(re *strings.Builder, segment string) error {
+	for i := 0; i < len(segment); i++ {
+		c := segment[i]
+		switch c {
+		case '*':
+			re.WriteString("[^/]*")
+		case '?':
+			re.WriteString("[^/]")
+		case '\\':
+			i++
+			if i == len(segment) {
+				return errors.New("trailing backslash")
+			}
+			re.WriteString(regexp.QuoteMeta(segment[i : i+1]))
+		case '[':
+			end := strings.IndexByte(segment[i+1:], ']')
+			if end < 0 {
+				return errors.New("unterminated character class")
+			}
+			class := segment[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			re.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			re.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return nil
+}
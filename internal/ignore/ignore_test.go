@@ -0,0 +1,105 @@
+package ignore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/epinio/epinio/internal/ignore"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Ignore", func() {
+	Describe("Parse", func() {
+		It("ignores the git files by default", func() {
+			m, err := Parse("")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Ignored(".git", true)).To(BeTrue())
+			Expect(m.Ignored(".gitignore", false)).To(BeTrue())
+			Expect(m.Ignored("main.go", false)).To(BeFalse())
+		})
+
+		It("matches patterns without slash at any depth", func() {
+			m, err := Parse("*.log\nnode_modules/\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Ignored("app.log", false)).To(BeTrue())
+			Expect(m.Ignored("logs/app.log", false)).To(BeTrue())
+			Expect(m.Ignored("web/node_modules", true)).To(BeTrue())
+			Expect(m.Ignored("web/node_modules", false)).To(BeFalse())
+			Expect(m.Ignored("app.logger", false)).To(BeFalse())
+		})
+
+		It("matches patterns with slash from the toplevel", func() {
+			m, err := Parse("/target\nconfig/*.key\ndocs/**/draft.md\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Ignored("target", true)).To(BeTrue())
+			Expect(m.Ignored("sub/target", true)).To(BeFalse())
+			Expect(m.Ignored("config/tls.key", false)).To(BeTrue())
+			Expect(m.Ignored("sub/config/tls.key", false)).To(BeFalse())
+			Expect(m.Ignored("docs/draft.md", false)).To(BeTrue())
+			Expect(m.Ignored("docs/a/b/draft.md", false)).To(BeTrue())
+		})
+
+		It("includes negated patterns again, and skips comments", func() {
+			m, err := Parse("# secrets\n*.env\n!sample.env\n")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Ignored("prod.env", false)).To(BeTrue())
+			Expect(m.Ignored("sample.env", false)).To(BeFalse())
+			Expect(m.Ignored("# secrets", false)).To(BeFalse())
+		})
+
+		It("rejects bad patterns", func() {
+			_, err := Parse("[abc\n")
+			Expect(err).To(MatchError(ContainSubstring("bad ignore pattern '[abc'")))
+		})
+	})
+
+	Describe("Load", func() {
+		var dir string
+
+		write := func(file, content string) {
+			Expect(os.MkdirAll(path.Dir(path.Join(dir, file)), 0755)).To(Succeed())
+			Expect(ioutil.WriteFile(path.Join(dir, file), []byte(content), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "epinio-ignore")
+			Expect(err).ToNot(HaveOccurred())
+
+			write("main.go", "package main\n")
+			write("node_modules/lib/index.js", "\n")
+			write("target/app.jar", "\n")
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("prefers .epinioignore over .cfignore and .gitignore", func() {
+			write(".gitignore", "target/\n")
+			write(".cfignore", "node_modules/\n")
+
+			m, name, err := Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal(".cfignore"))
+			Expect(m.Files(dir)).To(Equal([]string{"main.go", "target", "target/app.jar"}))
+
+			write(".epinioignore", "node_modules/\ntarget/\n")
+
+			m, name, err = Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(Equal(".epinioignore"))
+			Expect(m.Files(dir)).To(Equal([]string{"main.go"}))
+		})
+
+		It("uploads everything but the defaults without ignore file", func() {
+			m, name, err := Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(name).To(BeEmpty())
+			Expect(m.Files(dir)).To(Equal([]string{"main.go", "node_modules", "node_modules/lib",
+				"node_modules/lib/index.js", "target", "target/app.jar"}))
+		})
+	})
+})
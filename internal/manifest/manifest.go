@@ -0,0 +1,90 @@
+// Package manifest handles the application manifest, `epinio.yml`. It is a
+// declarative description of an application, read by `epinio push` from the
+// application sources.
+package manifest
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"sort"
+
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/application"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// FileName is the name of the manifest file, expected at the toplevel
+	// of the application sources
+	FileName = "epinio.yml"
+)
+
+// Manifest describes an application, its runtime configuration and how to
+// stage it.
+type Manifest struct {
+	Name        string            `json:"name,omitempty"`
+	Instances   *int32            `json:"instances,omitempty"`
+	Environment map[string]string `json:"env,omitempty"`
+	Services    []string          `json:"services,omitempty"`
+	Routes      []string          `json:"routes,omitempty"`
+	Staging     Staging           `json:"staging,omitempty"`
+
+	// The runtime settings: port, liveness, readiness and resources
+	models.AppSettings
+}
+
+// Staging holds the manifest's staging configuration
+type Staging struct {
+	DockerImageURL string `json:"docker_image_url,omitempty"`
+}
+
+// Load reads the manifest found in the specified directory. It returns nil,
+// without error, when the directory has no manifest.
+func Load(dir string) (*Manifest, error) {
+	file := path.Join(dir, FileName)
+
+	content, err := ioutil.ReadFile(file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.Wrapf(err, "failed to read manifest '%s'", file)
+	}
+
+	return Parse(content)
+}
+
+// Parse decodes and validates the manifest in the given yaml content
+func Parse(content []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := yaml.UnmarshalStrict(content, m); err != nil {
+		return nil, errors.Wrap(err, "failed to parse manifest")
+	}
+
+	if m.Instances != nil && *m.Instances < 0 {
+		return nil, errors.New("manifest instances should be integer equal or greater than zero")
+	}
+
+	if err := application.ValidateSettings(m.AppSettings); err != nil {
+		return nil, errors.Wrap(err, "bad manifest settings")
+	}
+
+	return m, nil
+}
+
+// EnvVariables returns the manifest's environment as a list suitable for the
+// EnvSet API, sorted by name.
+func (m *Manifest) EnvVariables() models.EnvVariableList {
+	result := models.EnvVariableList{}
+	for name, value := range m.Environment {
+		result = append(result, models.EnvVariable{
+			Name:  name,
+			Value: value,
+		})
+	}
+
+	sort.Sort(result)
+	return result
+}
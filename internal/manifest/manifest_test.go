@@ -0,0 +1,105 @@
+package manifest_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+
+	. "github.com/epinio/epinio/internal/manifest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Manifest", func() {
+	Describe("Parse", func() {
+		It("decodes all the supported fields", func() {
+			m, err := Parse([]byte(`
+name: sample
+instances: 3
+env:
+  FOO: bar
+  ALPHA: omega
+services:
+- mydb
+routes:
+- sample.example.com
+staging:
+  docker_image_url: splatform/sample-app
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Name).To(Equal("sample"))
+			Expect(*m.Instances).To(Equal(int32(3)))
+			Expect(m.Services).To(ConsistOf("mydb"))
+			Expect(m.Routes).To(ConsistOf("sample.example.com"))
+			Expect(m.Staging.DockerImageURL).To(Equal("splatform/sample-app"))
+
+			env := m.EnvVariables()
+			Expect(env).To(HaveLen(2))
+			Expect(env[0].Name).To(Equal("ALPHA"))
+			Expect(env[1].Name).To(Equal("FOO"))
+			Expect(env[1].Value).To(Equal("bar"))
+		})
+
+		It("decodes the runtime settings", func() {
+			m, err := Parse([]byte(`
+port: 8000
+liveness:
+  type: http
+  path: /healthz
+  initial_delay_seconds: 30
+resources:
+  memory_limit: 1Gi
+`))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Port).To(Equal(int32(8000)))
+			Expect(m.Liveness.Path).To(Equal("/healthz"))
+			Expect(m.Liveness.InitialDelaySeconds).To(Equal(int32(30)))
+			Expect(m.Readiness).To(BeNil())
+			Expect(m.Resources.MemoryLimit).To(Equal("1Gi"))
+		})
+
+		It("rejects bad runtime settings", func() {
+			_, err := Parse([]byte("liveness:\n  type: grpc\n"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects unknown fields", func() {
+			_, err := Parse([]byte("name: sample\nbogus: true\n"))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects negative instances", func() {
+			_, err := Parse([]byte("instances: -1\n"))
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Load", func() {
+		var dir string
+
+		BeforeEach(func() {
+			var err error
+			dir, err = ioutil.TempDir("", "epinio-manifest")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(dir)
+		})
+
+		It("returns nothing for sources without manifest", func() {
+			m, err := Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m).To(BeNil())
+		})
+
+		It("reads the manifest from the sources", func() {
+			err := ioutil.WriteFile(path.Join(dir, FileName), []byte("name: sample\n"), 0600)
+			Expect(err).ToNot(HaveOccurred())
+
+			m, err := Load(dir)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(m.Name).To(Equal("sample"))
+		})
+	})
+})
@@ -0,0 +1,76 @@
+// Package oidc implements the parts of OpenID Connect used by Epinio. The
+// client side runs the device-code and password grants for `epinio login`.
+// The server side verifies the resulting ID tokens.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Scopes are the scopes requested by the login flows
+const Scopes = "openid email profile"
+
+// Discovery is the subset of the issuer's discovery document used by Epinio
+type Discovery struct {
+	Issuer                      string `json:"issuer"`
+	JWKSURI                     string `json:"jwks_uri"`
+	TokenEndpoint               string `json:"token_endpoint"`
+	DeviceAuthorizationEndpoint string `json:"device_authorization_endpoint,omitempty"`
+}
+
+// Discover retrieves the discovery document of the issuer
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Discovery, error) {
+	uri := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+
+	body, err := getJSON(ctx, client, uri)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to discover issuer '%s'", issuer)
+	}
+
+	discovery := &Discovery{}
+	if err := json.Unmarshal(body, discovery); err != nil {
+		return nil, errors.Wrapf(err, "bad discovery document of issuer '%s'", issuer)
+	}
+
+	// See OpenID Connect Discovery 1.0, section 4.3
+	if strings.TrimSuffix(discovery.Issuer, "/") != strings.TrimSuffix(issuer, "/") {
+		return nil, fmt.Errorf("issuer mismatch, expected '%s', got '%s'", issuer, discovery.Issuer)
+	}
+	if discovery.JWKSURI == "" || discovery.TokenEndpoint == "" {
+		return nil, fmt.Errorf("discovery document of issuer '%s' is incomplete", issuer)
+	}
+
+	return discovery, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, uri string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", http.StatusText(response.StatusCode), string(body))
+	}
+
+	return body, nil
+}
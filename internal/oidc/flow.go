@@ -0,0 +1,183 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Token is the response of the issuer's token endpoint
+type Token struct {
+	IDToken      string `json:"id_token"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in,omitempty"`
+}
+
+// DeviceAuthorization is the response of the issuer's device authorization
+// endpoint. See RFC 8628, section 3.2
+type DeviceAuthorization struct {
+	DeviceCode              string `json:"device_code"`
+	UserCode                string `json:"user_code"`
+	VerificationURI         string `json:"verification_uri"`
+	VerificationURIComplete string `json:"verification_uri_complete,omitempty"`
+	ExpiresIn               int    `json:"expires_in"`
+	Interval                int    `json:"interval,omitempty"`
+}
+
+// Error is an error response of the issuer. See RFC 6749, section 5.2
+type Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+func (e *Error) Error() string {
+	if e.Description != "" {
+		return e.Code + ": " + e.Description
+	}
+	return e.Code
+}
+
+// Client runs the login flows of a client against an issuer
+type Client struct {
+	Discovery *Discovery
+	ClientID  string
+	HTTP      *http.Client
+}
+
+// PasswordGrant exchanges the user's credentials for a token. See RFC 6749,
+// section 4.3
+func (c *Client) PasswordGrant(ctx context.Context, username, password string) (*Token, error) {
+	return c.token(ctx, url.Values{
+		"grant_type": {"password"},
+		"username":   {username},
+		"password":   {password},
+		"scope":      {Scopes},
+	})
+}
+
+// StartDeviceAuthorization requests the codes of a device-code flow. The user
+// has to confirm the user code at the verification URI, while the client waits
+// for the token with PollDeviceToken.
+func (c *Client) StartDeviceAuthorization(ctx context.Context) (*DeviceAuthorization, error) {
+	if c.Discovery.DeviceAuthorizationEndpoint == "" {
+		return nil, errors.New("the issuer does not support the device-code flow, use a password instead")
+	}
+
+	body, err := c.postForm(ctx, c.Discovery.DeviceAuthorizationEndpoint, url.Values{
+		"client_id": {c.ClientID},
+		"scope":     {Scopes},
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "device authorization failed")
+	}
+
+	authorization := &DeviceAuthorization{}
+	if err := json.Unmarshal(body, authorization); err != nil {
+		return nil, errors.Wrap(err, "bad device authorization response")
+	}
+	if authorization.Interval <= 0 {
+		authorization.Interval = 5
+	}
+
+	return authorization, nil
+}
+
+// PollDeviceToken waits until the user confirmed the device authorization,
+// and returns the token. See RFC 8628, section 3.4
+func (c *Client) PollDeviceToken(ctx context.Context, authorization *DeviceAuthorization) (*Token, error) {
+	interval := time.Duration(authorization.Interval) * time.Second
+	deadline := time.Now().Add(time.Duration(authorization.ExpiresIn) * time.Second)
+
+	for {
+		if authorization.ExpiresIn > 0 && time.Now().After(deadline) {
+			return nil, errors.New("device authorization expired")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+
+		token, err := c.token(ctx, url.Values{
+			"grant_type":  {"urn:ietf:params:oauth:grant-type:device_code"},
+			"device_code": {authorization.DeviceCode},
+		})
+		if err == nil {
+			return token, nil
+		}
+
+		var oerr *Error
+		if !errors.As(err, &oerr) {
+			return nil, err
+		}
+		switch oerr.Code {
+		case "authorization_pending":
+		case "slow_down":
+			interval += 5 * time.Second
+		default:
+			return nil, err
+		}
+	}
+}
+
+// token requests a token from the token endpoint
+func (c *Client) token(ctx context.Context, values url.Values) (*Token, error) {
+	values.Set("client_id", c.ClientID)
+
+	body, err := c.postForm(ctx, c.Discovery.TokenEndpoint, values)
+	if err != nil {
+		return nil, err
+	}
+
+	token := &Token{}
+	if err := json.Unmarshal(body, token); err != nil {
+		return nil, errors.Wrap(err, "bad token response")
+	}
+	if token.IDToken == "" {
+		return nil, errors.New("the issuer returned no ID token")
+	}
+
+	return token, nil
+}
+
+// postForm posts the values to the endpoint. Error responses of the issuer
+// are returned as *Error.
+func (c *Client) postForm(ctx context.Context, endpoint string, values url.Values) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(values.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	request.Header.Set("Accept", "application/json")
+
+	response, err := c.HTTP.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusOK {
+		oerr := &Error{}
+		if err := json.Unmarshal(body, oerr); err == nil && oerr.Code != "" {
+			return nil, oerr
+		}
+		return nil, fmt.Errorf("%s: %s", http.StatusText(response.StatusCode), string(body))
+	}
+
+	return body, nil
+}
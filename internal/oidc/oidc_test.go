@@ -0,0 +1,160 @@
+package oidc_test
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/epinio/epinio/internal/oidc"
+	"github.com/epinio/epinio/internal/oidc/oidctest"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("OIDC", func() {
+	var issuer *oidctest.Issuer
+	ctx := context.Background()
+
+	BeforeEach(func() {
+		var err error
+		issuer, err = oidctest.NewIssuer("epinio-cli")
+		Expect(err).ToNot(HaveOccurred())
+		issuer.AddUser("jane@example.com", "secret")
+	})
+
+	AfterEach(func() {
+		issuer.Close()
+	})
+
+	Describe("Verifier", func() {
+		var verifier *oidc.Verifier
+
+		BeforeEach(func() {
+			verifier = oidc.NewVerifier(issuer.URL(), "epinio-cli", http.DefaultClient)
+		})
+
+		It("accepts a valid token", func() {
+			claims, err := verifier.Verify(ctx, issuer.IDToken("jane@example.com", time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Username()).To(Equal("jane@example.com"))
+		})
+
+		It("rejects an expired token", func() {
+			_, err := verifier.Verify(ctx, issuer.IDToken("jane@example.com", -time.Minute))
+			Expect(err).To(MatchError(ContainSubstring("token expired")))
+		})
+
+		It("rejects a token for another client", func() {
+			other := oidc.NewVerifier(issuer.URL(), "other-client", http.DefaultClient)
+			_, err := other.Verify(ctx, issuer.IDToken("jane@example.com", time.Hour))
+			Expect(err).To(MatchError(ContainSubstring("another client")))
+		})
+
+		It("rejects a token with an unverified email address", func() {
+			claims := issuer.Claims("admin@example.com", time.Hour)
+			claims["email_verified"] = false
+			_, err := verifier.Verify(ctx, issuer.Sign(claims))
+			Expect(err).To(MatchError(ContainSubstring("email address not verified")))
+
+			delete(claims, "email_verified")
+			_, err = verifier.Verify(ctx, issuer.Sign(claims))
+			Expect(err).To(MatchError(ContainSubstring("email address not verified")))
+		})
+
+		It("identifies a token without email address by its subject", func() {
+			claims := issuer.Claims("jane@example.com", time.Hour)
+			delete(claims, "email")
+			delete(claims, "email_verified")
+			claims["preferred_username"] = "admin"
+			verified, err := verifier.Verify(ctx, issuer.Sign(claims))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(verified.Username()).To(Equal("sub-jane@example.com"))
+		})
+
+		It("rejects a tampered token", func() {
+			parts := strings.Split(issuer.IDToken("jane@example.com", time.Hour), ".")
+			forged := strings.Split(issuer.IDToken("mallory@example.com", time.Hour), ".")
+			_, err := verifier.Verify(ctx, parts[0]+"."+forged[1]+"."+parts[2])
+			Expect(err).To(MatchError(ContainSubstring("bad signature")))
+		})
+
+		It("rejects a token of another issuer", func() {
+			other, err := oidctest.NewIssuer("epinio-cli")
+			Expect(err).ToNot(HaveOccurred())
+			defer other.Close()
+
+			_, err = verifier.Verify(ctx, other.IDToken("jane@example.com", time.Hour))
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("rejects unknown signing keys without refetching the keys each time", func() {
+			parts := strings.Split(issuer.IDToken("jane@example.com", time.Hour), ".")
+			header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","kid":"rotated"}`))
+			unknown := header + "." + parts[1] + "." + parts[2]
+
+			for n := 0; n < 3; n++ {
+				_, err := verifier.Verify(ctx, unknown)
+				Expect(err).To(MatchError(ContainSubstring("unknown signing key 'rotated'")))
+			}
+			Expect(issuer.KeyRequests()).To(Equal(1))
+
+			_, err := verifier.Verify(ctx, issuer.IDToken("jane@example.com", time.Hour))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(issuer.KeyRequests()).To(Equal(1))
+		})
+
+		It("rejects garbage", func() {
+			_, err := verifier.Verify(ctx, "not-a-token")
+			Expect(err).To(MatchError(ContainSubstring("malformed token")))
+		})
+	})
+
+	Describe("Client", func() {
+		var client *oidc.Client
+
+		BeforeEach(func() {
+			discovery, err := oidc.Discover(ctx, http.DefaultClient, issuer.URL())
+			Expect(err).ToNot(HaveOccurred())
+			client = &oidc.Client{
+				Discovery: discovery,
+				ClientID:  "epinio-cli",
+				HTTP:      http.DefaultClient,
+			}
+		})
+
+		It("gets a token with the password grant", func() {
+			token, err := client.PasswordGrant(ctx, "jane@example.com", "secret")
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := oidc.UnverifiedClaims(token.IDToken)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Username()).To(Equal("jane@example.com"))
+		})
+
+		It("fails the password grant for bad credentials", func() {
+			_, err := client.PasswordGrant(ctx, "jane@example.com", "wrong")
+			Expect(err).To(MatchError("invalid_grant"))
+		})
+
+		It("gets a token with the device-code flow", func() {
+			authorization, err := client.StartDeviceAuthorization(ctx)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(authorization.UserCode).ToNot(BeEmpty())
+
+			go func() {
+				defer GinkgoRecover()
+				time.Sleep(1500 * time.Millisecond)
+				Expect(issuer.Approve(authorization.UserCode, "jane@example.com")).To(BeTrue())
+			}()
+
+			token, err := client.PollDeviceToken(ctx, authorization)
+			Expect(err).ToNot(HaveOccurred())
+
+			claims, err := oidc.UnverifiedClaims(token.IDToken)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(claims.Username()).To(Equal("jane@example.com"))
+		})
+	})
+})
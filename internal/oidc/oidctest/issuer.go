@@ -0,0 +1,242 @@
+// Package oidctest provides a minimal OpenID Connect issuer, for testing the
+// login flows and the verification of tokens without a real identity provider.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+)
+
+// KeyID is the id of the issuer's signing key
+const KeyID = "oidctest"
+
+// Issuer is a mock issuer serving discovery, keys, the password grant and
+// the device-code flow. Device authorizations are approved with Approve.
+type Issuer struct {
+	Server   *httptest.Server
+	ClientID string
+	Key      *rsa.PrivateKey
+	TTL      time.Duration
+
+	mu          sync.Mutex
+	users       map[string]string // name -> password
+	devices     map[string]*device
+	keyRequests int
+}
+
+type device struct {
+	userCode string
+	username string // set on approval
+}
+
+// NewIssuer starts an issuer for the client
+func NewIssuer(clientID string) (*Issuer, error) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, err
+	}
+
+	issuer := &Issuer{
+		ClientID: clientID,
+		Key:      key,
+		TTL:      time.Hour,
+		users:    map[string]string{},
+		devices:  map[string]*device{},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", issuer.discovery)
+	mux.HandleFunc("/keys", issuer.keys)
+	mux.HandleFunc("/token", issuer.token)
+	mux.HandleFunc("/device", issuer.device)
+	issuer.Server = httptest.NewServer(mux)
+
+	return issuer, nil
+}
+
+// URL returns the issuer URL
+func (i *Issuer) URL() string {
+	return i.Server.URL
+}
+
+// Close stops the issuer
+func (i *Issuer) Close() {
+	i.Server.Close()
+}
+
+// KeyRequests returns the number of requests for the issuer's keys
+func (i *Issuer) KeyRequests() int {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	return i.keyRequests
+}
+
+// AddUser registers a user for the password grant
+func (i *Issuer) AddUser(username, password string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.users[username] = password
+}
+
+// Approve confirms the pending device authorization with the user code, on
+// behalf of the user
+func (i *Issuer) Approve(userCode, username string) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	for _, d := range i.devices {
+		if d.userCode == userCode {
+			d.username = username
+			return true
+		}
+	}
+	return false
+}
+
+// IDToken returns a signed ID token for the user, with the given lifetime
+func (i *Issuer) IDToken(username string, ttl time.Duration) string {
+	return i.Sign(i.Claims(username, ttl))
+}
+
+// Claims returns the claims of an ID token for the user, with the given
+// lifetime. The email address of the user is verified.
+func (i *Issuer) Claims(username string, ttl time.Duration) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"iss":            i.URL(),
+		"sub":            "sub-" + username,
+		"aud":            i.ClientID,
+		"iat":            now.Unix(),
+		"exp":            now.Add(ttl).Unix(),
+		"email":          username,
+		"email_verified": true,
+	}
+}
+
+// Sign returns an ID token with the claims, signed by the issuer's key
+func (i *Issuer) Sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": KeyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, i.Key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func (i *Issuer) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{
+		"issuer":                        i.URL(),
+		"jwks_uri":                      i.URL() + "/keys",
+		"token_endpoint":                i.URL() + "/token",
+		"device_authorization_endpoint": i.URL() + "/device",
+	})
+}
+
+func (i *Issuer) keys(w http.ResponseWriter, r *http.Request) {
+	i.mu.Lock()
+	i.keyRequests++
+	i.mu.Unlock()
+
+	pub := i.Key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": KeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+func (i *Issuer) device(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") != i.ClientID {
+		writeError(w, "invalid_client")
+		return
+	}
+
+	i.mu.Lock()
+	n := len(i.devices) + 1
+	deviceCode := fmt.Sprintf("device-%d", n)
+	userCode := fmt.Sprintf("USER-%04d", n)
+	i.devices[deviceCode] = &device{userCode: userCode}
+	i.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"device_code":               deviceCode,
+		"user_code":                 userCode,
+		"verification_uri":          i.URL() + "/verify",
+		"verification_uri_complete": i.URL() + "/verify?user_code=" + userCode,
+		"expires_in":                300,
+		"interval":                  1,
+	})
+}
+
+func (i *Issuer) token(w http.ResponseWriter, r *http.Request) {
+	if r.FormValue("client_id") != i.ClientID {
+		writeError(w, "invalid_client")
+		return
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	var username string
+	switch r.FormValue("grant_type") {
+	case "password":
+		password, ok := i.users[r.FormValue("username")]
+		if !ok || password != r.FormValue("password") {
+			writeError(w, "invalid_grant")
+			return
+		}
+		username = r.FormValue("username")
+	case "urn:ietf:params:oauth:grant-type:device_code":
+		d, ok := i.devices[r.FormValue("device_code")]
+		if !ok {
+			writeError(w, "invalid_grant")
+			return
+		}
+		if d.username == "" {
+			writeError(w, "authorization_pending")
+			return
+		}
+		delete(i.devices, r.FormValue("device_code"))
+		username = d.username
+	default:
+		writeError(w, "unsupported_grant_type")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id_token":     i.IDToken(username, i.TTL),
+		"access_token": "access-" + username,
+		"token_type":   "Bearer",
+		"expires_in":   int(i.TTL.Seconds()),
+	})
+}
+
+func writeError(w http.ResponseWriter, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]string{"error": code})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,260 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrInvalidToken is returned for tokens which fail verification
+var ErrInvalidToken = errors.New("invalid token")
+
+// keysRefetchInterval is the minimum time between two fetches of the
+// issuer's keys. Tokens signed by an unknown key within it are rejected
+// without asking the issuer.
+const keysRefetchInterval = time.Minute
+
+// Claims are the claims of an ID token used by Epinio
+type Claims struct {
+	Issuer        string   `json:"iss"`
+	Subject       string   `json:"sub"`
+	Audience      audience `json:"aud"`
+	Expiry        int64    `json:"exp"`
+	NotBefore     int64    `json:"nbf,omitempty"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+}
+
+// Username returns the name of the Epinio user identified by the token. It
+// is the email address, falling back to the subject. Verify rejects tokens
+// with an email address the issuer did not verify.
+func (c *Claims) Username() string {
+	if c.Email != "" {
+		return c.Email
+	}
+	return c.Subject
+}
+
+// audience is a list of strings, which may be encoded as a single string
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	*a = audience(list)
+	return nil
+}
+
+func (a audience) contains(s string) bool {
+	for _, v := range a {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifier checks ID tokens issued for a client by an issuer. The issuer's
+// keys are fetched on first use, and again when a token is signed by an
+// unknown key, at most once per keysRefetchInterval.
+type Verifier struct {
+	issuer   string
+	clientID string
+	client   *http.Client
+
+	mu        sync.Mutex
+	discovery *Discovery
+	keys      map[string]*rsa.PublicKey
+	fetched   time.Time // when the keys were fetched last
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// NewVerifier returns a verifier of the ID tokens issued by the issuer to the client
+func NewVerifier(issuer, clientID string, client *http.Client) *Verifier {
+	return &Verifier{
+		issuer:   issuer,
+		clientID: clientID,
+		client:   client,
+		keys:     map[string]*rsa.PublicKey{},
+		now:      time.Now,
+	}
+}
+
+// Verify checks the signature and the claims of the raw token, and returns
+// the claims of a valid token.
+func (v *Verifier) Verify(ctx context.Context, raw string) (*Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token")
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token header")
+	}
+	if header.Alg != "RS256" {
+		return nil, errors.Wrapf(ErrInvalidToken, "unsupported signing algorithm '%s'", header.Alg)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token signature")
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature); err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "bad signature")
+	}
+
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token claims")
+	}
+
+	now := v.now().Unix()
+	switch {
+	case strings.TrimSuffix(claims.Issuer, "/") != strings.TrimSuffix(v.issuer, "/"):
+		return nil, errors.Wrapf(ErrInvalidToken, "issued by '%s'", claims.Issuer)
+	case !claims.Audience.contains(v.clientID):
+		return nil, errors.Wrap(ErrInvalidToken, "issued for another client")
+	case claims.Expiry <= now:
+		return nil, errors.Wrap(ErrInvalidToken, "token expired")
+	case claims.NotBefore > now:
+		return nil, errors.Wrap(ErrInvalidToken, "token not valid yet")
+	case claims.Email != "" && !claims.EmailVerified:
+		return nil, errors.Wrap(ErrInvalidToken, "email address not verified")
+	}
+
+	return claims, nil
+}
+
+// key returns the issuer's public key with the given id
+func (v *Verifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok {
+		return key, nil
+	}
+
+	// Unknown key. Either the first use, or the issuer rotated its keys. Made
+	// up key ids must not make every request ask the issuer.
+	if !v.fetched.IsZero() && v.now().Sub(v.fetched) < keysRefetchInterval {
+		return nil, errors.Wrapf(ErrInvalidToken, "unknown signing key '%s'", kid)
+	}
+
+	if v.discovery == nil {
+		discovery, err := Discover(ctx, v.client, v.issuer)
+		if err != nil {
+			return nil, err
+		}
+		v.discovery = discovery
+	}
+
+	keys, err := fetchKeys(ctx, v.client, v.discovery.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.fetched = v.now()
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, errors.Wrapf(ErrInvalidToken, "unknown signing key '%s'", kid)
+	}
+	return key, nil
+}
+
+// fetchKeys retrieves the RSA keys of the issuer's key set
+func fetchKeys(ctx context.Context, client *http.Client, uri string) (map[string]*rsa.PublicKey, error) {
+	body, err := getJSON(ctx, client, uri)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get the issuer's keys")
+	}
+
+	var set struct {
+		Keys []struct {
+			Kty string `json:"kty"`
+			Kid string `json:"kid"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := json.Unmarshal(body, &set); err != nil {
+		return nil, errors.Wrap(err, "bad key set")
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("bad modulus of key '%s'", k.Kid)
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("bad exponent of key '%s'", k.Kid)
+		}
+
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
+
+// UnverifiedClaims decodes the claims of the token without verifying it. It
+// is for display by the client only.
+func UnverifiedClaims(raw string) (*Claims, error) {
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token")
+	}
+
+	claims := &Claims{}
+	if err := decodeSegment(parts[1], claims); err != nil {
+		return nil, errors.Wrap(ErrInvalidToken, "malformed token claims")
+	}
+	return claims, nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -0,0 +1,193 @@
+package organizations
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/epinio/epinio/deployments"
+	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/names"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// GitCredentialsComponent is the component label of the secrets and service
+// accounts holding the git credentials of the orgs
+const GitCredentialsComponent = "git-credentials"
+
+// gitCredentialNameLabel is the label holding the name of the credentials
+// within their org
+const gitCredentialNameLabel = "epinio.suse.org/git-credentials"
+
+// GitCredentialsName returns the name of the secret and of the service
+// account holding the named git credentials of the org, in the staging
+// namespace. Staging clones the git repository with the service account.
+// Neither org names nor credential names contain dots, which keeps the names
+// of different orgs apart.
+func GitCredentialsName(org, name string) string {
+	return names.GenerateDNS1123SubDomainName("git", org, name)
+}
+
+// GitCredentials returns the git credentials of the org, without their
+// passwords
+func GitCredentials(ctx context.Context, kubeClient *kubernetes.Cluster, org string) (models.GitCredentialsList, error) {
+	secrets, err := kubeClient.Kubectl.CoreV1().Secrets(deployments.TektonStagingNamespace).List(ctx, metav1.ListOptions{
+		LabelSelector: gitCredentialsSelector(org),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := models.GitCredentialsList{}
+	for _, secret := range secrets.Items {
+		result = append(result, models.GitCredentials{
+			Name:     secret.GetLabels()[gitCredentialNameLabel],
+			URL:      secret.GetAnnotations()["tekton.dev/git-0"],
+			Username: string(secret.Data[corev1.BasicAuthUsernameKey]),
+		})
+	}
+
+	return result, nil
+}
+
+// GitCredentialsExist returns true if the org has the named git credentials
+func GitCredentialsExist(ctx context.Context, kubeClient *kubernetes.Cluster, org, name string) (bool, error) {
+	secret, err := kubeClient.Kubectl.CoreV1().Secrets(deployments.TektonStagingNamespace).
+		Get(ctx, GitCredentialsName(org, name), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return gitCredentialsOf(secret.GetLabels(), org, name), nil
+}
+
+// GitCredentialsServiceAccount returns the name of the service account
+// cloning with the named git credentials of the org. It is empty if the org
+// has no such credentials.
+func GitCredentialsServiceAccount(ctx context.Context, kubeClient *kubernetes.Cluster, org, name string) (string, error) {
+	account, err := kubeClient.Kubectl.CoreV1().ServiceAccounts(deployments.TektonStagingNamespace).
+		Get(ctx, GitCredentialsName(org, name), metav1.GetOptions{})
+	if err != nil {
+		if apierrors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	if !gitCredentialsOf(account.GetLabels(), org, name) {
+		return "", nil
+	}
+	return account.GetName(), nil
+}
+
+// GitCredentialsCreate creates the git credentials of the org. These are a
+// basic auth secret for the git server, and a service account using it.
+func GitCredentialsCreate(ctx context.Context, kubeClient *kubernetes.Cluster, org string, request models.GitCredentialsCreateRequest) error {
+	name := GitCredentialsName(org, request.Name)
+	labels := map[string]string{
+		gitCredentialNameLabel:         request.Name,
+		"app.kubernetes.io/part-of":    org,
+		"app.kubernetes.io/managed-by": "epinio",
+		"app.kubernetes.io/component":  GitCredentialsComponent,
+	}
+
+	_, err := kubeClient.Kubectl.CoreV1().Secrets(deployments.TektonStagingNamespace).Create(ctx,
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+				Annotations: map[string]string{
+					// See https://github.com/tektoncd/pipeline/blob/main/docs/auth.md
+					"tekton.dev/git-0": request.URL,
+				},
+			},
+			Type: corev1.SecretTypeBasicAuth,
+			StringData: map[string]string{
+				corev1.BasicAuthUsernameKey: request.Username,
+				corev1.BasicAuthPasswordKey: request.Password,
+			},
+		}, metav1.CreateOptions{})
+	if err != nil {
+		return err
+	}
+
+	_, err = kubeClient.Kubectl.CoreV1().ServiceAccounts(deployments.TektonStagingNamespace).Create(ctx,
+		&corev1.ServiceAccount{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: labels,
+			},
+			Secrets: []corev1.ObjectReference{{Name: name}},
+		}, metav1.CreateOptions{})
+	if err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	return nil
+}
+
+// GitCredentialsDelete removes the named git credentials of the org
+func GitCredentialsDelete(ctx context.Context, kubeClient *kubernetes.Cluster, org, name string) error {
+	secretName := GitCredentialsName(org, name)
+
+	err := kubeClient.Kubectl.CoreV1().ServiceAccounts(deployments.TektonStagingNamespace).
+		Delete(ctx, secretName, metav1.DeleteOptions{})
+	if err != nil && !apierrors.IsNotFound(err) {
+		return err
+	}
+
+	return kubeClient.Kubectl.CoreV1().Secrets(deployments.TektonStagingNamespace).
+		Delete(ctx, secretName, metav1.DeleteOptions{})
+}
+
+// ValidateGitCredentials checks the request for new git credentials. The
+// name is a DNS label, and the URL the http(s) URL of a git server.
+func ValidateGitCredentials(request models.GitCredentialsCreateRequest) error {
+	if msgs := validation.IsDNS1123Label(request.Name); len(msgs) > 0 {
+		return errors.Errorf("bad git credentials name '%s': %s", request.Name, strings.Join(msgs, ", "))
+	}
+
+	u, err := url.Parse(request.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("bad git server url '%s', expected e.g. https://github.com", request.URL)
+	}
+
+	if request.Username == "" || request.Password == "" {
+		return errors.New("git credentials require a username and a password")
+	}
+
+	return nil
+}
+
+// gitCredentialsDelete removes all git credentials of the org
+func gitCredentialsDelete(ctx context.Context, kubeClient *kubernetes.Cluster, org string) error {
+	listOptions := metav1.ListOptions{LabelSelector: gitCredentialsSelector(org)}
+
+	err := kubeClient.Kubectl.CoreV1().ServiceAccounts(deployments.TektonStagingNamespace).
+		DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions)
+	if err != nil {
+		return err
+	}
+
+	return kubeClient.Kubectl.CoreV1().Secrets(deployments.TektonStagingNamespace).
+		DeleteCollection(ctx, metav1.DeleteOptions{}, listOptions)
+}
+
+// gitCredentialsOf returns true if the labels mark a resource as holding the
+// named git credentials of the org
+func gitCredentialsOf(labels map[string]string, org, name string) bool {
+	return labels["app.kubernetes.io/part-of"] == org &&
+		labels["app.kubernetes.io/component"] == GitCredentialsComponent &&
+		labels[gitCredentialNameLabel] == name
+}
+
+func gitCredentialsSelector(org string) string {
+	return fmt.Sprintf("app.kubernetes.io/part-of=%s,app.kubernetes.io/component=%s", org, GitCredentialsComponent)
+}
@@ -0,0 +1,56 @@
+package organizations_test
+
+import (
+	"github.com/epinio/epinio/internal/api/v1/models"
+	"github.com/epinio/epinio/internal/organizations"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GitCredentials", func() {
+	var request models.GitCredentialsCreateRequest
+
+	BeforeEach(func() {
+		request = models.GitCredentialsCreateRequest{
+			Name:     "github",
+			URL:      "https://github.com",
+			Username: "epinio",
+			Password: "s3cret",
+		}
+	})
+
+	It("names the resources after the org", func() {
+		Expect(organizations.GitCredentialsName("workspace", "github")).To(Equal("git.workspace.github"))
+	})
+
+	It("keeps the names of different orgs apart", func() {
+		Expect(organizations.GitCredentialsName("a", "b-c")).ToNot(Equal(organizations.GitCredentialsName("a-b", "c")))
+	})
+
+	It("accepts valid credentials", func() {
+		Expect(organizations.ValidateGitCredentials(request)).To(Succeed())
+
+		request.URL = "http://git.example.com:3000"
+		Expect(organizations.ValidateGitCredentials(request)).To(Succeed())
+	})
+
+	It("rejects bad names", func() {
+		request.Name = "Git_Hub"
+		Expect(organizations.ValidateGitCredentials(request)).To(
+			MatchError(ContainSubstring("bad git credentials name 'Git_Hub'")))
+	})
+
+	It("rejects urls which are not http(s)", func() {
+		for _, url := range []string{"", "github.com", "git@github.com:epinio/epinio.git", "ssh://github.com"} {
+			request.URL = url
+			Expect(organizations.ValidateGitCredentials(request)).To(
+				MatchError(ContainSubstring("bad git server url")), url)
+		}
+	})
+
+	It("requires a username and password", func() {
+		request.Password = ""
+		Expect(organizations.ValidateGitCredentials(request)).ToNot(Succeed())
+	})
+})
@@ -5,17 +5,24 @@ package organizations
 
 import (
 	"context"
+	"encoding/json"
 
 	"github.com/epinio/epinio/deployments"
 	"github.com/epinio/epinio/helpers/kubernetes"
 	"github.com/epinio/epinio/helpers/tracelog"
+	"github.com/epinio/epinio/internal/api/v1/models"
 	"github.com/epinio/epinio/internal/duration"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
 )
 
+// BuildpacksAnnotation is the annotation of the org namespace holding the JSON
+// encoded buildpacks settings the apps of the org default to
+const BuildpacksAnnotation = "epinio.suse.org/buildpacks"
+
 type Organization struct {
 	Name string
 }
@@ -93,7 +100,13 @@ func Create(ctx context.Context, kubeClient *kubernetes.Cluster, gitea GiteaInte
 }
 
 func Delete(ctx context.Context, kubeClient *kubernetes.Cluster, gitea GiteaInterface, org string) error {
-	err := kubeClient.Kubectl.CoreV1().Namespaces().Delete(ctx, org, metav1.DeleteOptions{})
+	// The git credentials live in the staging namespace
+	err := gitCredentialsDelete(ctx, kubeClient, org)
+	if err != nil {
+		return err
+	}
+
+	err = kubeClient.Kubectl.CoreV1().Namespaces().Delete(ctx, org, metav1.DeleteOptions{})
 	if err != nil {
 		return err
 	}
@@ -144,3 +157,61 @@ func createServiceAccount(ctx context.Context, kubeClient *kubernetes.Cluster, t
 
 	return err
 }
+
+// Buildpacks returns the buildpacks settings the apps of the org default to
+func Buildpacks(ctx context.Context, kubeClient *kubernetes.Cluster, org string) (models.Buildpacks, error) {
+	namespace, err := kubeClient.Kubectl.CoreV1().Namespaces().Get(ctx, org, metav1.GetOptions{})
+	if err != nil {
+		return models.Buildpacks{}, err
+	}
+
+	return buildpacksOf(namespace)
+}
+
+// BuildpacksUpdate merges the update into the buildpacks settings of the org,
+// see models.Buildpacks.Merge, and returns the result
+func BuildpacksUpdate(ctx context.Context, kubeClient *kubernetes.Cluster, org string, update models.Buildpacks) (models.Buildpacks, error) {
+	var buildpacks models.Buildpacks
+	client := kubeClient.Kubectl.CoreV1().Namespaces()
+
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		namespace, err := client.Get(ctx, org, metav1.GetOptions{})
+		if err != nil {
+			return err
+		}
+
+		current, err := buildpacksOf(namespace)
+		if err != nil {
+			return err
+		}
+		buildpacks = current.Merge(update)
+
+		data, err := json.Marshal(buildpacks)
+		if err != nil {
+			return err
+		}
+		if namespace.Annotations == nil {
+			namespace.Annotations = map[string]string{}
+		}
+		namespace.Annotations[BuildpacksAnnotation] = string(data)
+
+		_, err = client.Update(ctx, namespace, metav1.UpdateOptions{})
+		return err
+	})
+
+	return buildpacks, err
+}
+
+func buildpacksOf(namespace *corev1.Namespace) (models.Buildpacks, error) {
+	buildpacks := models.Buildpacks{}
+
+	data, ok := namespace.Annotations[BuildpacksAnnotation]
+	if !ok || data == "" {
+		return buildpacks, nil
+	}
+
+	if err := json.Unmarshal([]byte(data), &buildpacks); err != nil {
+		return buildpacks, errors.Wrap(err, "bad buildpacks settings of the org")
+	}
+	return buildpacks, nil
+}
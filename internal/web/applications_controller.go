@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/epinio/epinio/helpers/kubernetes"
+	"github.com/epinio/epinio/internal/auth"
 	"github.com/epinio/epinio/internal/filesystem"
 	"github.com/epinio/epinio/internal/organizations"
 )
@@ -34,7 +35,8 @@ func setCurrentOrgInCookie(org, cookieName string, w http.ResponseWriter) error
 // but the org does not (e.g. because it was deleted) or if the cookie does not
 // exist, then the first existing org becomes the current org and the cookie is
 // updated. If no orgs exist, then an empty string is returned as the org name.
-// The function also returns the rest of the available orgs.
+// The function also returns the rest of the available orgs. Only the orgs
+// the current user may view are available.
 func getOrgs(w http.ResponseWriter, r *http.Request) (string, []string, error) {
 	ctx := r.Context()
 	cluster, err := kubernetes.GetCluster(ctx)
@@ -42,10 +44,18 @@ func getOrgs(w http.ResponseWriter, r *http.Request) (string, []string, error) {
 		return "", []string{}, err
 	}
 
-	orgs, err := organizations.List(ctx, cluster)
+	allOrgs, err := organizations.List(ctx, cluster)
 	if err != nil {
 		return "", []string{}, err
 	}
+
+	user := auth.CurrentUser(ctx)
+	orgs := []organizations.Organization{}
+	for _, org := range allOrgs {
+		if user != nil && user.Allowed(org.Name, http.MethodGet) {
+			orgs = append(orgs, org)
+		}
+	}
 	if len(orgs) == 0 {
 		return "", []string{}, nil
 	}
@@ -82,12 +92,14 @@ func getOrgs(w http.ResponseWriter, r *http.Request) (string, []string, error) {
 	}(cookie.Value, orgs)
 
 	// If the cookie org no longer exists, set currentOrg to the first existing one.
+	currentOrg := cookie.Value
 	if !orgExists {
-		setCurrentOrgInCookie(orgs[0].Name, "currentOrg", w)
+		currentOrg = orgs[0].Name
+		setCurrentOrgInCookie(currentOrg, "currentOrg", w)
 	}
-	restOrgs := otherOrgs(cookie.Value, orgs)
+	restOrgs := otherOrgs(currentOrg, orgs)
 
-	return cookie.Value, restOrgs, nil
+	return currentOrg, restOrgs, nil
 }
 
 func (hc ApplicationsController) Index(w http.ResponseWriter, r *http.Request) {
@@ -97,8 +109,10 @@ func (hc ApplicationsController) Index(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if currentOrg == "" {
-		// TODO: Redirect to create org page. No orgs exist.
-		panic("no current org")
+		// TODO: Redirect to create org page. No orgs exist, or none
+		// the user may view.
+		http.Error(w, "No organization available", http.StatusNotFound)
+		return
 	}
 
 	// TODO: Move org specific links to a left navigation bar and keep only
@@ -4,14 +4,16 @@ package web
 import (
 	"net/http"
 
+	apiv1 "github.com/epinio/epinio/internal/api/v1"
 	"github.com/julienschmidt/httprouter"
 )
 
 func Router() *httprouter.Router {
 	router := httprouter.New()
-	router.HandlerFunc("GET", "/", ApplicationsController{}.Index)
-	router.HandlerFunc("GET", "/info", InfoController{}.Index)
-	router.HandlerFunc("GET", "/orgs/target/:org", OrgsController{}.Target)
+	// The routes are authenticated by the server, see startEpinioServer
+	router.HandlerFunc("GET", "/", apiv1.Authorize(ApplicationsController{}.Index))
+	router.HandlerFunc("GET", "/info", apiv1.Authorize(InfoController{}.Index))
+	router.HandlerFunc("GET", "/orgs/target/:org", apiv1.Authorize(OrgsController{}.Target))
 	router.NotFound = http.NotFoundHandler()
 
 	return router